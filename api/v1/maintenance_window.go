@@ -0,0 +1,220 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The longest maintenance window, a weekly schedule is the longest period that is expected
+const MaxMaintenanceWindowDuration = 7 * 24 * time.Hour
+
+// The bounds of the fields of a cron expression: minute, hour, day of month, month and day of week.
+// Day of week 7 is Sunday as well as 0.
+var cronFieldBounds = [5][2]int{{0, 59}, {0, 23}, {1, 31}, {1, 12}, {0, 7}}
+
+// A parsed cron expression, each field is the set of the values it matches
+type cronSchedule struct {
+	fields     [5]map[int]bool
+	anyDay     bool
+	anyWeekday bool
+}
+
+func parseCronSchedule(expression string) (*cronSchedule, error) {
+	parts := strings.Fields(expression)
+	if len(parts) != 5 {
+		return nil, fmt.Errorf("expected 5 fields (minute, hour, day of month, month, day of week), got %d", len(parts))
+	}
+	schedule := &cronSchedule{anyDay: parts[2] == "*", anyWeekday: parts[4] == "*"}
+	for i, part := range parts {
+		values, err := parseCronField(part, cronFieldBounds[i][0], cronFieldBounds[i][1])
+		if err != nil {
+			return nil, fmt.Errorf("field %d [%s]: %v", i+1, part, err)
+		}
+		schedule.fields[i] = values
+	}
+	if schedule.fields[4][7] {
+		schedule.fields[4][0] = true
+	}
+	return schedule, nil
+}
+
+// Parses a comma separated list of values, ranges 'a-b' and steps '*/n' or 'a-b/n'
+func parseCronField(field string, min int, max int) (map[int]bool, error) {
+	values := map[int]bool{}
+	for _, item := range strings.Split(field, ",") {
+		step, stepped := 1, false
+		if i := strings.Index(item, "/"); i >= 0 {
+			var err error
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step <= 0 {
+				return nil, fmt.Errorf("invalid step [%s]", item[i+1:])
+			}
+			item, stepped = item[:i], true
+		}
+		from, to := min, max
+		if item != "*" {
+			bounds := strings.SplitN(item, "-", 2)
+			var err error
+			if from, err = strconv.Atoi(bounds[0]); err != nil {
+				return nil, fmt.Errorf("invalid value [%s]", bounds[0])
+			}
+			to = from
+			if len(bounds) == 2 {
+				if to, err = strconv.Atoi(bounds[1]); err != nil {
+					return nil, fmt.Errorf("invalid value [%s]", bounds[1])
+				}
+			} else if stepped {
+				to = max
+			}
+		}
+		if from < min || to > max || from > to {
+			return nil, fmt.Errorf("[%d-%d] is out of the range [%d-%d]", from, to, min, max)
+		}
+		for v := from; v <= to; v += step {
+			values[v] = true
+		}
+	}
+	return values, nil
+}
+
+// The schedules that do not start within a year are rejected, a window opens at least once a year
+const maxMaintenanceWindowInterval = 366 * 24 * time.Hour
+
+// Checks if the schedule starts on the day of t. As in cron, when both the day of month and the day of week
+// are restricted the day matches either of them.
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	if !s.fields[3][int(t.Month())] {
+		return false
+	}
+	dayMatches, weekdayMatches := s.fields[2][t.Day()], s.fields[4][int(t.Weekday())]
+	if s.anyDay || s.anyWeekday {
+		return dayMatches && weekdayMatches
+	}
+	return dayMatches || weekdayMatches
+}
+
+// Returns the first start of the schedule at or after the minute of t, or false if it does not start before the limit.
+// The fields are matched from the month to the minute, a field that does not match skips to the start of its next value.
+func (s *cronSchedule) next(t time.Time, limit time.Time) (time.Time, bool) {
+	t = t.UTC().Truncate(time.Minute)
+	for !t.After(limit) {
+		switch {
+		case !s.fields[3][int(t.Month())]:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case !s.fields[1][t.Hour()]:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case !s.fields[0][t.Minute()]:
+			t = t.Add(time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Returns the last start of the schedule at or before the minute of t, or false if it does not start after the limit.
+// The fields are matched as by next, a field that does not match skips to the end of its previous value.
+func (s *cronSchedule) previous(t time.Time, limit time.Time) (time.Time, bool) {
+	t = t.UTC().Truncate(time.Minute)
+	for t.After(limit) {
+		switch {
+		case !s.fields[3][int(t.Month())]:
+			t = time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC).Add(-time.Minute)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Add(-time.Minute)
+		case !s.fields[1][t.Hour()]:
+			t = t.Truncate(time.Hour).Add(-time.Minute)
+		case !s.fields[0][t.Minute()]:
+			t = t.Add(-time.Minute)
+		default:
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// Validate checks the schedule and the duration of the window, the schedule should start within a year.
+func (w *MaintenanceWindow) Validate() error {
+	schedule, err := parseCronSchedule(w.Schedule)
+	if err != nil {
+		return fmt.Errorf("invalid schedule [%s]: %v", w.Schedule, err)
+	}
+	now := time.Now()
+	if _, found := schedule.next(now, now.Add(maxMaintenanceWindowInterval)); !found {
+		return fmt.Errorf("invalid schedule [%s]: it does not start within a year", w.Schedule)
+	}
+	if w.Duration.Duration < time.Minute || w.Duration.Duration > MaxMaintenanceWindowDuration {
+		return fmt.Errorf("the duration should be between 1m and %s, got %s", MaxMaintenanceWindowDuration, w.Duration.Duration)
+	}
+	return nil
+}
+
+// IsOpen checks if the window started in the last Duration before now.
+func (w *MaintenanceWindow) IsOpen(now time.Time) (bool, error) {
+	schedule, err := parseCronSchedule(w.Schedule)
+	if err != nil {
+		return false, err
+	}
+	_, found := schedule.previous(now, now.Add(-w.Duration.Duration))
+	return found, nil
+}
+
+// NextStart returns the next start of the window after now, or false if it does not start within a year.
+func (w *MaintenanceWindow) NextStart(now time.Time) (time.Time, bool, error) {
+	schedule, err := parseCronSchedule(w.Schedule)
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	now = now.UTC().Truncate(time.Minute)
+	start, found := schedule.next(now.Add(time.Minute), now.Add(maxMaintenanceWindowInterval))
+	return start, found, nil
+}
+
+// DisruptionsAllowed checks if the disruptive flows of the operator may run on the cluster at the given time:
+// the cluster is not paused, and it has no maintenance windows or one of them is open. The reason is returned
+// when they may not run.
+func (s *RedisClusterSpec) DisruptionsAllowed(now time.Time) (bool, string) {
+	if s.Paused {
+		return false, "the cluster is paused by spec.paused"
+	}
+	if len(s.MaintenanceWindows) == 0 {
+		return true, ""
+	}
+	var next time.Time
+	for i := range s.MaintenanceWindows {
+		w := &s.MaintenanceWindows[i]
+		open, err := w.IsOpen(now)
+		if err != nil {
+			return false, fmt.Sprintf("maintenance window [%s] is invalid: %v", w.Schedule, err)
+		}
+		if open {
+			return true, ""
+		}
+		if start, found, _ := w.NextStart(now); found && (next.IsZero() || start.Before(next)) {
+			next = start
+		}
+	}
+	if next.IsZero() {
+		return false, "no maintenance window is open"
+	}
+	return false, fmt.Sprintf("no maintenance window is open, the next one opens at %s", next.Format(time.RFC3339))
+}
@@ -0,0 +1,122 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMaintenanceWindowValidate(t *testing.T) {
+	testCases := []struct {
+		schedule string
+		duration time.Duration
+		valid    bool
+	}{
+		{"0 2 * * *", 2 * time.Hour, true},
+		{"*/15 1-3 1,15 * 1-5", time.Hour, true},
+		{"30 22 * * 7", 30 * time.Minute, true},
+		{"0 2 * *", time.Hour, false},
+		{"60 2 * * *", time.Hour, false},
+		{"0 5-2 * * *", time.Hour, false},
+		{"0 */0 * * *", time.Hour, false},
+		{"0 2 * * mon", time.Hour, false},
+		{"0 2 * * *", 0, false},
+		{"0 2 * * *", 8 * 24 * time.Hour, false},
+		{"0 0 30 2 *", time.Hour, false},
+		{"0 0 31 4,6 *", time.Hour, false},
+	}
+	for _, tc := range testCases {
+		w := MaintenanceWindow{Schedule: tc.schedule, Duration: metav1.Duration{Duration: tc.duration}}
+		if err := w.Validate(); (err == nil) != tc.valid {
+			t.Errorf("Validate of [%s] for %s: expected valid %v, got %v", tc.schedule, tc.duration, tc.valid, err)
+		}
+	}
+}
+
+func TestMaintenanceWindowIsOpen(t *testing.T) {
+	// Weekdays from 02:00 to 04:00 UTC, 2021-06-07 is a Monday
+	w := MaintenanceWindow{Schedule: "0 2 * * 1-5", Duration: metav1.Duration{Duration: 2 * time.Hour}}
+	testCases := []struct {
+		now  string
+		open bool
+	}{
+		{"2021-06-07T01:59:00Z", false},
+		{"2021-06-07T02:00:00Z", true},
+		{"2021-06-07T03:59:59Z", true},
+		{"2021-06-07T04:00:00Z", false},
+		{"2021-06-07T04:30:00+02:00", true},
+		{"2021-06-06T02:30:00Z", false},
+	}
+	for _, tc := range testCases {
+		now, _ := time.Parse(time.RFC3339, tc.now)
+		open, err := w.IsOpen(now)
+		if err != nil || open != tc.open {
+			t.Errorf("IsOpen at %s: expected %v, got %v (%v)", tc.now, tc.open, open, err)
+		}
+	}
+}
+
+func TestMaintenanceWindowDayOfMonthOrWeek(t *testing.T) {
+	// As in cron, the first of the month or any Sunday
+	w := MaintenanceWindow{Schedule: "0 0 1 * 0", Duration: metav1.Duration{Duration: time.Hour}}
+	for now, expected := range map[string]bool{
+		"2021-06-01T00:10:00Z": true,  // Tuesday the 1st
+		"2021-06-06T00:10:00Z": true,  // Sunday the 6th
+		"2021-06-07T00:10:00Z": false, // Monday the 7th
+	} {
+		t0, _ := time.Parse(time.RFC3339, now)
+		if open, _ := w.IsOpen(t0); open != expected {
+			t.Errorf("IsOpen at %s: expected %v, got %v", now, expected, open)
+		}
+	}
+}
+
+func TestMaintenanceWindowNextStart(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2021-06-07T12:00:30Z")
+	testCases := []struct {
+		schedule string
+		next     string
+	}{
+		{"0 2 * * *", "2021-06-08T02:00:00Z"},
+		{"*/20 12 * * *", "2021-06-07T12:20:00Z"},
+		{"30 4 1 */3 *", "2021-07-01T04:30:00Z"},
+		{"0 0 1 1 *", "2022-01-01T00:00:00Z"},
+		{"15 23 31 * 6", "2021-06-12T23:15:00Z"},
+		{"0 0 29 2 *", ""},
+	}
+	for _, tc := range testCases {
+		w := MaintenanceWindow{Schedule: tc.schedule, Duration: metav1.Duration{Duration: time.Hour}}
+		start, found, err := w.NextStart(now)
+		if err != nil || found != (tc.next != "") || (found && start.Format(time.RFC3339) != tc.next) {
+			t.Errorf("NextStart of [%s]: expected [%s], got [%s] %v (%v)", tc.schedule, tc.next, start.Format(time.RFC3339), found, err)
+		}
+	}
+}
+
+func TestDisruptionsAllowed(t *testing.T) {
+	now, _ := time.Parse(time.RFC3339, "2021-06-07T12:00:00Z")
+	spec := RedisClusterSpec{}
+	if allowed, _ := spec.DisruptionsAllowed(now); !allowed {
+		t.Error("Expected disruptions to be allowed without maintenance windows")
+	}
+	spec.MaintenanceWindows = []MaintenanceWindow{
+		{Schedule: "0 2 * * *", Duration: metav1.Duration{Duration: time.Hour}},
+		{Schedule: "0 20 * * *", Duration: metav1.Duration{Duration: time.Hour}},
+	}
+	allowed, reason := spec.DisruptionsAllowed(now)
+	if allowed {
+		t.Error("Expected disruptions not to be allowed outside the maintenance windows")
+	}
+	if expected := "no maintenance window is open, the next one opens at 2021-06-07T20:00:00Z"; reason != expected {
+		t.Errorf("Expected reason [%s], got [%s]", expected, reason)
+	}
+	spec.MaintenanceWindows = append(spec.MaintenanceWindows, MaintenanceWindow{Schedule: "0 11 * * *", Duration: metav1.Duration{Duration: 2 * time.Hour}})
+	if allowed, _ := spec.DisruptionsAllowed(now); !allowed {
+		t.Error("Expected disruptions to be allowed inside a maintenance window")
+	}
+	spec.Paused = true
+	if allowed, _ := spec.DisruptionsAllowed(now); allowed {
+		t.Error("Expected disruptions not to be allowed on a paused cluster")
+	}
+}
@@ -0,0 +1,57 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// GetCondition returns the condition of the given type, or nil if it is not reported.
+func (s *RedisClusterStatus) GetCondition(conditionType RedisClusterConditionType) *RedisClusterCondition {
+	for i := range s.Conditions {
+		if s.Conditions[i].Type == conditionType {
+			return &s.Conditions[i]
+		}
+	}
+	return nil
+}
+
+// SetCondition adds the condition or updates the existing condition of the same type.
+// The transition time is kept as long as the status of the condition does not change.
+func (s *RedisClusterStatus) SetCondition(condition RedisClusterCondition) {
+	if condition.LastTransitionTime.IsZero() {
+		condition.LastTransitionTime = metav1.Now()
+	}
+	existing := s.GetCondition(condition.Type)
+	if existing == nil {
+		s.Conditions = append(s.Conditions, condition)
+		return
+	}
+	if existing.Status != condition.Status {
+		existing.Status = condition.Status
+		existing.LastTransitionTime = condition.LastTransitionTime
+	}
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
+	existing.ObservedGeneration = condition.ObservedGeneration
+}
+
+// IsConditionTrue returns true if the condition of the given type is reported with status True.
+func (s *RedisClusterStatus) IsConditionTrue(conditionType RedisClusterConditionType) bool {
+	c := s.GetCondition(conditionType)
+	return c != nil && c.Status == metav1.ConditionTrue
+}
@@ -18,6 +18,7 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
@@ -36,7 +37,7 @@ type RedisClusterSpec struct {
 	// +optional
 	// Flag that toggles the default affinity rules added by the operator.
 	// Default is true.
-	EnableDefaultAffinity bool `json:"enableDefaultAffinity,omitempty"`
+	EnableDefaultAffinity *bool `json:"enableDefaultAffinity,omitempty"`
 
 	// +optional
 	// Annotations for the Redis pods.
@@ -52,6 +53,349 @@ type RedisClusterSpec struct {
 
 	// PodSpec for Redis pods.
 	RedisPodSpec corev1.PodSpec `json:"redisPodSpec"`
+
+	// +optional
+	// Credentials used by the operator to connect to the Redis nodes. When not set, the
+	// REDIS_USERNAME and REDISCLI_AUTH environment variables of the operator are used.
+	Auth *RedisClusterAuth `json:"auth,omitempty"`
+
+	// +optional
+	// Enables TLS on the Redis nodes, for the connections of the clients, of the operator and between the nodes.
+	TLS *RedisClusterTLS `json:"tls,omitempty"`
+
+	// +optional
+	// Addresses the nodes by stable host names of a headless service instead of their pod IPs, the nodes announce
+	// them to the clients and keep their address when their pod is rescheduled. Requires Redis 7 or later.
+	AnnounceHostnames bool `json:"announceHostnames,omitempty"`
+
+	// +optional
+	// Stops the disruptive flows of the operator on the cluster: rolling updates, leader scaling, rebalances
+	// and resets. The failed nodes are still recovered.
+	Paused bool `json:"paused,omitempty"`
+
+	// +optional
+	// The windows in which the disruptive flows of the operator may run. When not set they run at any time.
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
+
+	// +optional
+	// What happens to the data of the cluster when the RedisCluster is deleted, Delete or Snapshot.
+	// Default is Delete. Snapshot requires spec.persistence.
+	DeletionPolicy DeletionPolicy `json:"deletionPolicy,omitempty"`
+
+	// +optional
+	// Keeps the data and the cluster node ID of each node on a persistent volume, which is attached again
+	// when the pod of the node is recreated.
+	Persistence *RedisClusterPersistence `json:"persistence,omitempty"`
+
+	// +optional
+	// Gives every shard a share of the slots in proportion to its weight when the operator rebalances the cluster,
+	// instead of the same number of slots.
+	SlotWeights *SlotWeights `json:"slotWeights,omitempty"`
+
+	// +optional
+	// Scales the number of leaders between the bounds by the memory and the CPU usage of the leaders, the
+	// leader count of the spec is set by the operator.
+	Autoscaling *RedisClusterAutoscaling `json:"autoscaling,omitempty"`
+}
+
+// RedisClusterAutoscaling describes the automatic scaling of the leaders of the cluster.
+type RedisClusterAutoscaling struct {
+	// +kubebuilder:validation:Minimum=3
+	// The minimal number of leaders.
+	MinLeaders int `json:"minLeaders"`
+
+	// +kubebuilder:validation:Minimum=3
+	// The maximal number of leaders.
+	MaxLeaders int `json:"maxLeaders"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// The target average memory usage of the leaders, in percent of their maxmemory, or of the memory limit
+	// of their redis container when maxmemory is not set.
+	TargetMemoryPercent *int `json:"targetMemoryPercent,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=100
+	// The target average CPU usage of the leaders, in percent of the CPU limit of their redis container,
+	// or of a single core when it is not set.
+	TargetCPUPercent *int `json:"targetCPUPercent,omitempty"`
+
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:validation:Maximum=100
+	// How far, in percent of the target, the usage may be from the target before the leaders are scaled.
+	// Default is 10.
+	TolerancePercent *int `json:"tolerancePercent,omitempty"`
+
+	// +optional
+	// The time to wait after a scale of the leaders before scaling them up. Default is 5m.
+	ScaleUpCooldown *metav1.Duration `json:"scaleUpCooldown,omitempty"`
+
+	// +optional
+	// The time to wait after a scale of the leaders before scaling them down. Default is 30m.
+	ScaleDownCooldown *metav1.Duration `json:"scaleDownCooldown,omitempty"`
+}
+
+// SlotWeights describes the weights of the shards of the cluster in the slot distribution.
+type SlotWeights struct {
+	// +optional
+	// The weights of the shards by the name of their designated leader (redis-node-N), the shards that are
+	// not listed weigh 1.
+	Shards map[string]int `json:"shards,omitempty"`
+
+	// +optional
+	// Multiplies the weight of every leader by the memory limit of its redis container, in MiB. A leader
+	// whose container has no memory limit takes the smallest limit of the other leaders.
+	FromMemoryLimit bool `json:"fromMemoryLimit,omitempty"`
+}
+
+// RedisClusterPersistence describes the persistent volume of each node of the cluster.
+type RedisClusterPersistence struct {
+	// +optional
+	// The storage class of the volumes, the default storage class of the cluster is used when not set.
+	StorageClassName *string `json:"storageClassName,omitempty"`
+
+	// The size of the volume of each node.
+	Size resource.Quantity `json:"size"`
+
+	// +optional
+	// +kubebuilder:validation:Enum=RDB;AOF;RDBAndAOF
+	// How the data is written to the volume: RDB snapshots, an append only file, or both. Default is RDB.
+	Mode PersistenceMode `json:"mode,omitempty"`
+}
+
+// PersistenceMode defines how Redis writes its data to the persistent volume.
+type PersistenceMode string
+
+const (
+	PersistenceRDB       PersistenceMode = "RDB"
+	PersistenceAOF       PersistenceMode = "AOF"
+	PersistenceRDBAndAOF PersistenceMode = "RDBAndAOF"
+)
+
+// DeletionPolicy defines what happens to the data of the cluster when the RedisCluster is deleted.
+// +kubebuilder:validation:Enum=Delete;Snapshot
+type DeletionPolicy string
+
+const (
+	// DeletionPolicyDelete: the pods and everything else the cluster owns are removed with their data.
+	DeletionPolicyDelete DeletionPolicy = "Delete"
+
+	// DeletionPolicySnapshot: a final RDB snapshot is saved by every leader before its pod is removed,
+	// the persistent volumes of the nodes are kept.
+	DeletionPolicySnapshot DeletionPolicy = "Snapshot"
+)
+
+// MaintenanceWindow is a recurring period in which the disruptive flows of the operator may run.
+type MaintenanceWindow struct {
+	// A cron expression of the starts of the window in UTC, with the minute, hour, day of month, month
+	// and day of week fields, for example '0 2 * * 1-5' for 02:00 on weekdays. It should start within a year.
+	Schedule string `json:"schedule"`
+
+	// How long the window stays open after each start, for example '2h' or '90m'. At most 7 days.
+	Duration metav1.Duration `json:"duration"`
+}
+
+// RedisClusterAuth references the credentials used by the operator to connect to the Redis nodes.
+type RedisClusterAuth struct {
+	// Secret in the namespace of the RedisCluster that holds the 'username' and 'password' keys
+	// of the operator user, for example a secret of type kubernetes.io/basic-auth.
+	// The credentials are reloaded when the secret changes.
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+}
+
+// RedisClusterTLS references the certificates of the Redis nodes.
+type RedisClusterTLS struct {
+	// Secret in the namespace of the RedisCluster that holds the 'ca.crt', 'tls.crt' and 'tls.key' keys,
+	// for example a secret issued by cert-manager. The certificate is used by the Redis nodes as a server
+	// and a client certificate, and by the operator as a client certificate.
+	SecretRef corev1.LocalObjectReference `json:"secretRef"`
+}
+
+// RedisClusterConditionType is the type of a condition reported on the RedisCluster status.
+type RedisClusterConditionType string
+
+const (
+	// Available: the cluster is up, every node reported in the state map is healthy
+	// and the cluster can serve requests.
+	ConditionAvailable RedisClusterConditionType = "Available"
+
+	// Progressing: the operator is in the middle of creating, recovering, updating
+	// or scaling the cluster.
+	ConditionProgressing RedisClusterConditionType = "Progressing"
+
+	// Degraded: one or more nodes are missing, non reachable or not aligned with
+	// the cluster state map.
+	ConditionDegraded RedisClusterConditionType = "Degraded"
+
+	// SlotsCovered: all 16384 hash slots are assigned to reachable leaders.
+	ConditionSlotsCovered RedisClusterConditionType = "SlotsCovered"
+
+	// ACLSynced: the ACL config map is loaded on all the cluster nodes.
+	ConditionACLSynced RedisClusterConditionType = "ACLSynced"
+
+	// UpgradePending: nodes created by a previous version of the operator were
+	// detected, the upgrade can be triggered by the '/upgrade' entry point.
+	ConditionUpgradePending RedisClusterConditionType = "UpgradePending"
+
+	// DisruptionsAllowed: the cluster is not paused and a maintenance window is open,
+	// rolling updates, leader scaling, rebalances and resets may run.
+	ConditionDisruptionsAllowed RedisClusterConditionType = "DisruptionsAllowed"
+
+	// ZonesBalanced: the leaders are spread evenly over the availability zones of the
+	// cluster nodes and no follower is in the zone of its leader.
+	ConditionZonesBalanced RedisClusterConditionType = "ZonesBalanced"
+)
+
+// RedisClusterCondition describes one aspect of the current state of the cluster.
+// It follows the shape of the standard Kubernetes status conditions.
+type RedisClusterCondition struct {
+	// Type of the condition.
+	Type RedisClusterConditionType `json:"type"`
+
+	// Status of the condition, one of True, False, Unknown.
+	Status metav1.ConditionStatus `json:"status"`
+
+	// The generation of the RedisCluster spec the condition was set upon.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The last time the condition transitioned from one status to another.
+	LastTransitionTime metav1.Time `json:"lastTransitionTime"`
+
+	// A programmatic identifier of the reason for the last transition.
+	Reason string `json:"reason"`
+
+	// A human readable message with details about the transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// RedisNodeStatus describes a single node of the cluster as it is tracked by the operator.
+type RedisNodeStatus struct {
+	// The name of the node pod.
+	Name string `json:"name"`
+
+	// The name of the leader that the node belongs to, equal to the node name for leaders.
+	LeaderName string `json:"leaderName"`
+
+	// The current role of the node in the cluster, leader or follower.
+	// +optional
+	Role string `json:"role,omitempty"`
+
+	// The state of the node in the operator flow (NodeOK, CreateNode, FailoverNode, ...).
+	NodeState string `json:"nodeState"`
+
+	// Indicates if the node was created by the current spec of the cluster.
+	IsUpToDate bool `json:"isUpToDate"`
+
+	// The cluster node ID reported by the node.
+	// +optional
+	NodeID string `json:"nodeId,omitempty"`
+
+	// The slot ranges served by the node, reported for leaders only.
+	// +optional
+	Slots []string `json:"slots,omitempty"`
+
+	// The replication offset reported by the node.
+	// +optional
+	ReplicationOffset int64 `json:"replicationOffset,omitempty"`
+
+	// The memory used by the node in bytes.
+	// +optional
+	UsedMemory int64 `json:"usedMemory,omitempty"`
+
+	// The availability zone of the kubernetes node that runs the node pod.
+	// +optional
+	Zone string `json:"zone,omitempty"`
+}
+
+// ZoneSpread counts the leaders and the followers of the cluster in a single availability zone.
+type ZoneSpread struct {
+	// The name of the zone, by the topology.kubernetes.io/zone label of the kubernetes nodes.
+	Zone string `json:"zone"`
+
+	// The number of leaders in the zone.
+	Leaders int `json:"leaders"`
+
+	// The number of followers in the zone.
+	Followers int `json:"followers"`
+}
+
+// ReshardJournal records the progress of a reshard run by the operator, an interrupted reshard is resumed from it.
+type ReshardJournal struct {
+	// The cluster node ID of the leader the slots are moved from.
+	SourceID string `json:"sourceId"`
+
+	// The cluster node ID of the leader the slots are moved to.
+	TargetID string `json:"targetId"`
+
+	// The slot that was being migrated when the journal was written.
+	// +optional
+	MigratingSlot *int `json:"migratingSlot,omitempty"`
+
+	// The slot ranges that are left to move.
+	// +optional
+	PendingSlots []string `json:"pendingSlots,omitempty"`
+
+	// The number of slots moved so far.
+	MovedSlots int `json:"movedSlots"`
+
+	// The number of keys moved so far.
+	MovedKeys int64 `json:"movedKeys"`
+
+	// The time the reshard started.
+	StartTime metav1.Time `json:"startTime"`
+
+	// The last time the journal was written.
+	LastUpdateTime metav1.Time `json:"lastUpdateTime"`
+}
+
+// LeaderSlots is the number of slots a leader holds against the number it should hold by its weight.
+type LeaderSlots struct {
+	// The name of the leader node.
+	Name string `json:"name"`
+
+	// The cluster node ID of the leader.
+	// +optional
+	NodeID string `json:"nodeId,omitempty"`
+
+	// The weight of the leader in the slot distribution.
+	Weight int64 `json:"weight"`
+
+	// The number of slots the leader holds.
+	Slots int `json:"slots"`
+
+	// The number of slots the leader should hold by its weight.
+	TargetSlots int `json:"targetSlots"`
+
+	// The number of slots the leader should receive, negative when it should give slots.
+	Gap int `json:"gap"`
+}
+
+// AutoscalingStatus is the last evaluation of the autoscaling of the leaders.
+type AutoscalingStatus struct {
+	// The average memory usage of the leaders, in percent.
+	// +optional
+	MemoryPercent *int `json:"memoryPercent,omitempty"`
+
+	// The average CPU usage of the leaders, in percent.
+	// +optional
+	CPUPercent *int `json:"cpuPercent,omitempty"`
+
+	// The number of leaders recommended by the usage.
+	// +optional
+	DesiredLeaders int `json:"desiredLeaders,omitempty"`
+
+	// The last decision of the autoscaler and its reason.
+	// +optional
+	LastDecision string `json:"lastDecision,omitempty"`
+
+	// The last time the autoscaler scaled the leaders.
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
 }
 
 // RedisClusterStatus defines the observed state of RedisCluster
@@ -67,11 +411,66 @@ type RedisClusterStatus struct {
 	// The total expected pod number when the cluster is ready and stable.
 	// +optional
 	TotalExpectedPods int `json:"totalExpectedPods,omitempty"`
+
+	// The most recent generation of the RedisCluster spec observed by the operator.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// The version of the schema of the nodes state kept in the status.
+	// +optional
+	StateViewVersion int `json:"stateViewVersion,omitempty"`
+
+	// The state of the cluster view kept by the operator (ClusterCreate, ClusterFix, ClusterRebalance, ClusterOK).
+	// +optional
+	ClusterViewState string `json:"clusterViewState,omitempty"`
+
+	// The number of reconcile loops since the cluster was last seen healthy.
+	// +optional
+	NumOfReconcileLoopsSinceHealthyCluster int `json:"numOfReconcileLoopsSinceHealthyCluster,omitempty"`
+
+	// The number of healthy reconcile loops in a row.
+	// +optional
+	NumOfHealthyReconcileLoopsInRow int `json:"numOfHealthyReconcileLoopsInRow,omitempty"`
+
+	// The nodes of the cluster as they are tracked by the operator.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	Nodes []RedisNodeStatus `json:"nodes,omitempty"`
+
+	// The spread of the leaders and the followers over the availability zones.
+	// +optional
+	// +listType=map
+	// +listMapKey=zone
+	Zones []ZoneSpread `json:"zones,omitempty"`
+
+	// The slots of every leader against the target of its weight.
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	SlotDistribution []LeaderSlots `json:"slotDistribution,omitempty"`
+
+	// The last evaluation of the autoscaling of the leaders.
+	// +optional
+	Autoscaling *AutoscalingStatus `json:"autoscaling,omitempty"`
+
+	// The journal of the reshard in progress.
+	// +optional
+	Reshard *ReshardJournal `json:"reshard,omitempty"`
+
+	// The latest available observations of the cluster state.
+	// +optional
+	// +listType=map
+	// +listMapKey=type
+	Conditions []RedisClusterCondition `json:"conditions,omitempty"`
 }
 
 // +kubebuilder:object:root=true
 // +kubebuilder:subresource:status
 // +kubebuilder:resource:shortName=rdc
+// +kubebuilder:printcolumn:name="State",type=string,JSONPath=`.status.clusterState`
+// +kubebuilder:printcolumn:name="Available",type=string,JSONPath=`.status.conditions[?(@.type=="Available")].status`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
 // RedisCluster is the Schema for the redisclusters API.
 type RedisCluster struct {
@@ -0,0 +1,255 @@
+/*
+
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// RedisContainerName is the name of the container that runs redis in the Redis pod spec.
+const RedisContainerName = "redis-container"
+
+// The share of the redis container memory limit that a leader may use after a scale down.
+// The rest is kept for the replication buffers and the forks of the persistence process.
+const maxLeaderMemoryUsagePercent = 80
+
+// Labels that are set by the operator on every Redis pod, and can not be used by the pod label selector.
+var reservedPodLabels = []string{"redis-node-role", "leader-name", "node-name", "redis-cluster"}
+
+// The name of the designated leader of a shard, which keys the shard weights.
+var shardNamePattern = regexp.MustCompile(`^redis-node-[0-9]+$`)
+
+// The defaults of the autoscaling of the leaders.
+const (
+	defaultAutoscalingTolerancePercent = 10
+	defaultScaleUpCooldown             = 5 * time.Minute
+	defaultScaleDownCooldown           = 30 * time.Minute
+)
+
+// SetupWebhookWithManager registers the defaulting and validating webhooks of the RedisCluster in the manager.
+func (r *RedisCluster) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		Complete()
+}
+
+// +kubebuilder:webhook:path=/mutate-db-payu-com-v1-rediscluster,mutating=true,failurePolicy=fail,sideEffects=None,groups=db.payu.com,resources=redisclusters,verbs=create;update,versions=v1,name=mrediscluster.kb.io,admissionReviewVersions={v1beta1}
+
+var _ webhook.Defaulter = &RedisCluster{}
+
+// Default implements webhook.Defaulter so a webhook will be registered for the type.
+func (r *RedisCluster) Default() {
+	if r.Spec.EnableDefaultAffinity == nil {
+		enableDefaultAffinity := true
+		r.Spec.EnableDefaultAffinity = &enableDefaultAffinity
+	}
+	if r.Spec.DeletionPolicy == "" {
+		r.Spec.DeletionPolicy = DeletionPolicyDelete
+	}
+	if r.Spec.Persistence != nil && r.Spec.Persistence.Mode == "" {
+		r.Spec.Persistence.Mode = PersistenceRDB
+	}
+	if autoscaling := r.Spec.Autoscaling; autoscaling != nil {
+		if autoscaling.TolerancePercent == nil {
+			tolerancePercent := defaultAutoscalingTolerancePercent
+			autoscaling.TolerancePercent = &tolerancePercent
+		}
+		if autoscaling.ScaleUpCooldown == nil {
+			autoscaling.ScaleUpCooldown = &metav1.Duration{Duration: defaultScaleUpCooldown}
+		}
+		if autoscaling.ScaleDownCooldown == nil {
+			autoscaling.ScaleDownCooldown = &metav1.Duration{Duration: defaultScaleDownCooldown}
+		}
+	}
+}
+
+// +kubebuilder:webhook:path=/validate-db-payu-com-v1-rediscluster,mutating=false,failurePolicy=fail,sideEffects=None,groups=db.payu.com,resources=redisclusters,verbs=create;update,versions=v1,name=vrediscluster.kb.io,admissionReviewVersions={v1beta1}
+
+var _ webhook.Validator = &RedisCluster{}
+
+// ValidateCreate implements webhook.Validator so a webhook will be registered for the type.
+func (r *RedisCluster) ValidateCreate() error {
+	return r.toInvalidError(r.validateSpec())
+}
+
+// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type.
+func (r *RedisCluster) ValidateUpdate(old runtime.Object) error {
+	allErrs := r.validateSpec()
+	if oldRedisCluster, ok := old.(*RedisCluster); ok {
+		allErrs = append(allErrs, r.validateLeaderCountDrop(oldRedisCluster)...)
+		allErrs = append(allErrs, r.validateTLSToggle(oldRedisCluster)...)
+		allErrs = append(allErrs, r.validateAnnounceHostnamesToggle(oldRedisCluster)...)
+	}
+	return r.toInvalidError(allErrs)
+}
+
+// ValidateDelete implements webhook.Validator so a webhook will be registered for the type.
+func (r *RedisCluster) ValidateDelete() error {
+	return nil
+}
+
+func (r *RedisCluster) toInvalidError(allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return apierrors.NewInvalid(schema.GroupKind{Group: GroupVersion.Group, Kind: "RedisCluster"}, r.Name, allErrs)
+}
+
+func (r *RedisCluster) validateSpec() field.ErrorList {
+	var allErrs field.ErrorList
+	specPath := field.NewPath("spec")
+	for _, label := range reservedPodLabels {
+		if _, exists := r.Spec.PodLabelSelector[label]; exists {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("podLabelSelector").Key(label), r.Spec.PodLabelSelector[label],
+				fmt.Sprintf("the label '%s' is set by the operator on every Redis pod and can not be used by the pod label selector", label)))
+		}
+	}
+	if redisContainer(&r.Spec.RedisPodSpec) == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("redisPodSpec", "containers"),
+			fmt.Sprintf("a container named '%s' that runs redis is required", RedisContainerName)))
+	}
+	if r.Spec.Auth != nil && r.Spec.Auth.SecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("auth", "secretRef", "name"), "the name of the secret that holds the redis credentials is required"))
+	}
+	if r.Spec.TLS != nil && r.Spec.TLS.SecretRef.Name == "" {
+		allErrs = append(allErrs, field.Required(specPath.Child("tls", "secretRef", "name"), "the name of the secret that holds the certificates is required"))
+	}
+	if r.Spec.Persistence != nil && r.Spec.Persistence.Size.Sign() <= 0 {
+		allErrs = append(allErrs, field.Invalid(specPath.Child("persistence", "size"), r.Spec.Persistence.Size.String(), "the size of the volumes should be positive"))
+	}
+	if r.Spec.DeletionPolicy == DeletionPolicySnapshot && r.Spec.Persistence == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("persistence"), "the final snapshots are kept on the volumes of spec.persistence, it is required by the Snapshot deletion policy"))
+	}
+	if r.Spec.SlotWeights != nil {
+		allErrs = append(allErrs, r.validateSlotWeights(specPath.Child("slotWeights"))...)
+	}
+	if r.Spec.Autoscaling != nil {
+		allErrs = append(allErrs, r.validateAutoscaling(specPath.Child("autoscaling"))...)
+	}
+	for i := range r.Spec.MaintenanceWindows {
+		if err := r.Spec.MaintenanceWindows[i].Validate(); err != nil {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("maintenanceWindows").Index(i), r.Spec.MaintenanceWindows[i], err.Error()))
+		}
+	}
+	return allErrs
+}
+
+func (r *RedisCluster) validateSlotWeights(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for shard, weight := range r.Spec.SlotWeights.Shards {
+		if !shardNamePattern.MatchString(shard) {
+			allErrs = append(allErrs, field.Invalid(path.Child("shards").Key(shard), shard, "the shards are named by their designated leader, redis-node-N"))
+		}
+		if weight < 1 {
+			allErrs = append(allErrs, field.Invalid(path.Child("shards").Key(shard), weight, "the weight of a shard should be at least 1"))
+		}
+	}
+	if r.Spec.SlotWeights.FromMemoryLimit {
+		if container := redisContainer(&r.Spec.RedisPodSpec); container != nil && container.Resources.Limits.Memory().IsZero() {
+			allErrs = append(allErrs, field.Required(path.Child("fromMemoryLimit"),
+				fmt.Sprintf("the '%s' container should have a memory limit to weigh the leaders by it", RedisContainerName)))
+		}
+	}
+	return allErrs
+}
+
+func (r *RedisCluster) validateAutoscaling(path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	autoscaling := r.Spec.Autoscaling
+	if autoscaling.MinLeaders > autoscaling.MaxLeaders {
+		allErrs = append(allErrs, field.Invalid(path.Child("maxLeaders"), autoscaling.MaxLeaders, "the maximal number of leaders should not be below the minimal number of leaders"))
+	}
+	if autoscaling.TargetMemoryPercent == nil && autoscaling.TargetCPUPercent == nil {
+		allErrs = append(allErrs, field.Required(path, "a target memory or CPU usage is required to scale the leaders by it"))
+	}
+	for name, cooldown := range map[string]*metav1.Duration{"scaleUpCooldown": autoscaling.ScaleUpCooldown, "scaleDownCooldown": autoscaling.ScaleDownCooldown} {
+		if cooldown != nil && cooldown.Duration < 0 {
+			allErrs = append(allErrs, field.Invalid(path.Child(name), cooldown.Duration.String(), "the cooldown should not be negative"))
+		}
+	}
+	return allErrs
+}
+
+// The nodes of a running cluster can not reach each other while some of them serve TLS and the others do not,
+// so TLS can only be set when the cluster is created
+func (r *RedisCluster) validateTLSToggle(old *RedisCluster) field.ErrorList {
+	if (r.Spec.TLS == nil) == (old.Spec.TLS == nil) {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(field.NewPath("spec", "tls"), "TLS can not be enabled or disabled on an existing cluster")}
+}
+
+// The nodes of a running cluster know each other by the addresses they announced when they joined it,
+// so the host names can only be announced from the creation of the cluster
+func (r *RedisCluster) validateAnnounceHostnamesToggle(old *RedisCluster) field.ErrorList {
+	if r.Spec.AnnounceHostnames == old.Spec.AnnounceHostnames {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(field.NewPath("spec", "announceHostnames"), "host names can not be announced or stop being announced on an existing cluster")}
+}
+
+// Checks that the memory used by the current leaders fits into the remaining leaders, the data of the removed
+// leaders is resharded evenly between them
+func (r *RedisCluster) validateLeaderCountDrop(old *RedisCluster) field.ErrorList {
+	if r.Spec.LeaderCount <= 0 || r.Spec.LeaderCount >= old.Spec.LeaderCount {
+		return nil
+	}
+	container := redisContainer(&r.Spec.RedisPodSpec)
+	if container == nil {
+		return nil
+	}
+	memoryLimit, exists := container.Resources.Limits[corev1.ResourceMemory]
+	if !exists || memoryLimit.IsZero() {
+		return nil
+	}
+	var leadersUsedMemory int64
+	for _, n := range old.Status.Nodes {
+		if n.Role == "leader" {
+			leadersUsedMemory += n.UsedMemory
+		}
+	}
+	expectedMemoryPerLeader := leadersUsedMemory / int64(r.Spec.LeaderCount)
+	allowedMemoryPerLeader := memoryLimit.Value() * maxLeaderMemoryUsagePercent / 100
+	if expectedMemoryPerLeader <= allowedMemoryPerLeader {
+		return nil
+	}
+	return field.ErrorList{field.Forbidden(field.NewPath("spec", "leaderCount"),
+		fmt.Sprintf("scaling down from %d to %d leaders would put about %s of data on each leader, above %d%% of the redis container memory limit (%s)",
+			old.Spec.LeaderCount, r.Spec.LeaderCount, resource.NewQuantity(expectedMemoryPerLeader, resource.BinarySI).String(),
+			maxLeaderMemoryUsagePercent, memoryLimit.String()))}
+}
+
+func redisContainer(podSpec *corev1.PodSpec) *corev1.Container {
+	for i := range podSpec.Containers {
+		if podSpec.Containers[i].Name == RedisContainerName {
+			return &podSpec.Containers[i]
+		}
+	}
+	return nil
+}
@@ -0,0 +1,292 @@
+package v1
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+var k8sClient client.Client
+
+// The webhook tests run against a local api server started by envtest, the tests are skipped when its binaries are not installed
+func TestRedisClusterWebhook(t *testing.T) {
+	assetsPath := os.Getenv("KUBEBUILDER_ASSETS")
+	if assetsPath == "" {
+		assetsPath = "/usr/local/kubebuilder/bin"
+	}
+	if _, err := os.Stat(filepath.Join(assetsPath, "kube-apiserver")); err != nil {
+		t.Skipf("envtest binaries are not found in %s, skipping webhook tests", assetsPath)
+	}
+
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths: []string{filepath.Join("..", "..", "config", "crd", "bases")},
+		WebhookInstallOptions: envtest.WebhookInstallOptions{
+			DirectoryPaths: []string{filepath.Join("..", "..", "config", "webhook")},
+		},
+	}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		t.Fatalf("Failed to start the test environment: %v", err)
+	}
+	defer testEnv.Stop()
+
+	scheme := runtime.NewScheme()
+	if err = AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	webhookInstallOptions := &testEnv.WebhookInstallOptions
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:             scheme,
+		Host:               webhookInstallOptions.LocalServingHost,
+		Port:               webhookInstallOptions.LocalServingPort,
+		CertDir:            webhookInstallOptions.LocalServingCertDir,
+		LeaderElection:     false,
+		MetricsBindAddress: "0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = (&RedisCluster{}).SetupWebhookWithManager(mgr); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		if err := mgr.Start(stop); err != nil {
+			t.Errorf("Failed to start the manager: %v", err)
+		}
+	}()
+
+	dialer := &net.Dialer{Timeout: time.Second}
+	addrPort := fmt.Sprintf("%s:%d", webhookInstallOptions.LocalServingHost, webhookInstallOptions.LocalServingPort)
+	err = wait.PollImmediate(time.Second, 10*time.Second, func() (bool, error) {
+		conn, err := tls.DialWithDialer(dialer, "tcp", addrPort, &tls.Config{InsecureSkipVerify: true})
+		if err != nil {
+			return false, nil
+		}
+		conn.Close()
+		return true, nil
+	})
+	if err != nil {
+		t.Fatalf("Webhook server is not ready: %v", err)
+	}
+	k8sClient = mgr.GetClient()
+
+	t.Run("Defaulting", testDefaulting)
+	t.Run("ReservedPodLabels", testReservedPodLabels)
+	t.Run("MissingRedisContainer", testMissingRedisContainer)
+	t.Run("LeaderCountDrop", testLeaderCountDrop)
+	t.Run("TLSToggle", testTLSToggle)
+	t.Run("Persistence", testPersistence)
+	t.Run("AnnounceHostnamesToggle", testAnnounceHostnamesToggle)
+	t.Run("SlotWeights", testSlotWeights)
+	t.Run("Autoscaling", testAutoscaling)
+}
+
+func newTestRedisCluster(name string) *RedisCluster {
+	return &RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      name,
+			Namespace: "default",
+		},
+		Spec: RedisClusterSpec{
+			LeaderCount:          3,
+			LeaderFollowersCount: 1,
+			PodLabelSelector:     map[string]string{"app": name},
+			RedisPodSpec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name:  RedisContainerName,
+					Image: "redis:testing",
+					Resources: corev1.ResourceRequirements{
+						Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse("1Gi")},
+					},
+				}},
+			},
+		},
+	}
+}
+
+func expectRejected(t *testing.T, err error, message string) {
+	if err == nil {
+		t.Errorf("Expected the request to be rejected with [%s]", message)
+	} else if !strings.Contains(err.Error(), message) {
+		t.Errorf("Expected the request to be rejected with [%s], got: %v", message, err)
+	}
+}
+
+func testDefaulting(t *testing.T) {
+	rdc := newTestRedisCluster("defaulting")
+	if err := k8sClient.Create(context.Background(), rdc); err != nil {
+		t.Fatalf("Failed to create a valid RedisCluster: %v", err)
+	}
+	if rdc.Spec.EnableDefaultAffinity == nil || !*rdc.Spec.EnableDefaultAffinity {
+		t.Errorf("Expected enableDefaultAffinity to be defaulted to true")
+	}
+
+	disabled := newTestRedisCluster("affinity-disabled")
+	enableDefaultAffinity := false
+	disabled.Spec.EnableDefaultAffinity = &enableDefaultAffinity
+	if err := k8sClient.Create(context.Background(), disabled); err != nil {
+		t.Fatalf("Failed to create a valid RedisCluster: %v", err)
+	}
+	if disabled.Spec.EnableDefaultAffinity == nil || *disabled.Spec.EnableDefaultAffinity {
+		t.Errorf("Expected enableDefaultAffinity to keep the value set by the user")
+	}
+}
+
+func testReservedPodLabels(t *testing.T) {
+	for _, label := range reservedPodLabels {
+		rdc := newTestRedisCluster("reserved-label")
+		rdc.Spec.PodLabelSelector[label] = "value"
+		err := k8sClient.Create(context.Background(), rdc)
+		expectRejected(t, err, fmt.Sprintf("the label '%s' is set by the operator", label))
+	}
+}
+
+func testMissingRedisContainer(t *testing.T) {
+	rdc := newTestRedisCluster("missing-container")
+	rdc.Spec.RedisPodSpec.Containers[0].Name = "other-container"
+	err := k8sClient.Create(context.Background(), rdc)
+	expectRejected(t, err, fmt.Sprintf("a container named '%s' that runs redis is required", RedisContainerName))
+}
+
+func testLeaderCountDrop(t *testing.T) {
+	rdc := newTestRedisCluster("leader-count-drop")
+	rdc.Spec.LeaderCount = 6
+	if err := k8sClient.Create(context.Background(), rdc); err != nil {
+		t.Fatalf("Failed to create a valid RedisCluster: %v", err)
+	}
+	for i := 0; i < rdc.Spec.LeaderCount; i++ {
+		rdc.Status.Nodes = append(rdc.Status.Nodes, RedisNodeStatus{
+			Name:       fmt.Sprintf("redis-node-%d", i),
+			LeaderName: fmt.Sprintf("redis-node-%d", i),
+			Role:       "leader",
+			NodeState:  "NodeOK",
+			IsUpToDate: true,
+			UsedMemory: 500 * 1024 * 1024,
+		})
+	}
+	if err := k8sClient.Status().Update(context.Background(), rdc); err != nil {
+		t.Fatalf("Failed to update the RedisCluster status: %v", err)
+	}
+
+	// 6 leaders of 500Mi fit into 4 leaders of 1Gi, but not into 3
+	rdc.Spec.LeaderCount = 3
+	err := k8sClient.Update(context.Background(), rdc)
+	expectRejected(t, err, "scaling down from 6 to 3 leaders")
+
+	rdc.Spec.LeaderCount = 4
+	if err = k8sClient.Update(context.Background(), rdc); err != nil {
+		t.Errorf("Expected the scale down to 4 leaders to be allowed: %v", err)
+	}
+}
+
+func testTLSToggle(t *testing.T) {
+	rdc := newTestRedisCluster("tls-toggle")
+	if err := k8sClient.Create(context.Background(), rdc); err != nil {
+		t.Fatalf("Failed to create a valid RedisCluster: %v", err)
+	}
+	rdc.Spec.TLS = &RedisClusterTLS{SecretRef: corev1.LocalObjectReference{Name: "redis-tls"}}
+	err := k8sClient.Update(context.Background(), rdc)
+	expectRejected(t, err, "TLS can not be enabled or disabled on an existing cluster")
+}
+
+func testPersistence(t *testing.T) {
+	rdc := newTestRedisCluster("persistence")
+	rdc.Spec.Persistence = &RedisClusterPersistence{Size: resource.MustParse("0")}
+	err := k8sClient.Create(context.Background(), rdc)
+	expectRejected(t, err, "the size of the volumes should be positive")
+
+	rdc = newTestRedisCluster("persistence")
+	rdc.Spec.DeletionPolicy = DeletionPolicySnapshot
+	err = k8sClient.Create(context.Background(), rdc)
+	expectRejected(t, err, "it is required by the Snapshot deletion policy")
+
+	rdc = newTestRedisCluster("persistence")
+	rdc.Spec.Persistence = &RedisClusterPersistence{Size: resource.MustParse("1Gi")}
+	rdc.Spec.DeletionPolicy = DeletionPolicySnapshot
+	if err := k8sClient.Create(context.Background(), rdc); err != nil {
+		t.Fatalf("Failed to create a valid RedisCluster: %v", err)
+	}
+	if rdc.Spec.Persistence.Mode != PersistenceRDB {
+		t.Errorf("Expected the persistence mode to be defaulted to RDB, got [%s]", rdc.Spec.Persistence.Mode)
+	}
+}
+
+func testAnnounceHostnamesToggle(t *testing.T) {
+	rdc := newTestRedisCluster("announce-hostnames-toggle")
+	if err := k8sClient.Create(context.Background(), rdc); err != nil {
+		t.Fatalf("Failed to create a valid RedisCluster: %v", err)
+	}
+	rdc.Spec.AnnounceHostnames = true
+	err := k8sClient.Update(context.Background(), rdc)
+	expectRejected(t, err, "host names can not be announced or stop being announced on an existing cluster")
+}
+
+func testSlotWeights(t *testing.T) {
+	rdc := newTestRedisCluster("slot-weights")
+	rdc.Spec.SlotWeights = &SlotWeights{Shards: map[string]int{"redis-node-0-1": 2}}
+	err := k8sClient.Create(context.Background(), rdc)
+	expectRejected(t, err, "the shards are named by their designated leader")
+
+	rdc = newTestRedisCluster("slot-weights")
+	rdc.Spec.SlotWeights = &SlotWeights{Shards: map[string]int{"redis-node-0": 0}}
+	err = k8sClient.Create(context.Background(), rdc)
+	expectRejected(t, err, "the weight of a shard should be at least 1")
+
+	rdc = newTestRedisCluster("slot-weights")
+	rdc.Spec.RedisPodSpec.Containers[0].Resources.Limits = nil
+	rdc.Spec.SlotWeights = &SlotWeights{FromMemoryLimit: true}
+	err = k8sClient.Create(context.Background(), rdc)
+	expectRejected(t, err, "should have a memory limit to weigh the leaders by it")
+
+	rdc = newTestRedisCluster("slot-weights")
+	rdc.Spec.SlotWeights = &SlotWeights{Shards: map[string]int{"redis-node-0": 2}, FromMemoryLimit: true}
+	if err := k8sClient.Create(context.Background(), rdc); err != nil {
+		t.Fatalf("Failed to create a valid RedisCluster: %v", err)
+	}
+}
+
+func testAutoscaling(t *testing.T) {
+	targetPercent := 70
+	rdc := newTestRedisCluster("autoscaling")
+	rdc.Spec.Autoscaling = &RedisClusterAutoscaling{MinLeaders: 6, MaxLeaders: 3, TargetMemoryPercent: &targetPercent}
+	err := k8sClient.Create(context.Background(), rdc)
+	expectRejected(t, err, "the maximal number of leaders should not be below the minimal number of leaders")
+
+	rdc = newTestRedisCluster("autoscaling")
+	rdc.Spec.Autoscaling = &RedisClusterAutoscaling{MinLeaders: 3, MaxLeaders: 6}
+	err = k8sClient.Create(context.Background(), rdc)
+	expectRejected(t, err, "a target memory or CPU usage is required to scale the leaders by it")
+
+	rdc = newTestRedisCluster("autoscaling")
+	rdc.Spec.Autoscaling = &RedisClusterAutoscaling{MinLeaders: 3, MaxLeaders: 6, TargetCPUPercent: &targetPercent}
+	if err := k8sClient.Create(context.Background(), rdc); err != nil {
+		t.Fatalf("Failed to create a valid RedisCluster: %v", err)
+	}
+	autoscaling := rdc.Spec.Autoscaling
+	if autoscaling.TolerancePercent == nil || *autoscaling.TolerancePercent != defaultAutoscalingTolerancePercent {
+		t.Errorf("Expected tolerancePercent to be defaulted to %d", defaultAutoscalingTolerancePercent)
+	}
+	if autoscaling.ScaleUpCooldown == nil || autoscaling.ScaleUpCooldown.Duration != defaultScaleUpCooldown {
+		t.Errorf("Expected scaleUpCooldown to be defaulted to %v", defaultScaleUpCooldown)
+	}
+	if autoscaling.ScaleDownCooldown == nil || autoscaling.ScaleDownCooldown.Duration != defaultScaleDownCooldown {
+		t.Errorf("Expected scaleDownCooldown to be defaulted to %v", defaultScaleDownCooldown)
+	}
+}
@@ -22,9 +22,70 @@ package v1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AutoscalingStatus) DeepCopyInto(out *AutoscalingStatus) {
+	*out = *in
+	if in.MemoryPercent != nil {
+		in, out := &in.MemoryPercent, &out.MemoryPercent
+		*out = new(int)
+		**out = **in
+	}
+	if in.CPUPercent != nil {
+		in, out := &in.CPUPercent, &out.CPUPercent
+		*out = new(int)
+		**out = **in
+	}
+	if in.LastScaleTime != nil {
+		in, out := &in.LastScaleTime, &out.LastScaleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AutoscalingStatus.
+func (in *AutoscalingStatus) DeepCopy() *AutoscalingStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(AutoscalingStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LeaderSlots) DeepCopyInto(out *LeaderSlots) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LeaderSlots.
+func (in *LeaderSlots) DeepCopy() *LeaderSlots {
+	if in == nil {
+		return nil
+	}
+	out := new(LeaderSlots)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisCluster) DeepCopyInto(out *RedisCluster) {
 	*out = *in
@@ -52,6 +113,78 @@ func (in *RedisCluster) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisClusterAuth) DeepCopyInto(out *RedisClusterAuth) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisClusterAuth.
+func (in *RedisClusterAuth) DeepCopy() *RedisClusterAuth {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisClusterAuth)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisClusterAutoscaling) DeepCopyInto(out *RedisClusterAutoscaling) {
+	*out = *in
+	if in.TargetMemoryPercent != nil {
+		in, out := &in.TargetMemoryPercent, &out.TargetMemoryPercent
+		*out = new(int)
+		**out = **in
+	}
+	if in.TargetCPUPercent != nil {
+		in, out := &in.TargetCPUPercent, &out.TargetCPUPercent
+		*out = new(int)
+		**out = **in
+	}
+	if in.TolerancePercent != nil {
+		in, out := &in.TolerancePercent, &out.TolerancePercent
+		*out = new(int)
+		**out = **in
+	}
+	if in.ScaleUpCooldown != nil {
+		in, out := &in.ScaleUpCooldown, &out.ScaleUpCooldown
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ScaleDownCooldown != nil {
+		in, out := &in.ScaleDownCooldown, &out.ScaleDownCooldown
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisClusterAutoscaling.
+func (in *RedisClusterAutoscaling) DeepCopy() *RedisClusterAutoscaling {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisClusterAutoscaling)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisClusterCondition) DeepCopyInto(out *RedisClusterCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisClusterCondition.
+func (in *RedisClusterCondition) DeepCopy() *RedisClusterCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisClusterCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisClusterList) DeepCopyInto(out *RedisClusterList) {
 	*out = *in
@@ -84,9 +217,35 @@ func (in *RedisClusterList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisClusterPersistence) DeepCopyInto(out *RedisClusterPersistence) {
+	*out = *in
+	if in.StorageClassName != nil {
+		in, out := &in.StorageClassName, &out.StorageClassName
+		*out = new(string)
+		**out = **in
+	}
+	out.Size = in.Size.DeepCopy()
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisClusterPersistence.
+func (in *RedisClusterPersistence) DeepCopy() *RedisClusterPersistence {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisClusterPersistence)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *RedisClusterSpec) DeepCopyInto(out *RedisClusterSpec) {
 	*out = *in
+	if in.EnableDefaultAffinity != nil {
+		in, out := &in.EnableDefaultAffinity, &out.EnableDefaultAffinity
+		*out = new(bool)
+		**out = **in
+	}
 	if in.Annotations != nil {
 		in, out := &in.Annotations, &out.Annotations
 		*out = make(map[string]string, len(*in))
@@ -109,6 +268,36 @@ func (in *RedisClusterSpec) DeepCopyInto(out *RedisClusterSpec) {
 		}
 	}
 	in.RedisPodSpec.DeepCopyInto(&out.RedisPodSpec)
+	if in.Auth != nil {
+		in, out := &in.Auth, &out.Auth
+		*out = new(RedisClusterAuth)
+		**out = **in
+	}
+	if in.TLS != nil {
+		in, out := &in.TLS, &out.TLS
+		*out = new(RedisClusterTLS)
+		**out = **in
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
+	if in.Persistence != nil {
+		in, out := &in.Persistence, &out.Persistence
+		*out = new(RedisClusterPersistence)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.SlotWeights != nil {
+		in, out := &in.SlotWeights, &out.SlotWeights
+		*out = new(SlotWeights)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(RedisClusterAutoscaling)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisClusterSpec.
@@ -129,6 +318,40 @@ func (in *RedisClusterStatus) DeepCopyInto(out *RedisClusterStatus) {
 		*out = make([]corev1.ObjectReference, len(*in))
 		copy(*out, *in)
 	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]RedisNodeStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]ZoneSpread, len(*in))
+		copy(*out, *in)
+	}
+	if in.SlotDistribution != nil {
+		in, out := &in.SlotDistribution, &out.SlotDistribution
+		*out = make([]LeaderSlots, len(*in))
+		copy(*out, *in)
+	}
+	if in.Autoscaling != nil {
+		in, out := &in.Autoscaling, &out.Autoscaling
+		*out = new(AutoscalingStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Reshard != nil {
+		in, out := &in.Reshard, &out.Reshard
+		*out = new(ReshardJournal)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]RedisClusterCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisClusterStatus.
@@ -140,3 +363,103 @@ func (in *RedisClusterStatus) DeepCopy() *RedisClusterStatus {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisClusterTLS) DeepCopyInto(out *RedisClusterTLS) {
+	*out = *in
+	out.SecretRef = in.SecretRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisClusterTLS.
+func (in *RedisClusterTLS) DeepCopy() *RedisClusterTLS {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisClusterTLS)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RedisNodeStatus) DeepCopyInto(out *RedisNodeStatus) {
+	*out = *in
+	if in.Slots != nil {
+		in, out := &in.Slots, &out.Slots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RedisNodeStatus.
+func (in *RedisNodeStatus) DeepCopy() *RedisNodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(RedisNodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ReshardJournal) DeepCopyInto(out *ReshardJournal) {
+	*out = *in
+	if in.MigratingSlot != nil {
+		in, out := &in.MigratingSlot, &out.MigratingSlot
+		*out = new(int)
+		**out = **in
+	}
+	if in.PendingSlots != nil {
+		in, out := &in.PendingSlots, &out.PendingSlots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	in.LastUpdateTime.DeepCopyInto(&out.LastUpdateTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ReshardJournal.
+func (in *ReshardJournal) DeepCopy() *ReshardJournal {
+	if in == nil {
+		return nil
+	}
+	out := new(ReshardJournal)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SlotWeights) DeepCopyInto(out *SlotWeights) {
+	*out = *in
+	if in.Shards != nil {
+		in, out := &in.Shards, &out.Shards
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SlotWeights.
+func (in *SlotWeights) DeepCopy() *SlotWeights {
+	if in == nil {
+		return nil
+	}
+	out := new(SlotWeights)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZoneSpread) DeepCopyInto(out *ZoneSpread) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZoneSpread.
+func (in *ZoneSpread) DeepCopy() *ZoneSpread {
+	if in == nil {
+		return nil
+	}
+	out := new(ZoneSpread)
+	in.DeepCopyInto(out)
+	return out
+}
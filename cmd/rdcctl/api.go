@@ -0,0 +1,248 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PayU/redis-operator/controllers"
+	"github.com/pkg/errors"
+)
+
+const jobPollInterval = 2 * time.Second
+
+type apiOptions struct {
+	address string
+	token   string
+	caFile  string
+}
+
+// The entry points of the operator API that rdcctl calls, by command
+var entryPoints = map[string]string{
+	"state":           http.MethodGet,
+	"info":            http.MethodGet,
+	"plan":            http.MethodGet,
+	"rebalance":       http.MethodPost,
+	"memoryRebalance": http.MethodPost,
+	"fix":             http.MethodPost,
+	"test":            http.MethodPost,
+	"forgetLostNodes": http.MethodPost,
+	"upgrade":         http.MethodPost,
+	"reset":           http.MethodPost,
+}
+
+type apiClient struct {
+	opts   apiOptions
+	client *http.Client
+}
+
+func newAPIClient(opts apiOptions) (*apiClient, error) {
+	httpClient := &http.Client{Timeout: 5 * time.Minute}
+	if opts.caFile != "" {
+		ca, err := ioutil.ReadFile(opts.caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read the CA of the operator API")
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, errors.Errorf("No certificate was found in %s", opts.caFile)
+		}
+		httpClient.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}
+	}
+	return &apiClient{opts: opts, client: httpClient}, nil
+}
+
+// Calls the path of the operator API and decodes the JSON response, the error responses are returned as errors
+func (a *apiClient) call(method string, path string, response interface{}) (int, error) {
+	req, err := http.NewRequest(method, strings.TrimSuffix(a.opts.address, "/")+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	if a.opts.token != "" {
+		req.Header.Set("Authorization", "Bearer "+a.opts.token)
+	}
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return 0, errors.Wrapf(err, "Failed to call the operator API at %s", a.opts.address)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, err
+	}
+	var apiErr controllers.ErrorResponse
+	isError := json.Unmarshal(body, &apiErr) == nil && apiErr.Error != ""
+	// A request for a job while another job of the cluster is running is answered by 409 with the running job
+	if resp.StatusCode >= http.StatusBadRequest && (resp.StatusCode != http.StatusConflict || isError) {
+		if !isError {
+			apiErr.Error = strings.TrimSpace(string(body))
+		}
+		return resp.StatusCode, errors.Errorf("%s %s: %d %s", method, path, resp.StatusCode, apiErr.Error)
+	}
+	if err := json.Unmarshal(body, response); err != nil {
+		return resp.StatusCode, errors.Wrapf(err, "Failed to decode the response of %s %s", method, path)
+	}
+	return resp.StatusCode, nil
+}
+
+func clusterPath(namespace string, cluster string, endpoint string) string {
+	return fmt.Sprintf("/cluster/%s/%s/%s", namespace, cluster, endpoint)
+}
+
+func callEntryPoint(out io.Writer, opts *options, command string, cluster string) error {
+	method, exists := entryPoints[command]
+	if !exists {
+		return errors.Errorf("unknown command %s, run 'rdcctl --help' for the list of commands", command)
+	}
+	api, err := newAPIClient(opts.api)
+	if err != nil {
+		return err
+	}
+	path := clusterPath(opts.namespace, cluster, command)
+	switch command {
+	case "state":
+		var state controllers.ClusterStateResponse
+		if _, err := api.call(method, path, &state); err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "Operator state: %s\nCluster state:  %s\n", state.OperatorState, state.ClusterState)
+		return nil
+	case "info":
+		var info controllers.ClusterInfoResponse
+		if _, err := api.call(method, path, &info); err != nil {
+			return err
+		}
+		printClusterInfo(out, &info)
+		return nil
+	case "plan":
+		query := url.Values{}
+		if opts.planLeaderCount >= 0 {
+			query.Set("leaderCount", strconv.Itoa(opts.planLeaderCount))
+		}
+		if opts.planLeaderFollowersCount >= 0 {
+			query.Set("leaderFollowersCount", strconv.Itoa(opts.planLeaderFollowersCount))
+		}
+		if len(query) > 0 {
+			path += "?" + query.Encode()
+		}
+		var plan controllers.ClusterPlan
+		if _, err := api.call(method, path, &plan); err != nil {
+			return err
+		}
+		printPlan(out, &plan)
+		return nil
+	case "rebalance", "memoryRebalance", "fix", "test":
+		if command == "memoryRebalance" && opts.applyMemoryRebalance {
+			path += "?apply=true"
+		}
+		var job controllers.Job
+		status, err := api.call(method, path, &job)
+		if err != nil {
+			return err
+		}
+		if status == http.StatusConflict {
+			printJob(out, &job)
+			return errors.Errorf("job %s of kind %s is still running on the cluster", job.ID, job.Kind)
+		}
+		if opts.wait {
+			return waitForJob(out, api, opts.namespace, cluster, &job)
+		}
+		printJob(out, &job)
+		return nil
+	}
+	var message controllers.MessageResponse
+	if _, err := api.call(method, path, &message); err != nil {
+		return err
+	}
+	fmt.Fprintln(out, message.Message)
+	return nil
+}
+
+func callJob(out io.Writer, opts *options, command string, cluster string, id string) error {
+	api, err := newAPIClient(opts.api)
+	if err != nil {
+		return err
+	}
+	method := http.MethodGet
+	if command == "cancel" {
+		method = http.MethodDelete
+	}
+	var job controllers.Job
+	if _, err := api.call(method, clusterPath(opts.namespace, cluster, "jobs/"+id), &job); err != nil {
+		return err
+	}
+	if opts.wait && command == "job" {
+		return waitForJob(out, api, opts.namespace, cluster, &job)
+	}
+	printJob(out, &job)
+	return nil
+}
+
+// Polls the job until it is finished and prints it, a failed or cancelled job is returned as an error
+func waitForJob(out io.Writer, api *apiClient, namespace string, cluster string, job *controllers.Job) error {
+	for job.State == controllers.JobRunning {
+		fmt.Fprintf(out, "Job %s [%s]: %d/%d %s\n", job.ID, job.Kind, job.Progress.Done, job.Progress.Total, job.Progress.Message)
+		time.Sleep(jobPollInterval)
+		if _, err := api.call(http.MethodGet, clusterPath(namespace, cluster, "jobs/"+job.ID), job); err != nil {
+			return err
+		}
+	}
+	printJob(out, job)
+	if job.State != controllers.JobSucceeded {
+		return errors.Errorf("job %s finished in state %s", job.ID, job.State)
+	}
+	return nil
+}
+
+func printJob(out io.Writer, job *controllers.Job) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "ID:\t%s\n", job.ID)
+	fmt.Fprintf(w, "Kind:\t%s\n", job.Kind)
+	fmt.Fprintf(w, "State:\t%s\n", job.State)
+	fmt.Fprintf(w, "Progress:\t%d/%d %s\n", job.Progress.Done, job.Progress.Total, job.Progress.Message)
+	if job.Error != "" {
+		fmt.Fprintf(w, "Error:\t%s\n", job.Error)
+	}
+	w.Flush()
+	if job.Result != nil {
+		result, _ := json.MarshalIndent(job.Result, "", "  ")
+		fmt.Fprintf(out, "Result:\n%s\n", result)
+	}
+}
+
+func printPlan(out io.Writer, plan *controllers.ClusterPlan) {
+	fmt.Fprintf(out, "Plan of %s/%s for %d leaders with %d followers each (operator state %s, cluster state %s)\n",
+		plan.Namespace, plan.Cluster, plan.LeaderCount, plan.LeaderFollowersCount, plan.OperatorState, plan.ClusterState)
+	if len(plan.Steps) == 0 {
+		fmt.Fprintln(out, "No action is required")
+		return
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "#\tSTATE\tACTION\tNODE\tTARGET\tREASON\tDETAILS")
+	for i, step := range plan.Steps {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n", i+1, step.State, step.Action, step.Node, step.Target, step.Reason, step.Details)
+	}
+	w.Flush()
+}
+
+func printClusterInfo(out io.Writer, info *controllers.ClusterInfoResponse) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tROLE\tLEADER\tIP\tID")
+	for _, n := range info.Nodes {
+		role := "follower"
+		if n.IsLeader {
+			role = "leader"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", n.Name, role, n.LeaderName, n.IP, n.ID)
+	}
+	w.Flush()
+}
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	_ "k8s.io/client-go/plugin/pkg/client/auth/gcp"
+	"k8s.io/client-go/tools/clientcmd"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+)
+
+/*
+	rdcctl is the command line client of the operator. It reads the RedisCluster resources and their state
+	from the api server, and calls the entry points of the operator HTTP API. Installed on the PATH as
+	'kubectl-rdc' it is used as a kubectl plugin: 'kubectl rdc status <cluster>'.
+*/
+
+const usage = `rdcctl reads the state of the redis clusters and calls the entry points of the redis operator.
+
+Usage:
+  rdcctl [flags] <command> <cluster> [args]
+
+Commands reading the RedisCluster resource:
+  status <cluster>           Prints the cluster state and the tables of its leaders and followers
+  list                       Prints the redis clusters of the namespace
+
+Commands calling the operator API:
+  state <cluster>            Prints the operator state and the cluster state
+  info <cluster>             Prints the nodes of the cluster as seen by the operator
+  plan <cluster>             Prints the actions the operator would take on the cluster, without carrying them out
+  rebalance <cluster>        Starts a job that rebalances the slots of the cluster
+  memoryRebalance <cluster>  Starts a job that plans the moves of slots that even out the memory of the leaders,
+                             and carries them out with --apply
+  fix <cluster>              Starts a job that fixes the cluster
+  test <cluster>             Starts a job that runs the test lab on the cluster
+  forgetLostNodes <cluster>  Forgets the lost nodes of the cluster
+  upgrade <cluster>          Upgrades the nodes of the cluster
+  reset <cluster>            Sets the cluster state to reset mode
+  job <cluster> <id>         Prints a job of the cluster
+  cancel <cluster> <id>      Cancels a job of the cluster
+
+Flags:
+`
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = dbv1.AddToScheme(scheme)
+}
+
+type options struct {
+	namespace string
+	api       apiOptions
+	wait      bool
+
+	// Replace the counts of the spec in the plan of the cluster when set
+	planLeaderCount          int
+	planLeaderFollowersCount int
+
+	// Carries out the memory rebalance plan
+	applyMemoryRebalance bool
+}
+
+func main() {
+	var opts options
+	flag.StringVar(&opts.namespace, "namespace", "", "The namespace of the redis cluster, the namespace of the kubeconfig context when not set.")
+	flag.StringVar(&opts.namespace, "n", "", "Shorthand for --namespace.")
+	flag.StringVar(&opts.api.address, "api", envOrDefault("RDCCTL_API", "http://localhost:8080"),
+		"The address of the operator HTTP API, for example a port forward of the manager pod. Defaults to $RDCCTL_API.")
+	flag.StringVar(&opts.api.token, "token", os.Getenv("RDCCTL_TOKEN"),
+		"The bearer token presented to the operator API, the token of the kubeconfig user when not set. Defaults to $RDCCTL_TOKEN.")
+	flag.StringVar(&opts.api.caFile, "api-ca", "", "The CA file that verifies the serving certificate of the operator API.")
+	flag.BoolVar(&opts.wait, "wait", false, "Waits for the jobs started by the command to finish.")
+	flag.IntVar(&opts.planLeaderCount, "leader-count", -1, "The leader count that the plan command plans for, the one of the spec when not set.")
+	flag.IntVar(&opts.planLeaderFollowersCount, "leader-followers-count", -1, "The followers per leader that the plan command plans for, the ones of the spec when not set.")
+	flag.BoolVar(&opts.applyMemoryRebalance, "apply", false, "Carries out the moves of the memory rebalance plan.")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if err := run(&opts, flag.Args()); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
+
+func run(opts *options, args []string) error {
+	if len(args) == 0 {
+		flag.Usage()
+		return fmt.Errorf("no command was given")
+	}
+	command, args := args[0], args[1:]
+	loadKubeconfig(opts)
+	switch command {
+	case "list":
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		return listClusters(os.Stdout, c, opts.namespace)
+	case "status":
+		if len(args) != 1 {
+			return fmt.Errorf("usage: rdcctl status <cluster>")
+		}
+		c, err := newClient()
+		if err != nil {
+			return err
+		}
+		return printStatus(os.Stdout, c, opts.namespace, args[0])
+	case "job", "cancel":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: rdcctl %s <cluster> <id>", command)
+		}
+		return callJob(os.Stdout, opts, command, args[0], args[1])
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("usage: rdcctl %s <cluster>", command)
+	}
+	return callEntryPoint(os.Stdout, opts, command, args[0])
+}
+
+// Defaults the namespace and the API token from the current context of the kubeconfig
+func loadKubeconfig(opts *options) {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	if f := flag.Lookup("kubeconfig"); f != nil && f.Value.String() != "" {
+		loadingRules.ExplicitPath = f.Value.String()
+	}
+	config := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(loadingRules, &clientcmd.ConfigOverrides{})
+	if opts.namespace == "" {
+		namespace, _, err := config.Namespace()
+		if err != nil {
+			namespace = "default"
+		}
+		opts.namespace = namespace
+	}
+	if opts.api.token == "" {
+		if restConfig, err := config.ClientConfig(); err == nil {
+			opts.api.token = restConfig.BearerToken
+		}
+	}
+}
+
+func newClient() (client.Client, error) {
+	config, err := ctrl.GetConfig()
+	if err != nil {
+		return nil, err
+	}
+	return client.New(config, client.Options{Scheme: scheme})
+}
+
+func envOrDefault(name string, value string) string {
+	if v := strings.TrimSpace(os.Getenv(name)); v != "" {
+		return v
+	}
+	return value
+}
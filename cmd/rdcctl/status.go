@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/view"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func listClusters(out io.Writer, c client.Client, namespace string) error {
+	var redisClusters dbv1.RedisClusterList
+	if err := c.List(context.Background(), &redisClusters, client.InNamespace(namespace)); err != nil {
+		return errors.Wrapf(err, "Failed to list the redis clusters of namespace %s", namespace)
+	}
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLEADERS\tFOLLOWERS PER LEADER\tSTATE\tCLUSTER VIEW STATE")
+	for _, rdc := range redisClusters.Items {
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", rdc.Name, rdc.Spec.LeaderCount, rdc.Spec.LeaderFollowersCount, rdc.Status.ClusterState, rdc.Status.ClusterViewState)
+	}
+	return w.Flush()
+}
+
+func printStatus(out io.Writer, c client.Client, namespace string, name string) error {
+	var redisCluster dbv1.RedisCluster
+	if err := c.Get(context.Background(), types.NamespacedName{Namespace: namespace, Name: name}, &redisCluster); err != nil {
+		return errors.Wrapf(err, "Failed to get redis cluster %s/%s", namespace, name)
+	}
+	nodes := redisCluster.Status.Nodes
+	if redisCluster.Status.StateViewVersion == 0 {
+		legacyNodes, err := legacyStateMapNodes(c, &redisCluster)
+		if err != nil {
+			return err
+		}
+		nodes = legacyNodes
+	}
+	writeStatus(out, &redisCluster, nodes)
+	return nil
+}
+
+// Clusters that were not reconciled since their state view was kept in the '<name>-state-map' config map
+func legacyStateMapNodes(c client.Client, redisCluster *dbv1.RedisCluster) ([]dbv1.RedisNodeStatus, error) {
+	var configMap corev1.ConfigMap
+	key := types.NamespacedName{Namespace: redisCluster.Namespace, Name: redisCluster.Name + "-state-map"}
+	if err := c.Get(context.Background(), key, &configMap); err != nil {
+		return nil, errors.Wrapf(err, "The state of redis cluster %s is not in its status, failed to get the state map", redisCluster.Name)
+	}
+	return parseStateMap(configMap.Data["data"])
+}
+
+func parseStateMap(data string) ([]dbv1.RedisNodeStatus, error) {
+	var stateView view.RedisClusterStateView
+	if err := json.Unmarshal([]byte(data), &stateView); err != nil {
+		return nil, errors.Wrap(err, "Failed to parse the state map")
+	}
+	var nodes []dbv1.RedisNodeStatus
+	for _, n := range stateView.Nodes {
+		nodes = append(nodes, dbv1.RedisNodeStatus{
+			Name:       n.Name,
+			LeaderName: n.LeaderName,
+			NodeState:  string(n.NodeState),
+			IsUpToDate: n.IsUpToDate,
+		})
+	}
+	return nodes, nil
+}
+
+func isLeader(n *dbv1.RedisNodeStatus) bool {
+	if n.Role != "" {
+		return n.Role == "leader"
+	}
+	return n.Name == n.LeaderName
+}
+
+// Splits the nodes to the leaders and the followers, sorted by name
+func splitNodes(nodes []dbv1.RedisNodeStatus) ([]dbv1.RedisNodeStatus, []dbv1.RedisNodeStatus) {
+	var leaders, followers []dbv1.RedisNodeStatus
+	for _, n := range nodes {
+		if isLeader(&n) {
+			leaders = append(leaders, n)
+		} else {
+			followers = append(followers, n)
+		}
+	}
+	sort.Slice(leaders, func(i, j int) bool { return leaders[i].Name < leaders[j].Name })
+	sort.Slice(followers, func(i, j int) bool { return followers[i].Name < followers[j].Name })
+	return leaders, followers
+}
+
+func writeStatus(out io.Writer, redisCluster *dbv1.RedisCluster, nodes []dbv1.RedisNodeStatus) {
+	w := tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "Cluster:\t%s/%s\n", redisCluster.Namespace, redisCluster.Name)
+	fmt.Fprintf(w, "Operator state:\t%s\n", redisCluster.Status.ClusterState)
+	fmt.Fprintf(w, "Cluster view state:\t%s\n", redisCluster.Status.ClusterViewState)
+	fmt.Fprintf(w, "Loops since healthy:\t%d\n", redisCluster.Status.NumOfReconcileLoopsSinceHealthyCluster)
+	fmt.Fprintf(w, "Healthy loops in row:\t%d\n", redisCluster.Status.NumOfHealthyReconcileLoopsInRow)
+	for _, condition := range redisCluster.Status.Conditions {
+		fmt.Fprintf(w, "Condition %s:\t%s (%s) %s\n", condition.Type, condition.Status, condition.Reason, condition.Message)
+	}
+	w.Flush()
+
+	leaders, followers := splitNodes(nodes)
+	fmt.Fprintln(out, "\nLEADERS")
+	w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tNODE STATE\tUP TO DATE\tNODE ID\tSLOTS")
+	for _, n := range leaders {
+		fmt.Fprintf(w, "%s\t%s\t%t\t%s\t%s\n", n.Name, n.NodeState, n.IsUpToDate, n.NodeID, strings.Join(n.Slots, ","))
+	}
+	w.Flush()
+
+	fmt.Fprintln(out, "\nFOLLOWERS")
+	w = tabwriter.NewWriter(out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tLEADER\tNODE STATE\tUP TO DATE\tNODE ID\tREPLICATION OFFSET")
+	for _, n := range followers {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%t\t%s\t%d\n", n.Name, n.LeaderName, n.NodeState, n.IsUpToDate, n.NodeID, n.ReplicationOffset)
+	}
+	w.Flush()
+}
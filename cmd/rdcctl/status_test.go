@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+)
+
+func TestParseStateMap(test *testing.T) {
+	data := `{"Name":"rdc-state-map","ClusterState":"ClusterOK","Nodes":{
+		"redis-node-0":{"Name":"redis-node-0","LeaderName":"redis-node-0","IsUpToDate":true,"NodeState":"NodeOK"},
+		"redis-node-0-1":{"Name":"redis-node-0-1","LeaderName":"redis-node-0","IsUpToDate":false,"NodeState":"SyncNode"}}}`
+	nodes, err := parseStateMap(data)
+	if err != nil {
+		test.Fatal(err)
+	}
+	leaders, followers := splitNodes(nodes)
+	if len(leaders) != 1 || leaders[0].Name != "redis-node-0" {
+		test.Errorf("Expected redis-node-0 to be the only leader, got %v", leaders)
+	}
+	if len(followers) != 1 || followers[0].NodeState != "SyncNode" || followers[0].IsUpToDate {
+		test.Errorf("Expected redis-node-0-1 to be a follower in SyncNode state, got %v", followers)
+	}
+	if _, err := parseStateMap("not json"); err == nil {
+		test.Error("Expected an error for a state map that is not json")
+	}
+}
+
+func TestWriteStatus(test *testing.T) {
+	redisCluster := &dbv1.RedisCluster{}
+	redisCluster.Name, redisCluster.Namespace = "rdc", "default"
+	nodes := []dbv1.RedisNodeStatus{
+		{Name: "redis-node-1-1", LeaderName: "redis-node-1", Role: "follower", NodeState: "NodeOK", IsUpToDate: true},
+		{Name: "redis-node-1", LeaderName: "redis-node-1", Role: "leader", NodeState: "NodeOK", IsUpToDate: true, Slots: []string{"8192-16383"}},
+		{Name: "redis-node-0", LeaderName: "redis-node-0", Role: "leader", NodeState: "ReshardNode", Slots: []string{"0-8191"}},
+	}
+	var out bytes.Buffer
+	writeStatus(&out, redisCluster, nodes)
+	output := out.String()
+	leaders := output[strings.Index(output, "LEADERS"):strings.Index(output, "FOLLOWERS")]
+	if strings.Index(leaders, "redis-node-0") > strings.Index(leaders, "redis-node-1") {
+		test.Errorf("Expected the leaders to be sorted by name, got\n%s", leaders)
+	}
+	if !strings.Contains(leaders, "0-8191") || !strings.Contains(leaders, "ReshardNode") {
+		test.Errorf("Expected the slots and the node state of the leaders, got\n%s", leaders)
+	}
+	if followers := output[strings.Index(output, "FOLLOWERS"):]; !strings.Contains(followers, "redis-node-1-1") {
+		test.Errorf("Expected redis-node-1-1 in the followers, got\n%s", followers)
+	}
+}
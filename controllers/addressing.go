@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	"context"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+/*
+	With spec.announceHostnames, the pods of the cluster get stable host names from the headless service
+	'<cluster name>-nodes': <node name>.<cluster name>-nodes.<namespace>.svc. The nodes announce them by
+	cluster-announce-hostname and redirect the clients to them by cluster-preferred-endpoint-type, and the operator
+	reaches the nodes by them as well, so a rescheduled pod keeps its address when its IP changes.
+	The cluster bus still runs over the pod IPs, CLUSTER MEET accepts IP addresses only, the host names are resolved
+	when a node joins the cluster. The host names are announced by Redis 7 and later.
+*/
+
+func clusterHeadlessServiceName(redisClusterName string) string {
+	return redisClusterName + "-nodes"
+}
+
+// Sets the host name of the pod under the headless service and starts redis with the host name announced
+func (r *RedisClusterReconciler) addRedisHostname(redisCluster *dbv1.RedisCluster, spec *corev1.PodSpec, nodeName string) {
+	if !redisCluster.Spec.AnnounceHostnames {
+		return
+	}
+	spec.Hostname = nodeName
+	spec.Subdomain = clusterHeadlessServiceName(redisCluster.Name)
+	hostname := nodeName + "." + spec.Subdomain + "." + redisCluster.Namespace + ".svc"
+	for i := range spec.Containers {
+		container := &spec.Containers[i]
+		if container.Name != dbv1.RedisContainerName {
+			continue
+		}
+		if len(container.Command) == 0 && len(container.Args) == 0 {
+			container.Args = []string{"redis-server", redisConfigPath(container)}
+		}
+		container.Args = append(container.Args,
+			"--cluster-announce-hostname", hostname,
+			"--cluster-preferred-endpoint-type", "hostname",
+		)
+	}
+}
+
+// Creates the headless service that publishes the host names of the pods, the pods are published before they
+// are ready so the nodes can be reached while they join the cluster
+func (r *RedisClusterReconciler) createRedisHeadlessService(redisCluster *dbv1.RedisCluster) error {
+	if !redisCluster.Spec.AnnounceHostnames {
+		return nil
+	}
+	selector := map[string]string{"redis-cluster": redisCluster.Name}
+	for k, v := range redisCluster.Spec.PodLabelSelector {
+		selector[k] = v
+	}
+	service := corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      clusterHeadlessServiceName(redisCluster.Name),
+			Namespace: redisCluster.ObjectMeta.Namespace,
+		},
+		Spec: corev1.ServiceSpec{
+			ClusterIP:                corev1.ClusterIPNone,
+			PublishNotReadyAddresses: true,
+			Ports: []corev1.ServicePort{
+				{
+					Name:       "redis-client-port",
+					Port:       6379,
+					TargetPort: intstr.FromInt(6379),
+				},
+			},
+			Selector: selector,
+		},
+	}
+	if err := ctrl.SetControllerReference(redisCluster, &service, r.Scheme); err != nil {
+		return err
+	}
+	if err := r.Create(context.Background(), &service); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	return nil
+}
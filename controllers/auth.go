@@ -0,0 +1,80 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/rediscli"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+/*
+	The credentials of the operator user on the Redis nodes are read from the secret referenced by
+	spec.auth.secretRef of each RedisCluster, under the 'username' and 'password' keys.
+	The secret is watched, a change triggers a reconcile loop of the clusters that reference it and the
+	credentials are reloaded. Clusters without spec.auth use the REDIS_USERNAME and REDISCLI_AUTH
+	environment variables of the operator.
+*/
+
+// Loads the credentials that are used to connect to the nodes of the RedisCluster
+func loadRedisAuth(c client.Client, redisCluster *dbv1.RedisCluster) (*rediscli.RedisAuth, error) {
+	if redisCluster.Spec.Auth == nil {
+		return rediscli.NewRedisAuthFromEnv(), nil
+	}
+	key := types.NamespacedName{Namespace: redisCluster.Namespace, Name: redisCluster.Spec.Auth.SecretRef.Name}
+	var secret corev1.Secret
+	if err := c.Get(context.Background(), key, &secret); err != nil {
+		return nil, errors.Wrapf(err, "Failed to get the auth secret [%s]", key)
+	}
+	password, exists := secret.Data[corev1.BasicAuthPasswordKey]
+	if !exists {
+		return nil, errors.Errorf("The auth secret [%s] has no '%s' key", key, corev1.BasicAuthPasswordKey)
+	}
+	return &rediscli.RedisAuth{
+		User:     string(secret.Data[corev1.BasicAuthUsernameKey]),
+		Password: string(password),
+	}, nil
+}
+
+// Loads the credentials of the cluster, the RedisCLI of the reconciler is replaced when they change
+func (r *RedisClusterReconciler) reloadRedisAuth(redisCluster *dbv1.RedisCluster) error {
+	auth, err := loadRedisAuth(r.Client, redisCluster)
+	if err != nil {
+		r.recordEvent(corev1.EventTypeWarning, eventAuthLoadFailed, "Failed to load the Redis credentials: %v", err)
+		return err
+	}
+	if reflect.DeepEqual(auth, r.RedisCLI.Auth) {
+		return nil
+	}
+	r.RedisCLI = r.RedisCLI.WithAuth(auth)
+	if redisCluster.Spec.Auth != nil {
+		r.Log.Info(fmt.Sprintf("Loaded the Redis credentials from secret [%s]", redisCluster.Spec.Auth.SecretRef.Name))
+		r.recordEvent(corev1.EventTypeNormal, eventAuthLoaded, "Loaded the Redis credentials from secret [%s]", redisCluster.Spec.Auth.SecretRef.Name)
+	}
+	return nil
+}
+
+// Maps a secret to the RedisClusters that reference it by spec.auth.secretRef or spec.tls.secretRef
+func (r *RedisClusterReconciler) redisClustersForSecret(obj handler.MapObject) []reconcile.Request {
+	var redisClusters dbv1.RedisClusterList
+	if err := r.List(context.Background(), &redisClusters, client.InNamespace(obj.Meta.GetNamespace())); err != nil {
+		r.Log.Error(err, "Failed to list the redis clusters that reference secret "+obj.Meta.GetName())
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, rdc := range redisClusters.Items {
+		referencedByAuth := rdc.Spec.Auth != nil && rdc.Spec.Auth.SecretRef.Name == obj.Meta.GetName()
+		referencedByTLS := rdc.Spec.TLS != nil && rdc.Spec.TLS.SecretRef.Name == obj.Meta.GetName()
+		if referencedByAuth || referencedByTLS {
+			requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: rdc.Namespace, Name: rdc.Name}})
+		}
+	}
+	return requests
+}
@@ -0,0 +1,268 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/view"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+	With spec.autoscaling the operator sets the leader count of the spec by the usage of the leaders, and the
+	scale flows of the leaders carry it out like a leader count set by the user. The reconciler of a healthy cluster
+	reads INFO from every leader: the memory usage is its used_memory in percent of its maxmemory, or of the memory
+	limit of its redis container when maxmemory is not set, and the CPU usage is the used_cpu_sys and used_cpu_user
+	seconds it spent since the previous reconcile loop, in percent of the CPU limit of its redis container or of a
+	single core. The recommended number of leaders keeps the average usage of each signal at its target, like the
+	horizontal pod autoscaler of kubernetes does, and the leaders are scaled by the signal that needs the most of them.
+	To keep the leaders from flapping, an average usage within the tolerance of its target is left alone, the leaders
+	are scaled down one at a time, and no scale follows another scale within the cooldown of its direction.
+	The last evaluation is reported on status.autoscaling, and every scale is recorded as an event.
+*/
+
+// The CPU seconds a leader spent by the time they were sampled
+type cpuSample struct {
+	seconds float64
+	time    time.Time
+}
+
+// The usage of a leader in percent, a signal that could not be read is nil
+type leaderUsage struct {
+	memoryPercent *float64
+	cpuPercent    *float64
+}
+
+// Sets the leader count of the spec by the memory and CPU usage of the leaders of a healthy cluster, when the
+// cluster has spec.autoscaling
+func (r *RedisClusterReconciler) autoscaleLeaders(redisCluster *dbv1.RedisCluster, v *view.RedisClusterView) error {
+	autoscaling := redisCluster.Spec.Autoscaling
+	if autoscaling == nil {
+		redisCluster.Status.Autoscaling = nil
+		r.cpuSamples = nil
+		return nil
+	}
+	if redisCluster.Status.Autoscaling == nil {
+		redisCluster.Status.Autoscaling = &dbv1.AutoscalingStatus{}
+	}
+	status := redisCluster.Status.Autoscaling
+	if r.RedisClusterStateView.ClusterState != view.ClusterOK || redisCluster.Status.Reshard != nil {
+		return nil
+	}
+	isLeader, _, err := r.currentRoles(v)
+	if err != nil {
+		return err
+	}
+	leaders := make([]string, 0, len(isLeader))
+	for name := range isLeader {
+		leaders = append(leaders, name)
+	}
+	sort.Strings(leaders)
+	if len(leaders) != redisCluster.Spec.LeaderCount {
+		r.Log.Info(fmt.Sprintf("The cluster has %d leaders while the spec has %d, the autoscaling waits for the scale", len(leaders), redisCluster.Spec.LeaderCount))
+		return nil
+	}
+
+	var memoryPercents, cpuPercents []float64
+	samples := map[string]cpuSample{}
+	for _, name := range leaders {
+		usage := r.leaderUsage(v.Nodes[name], samples)
+		if usage.memoryPercent != nil {
+			memoryPercents = append(memoryPercents, *usage.memoryPercent)
+		}
+		if usage.cpuPercent != nil {
+			cpuPercents = append(cpuPercents, *usage.cpuPercent)
+		}
+	}
+	r.cpuSamples = samples
+
+	tolerancePercent := 0
+	if autoscaling.TolerancePercent != nil {
+		tolerancePercent = *autoscaling.TolerancePercent
+	}
+	current := redisCluster.Spec.LeaderCount
+	recommended := current
+	var reasons []string
+	status.MemoryPercent, status.CPUPercent = nil, nil
+	if memoryPercent, measured := averagePercent(memoryPercents); measured {
+		status.MemoryPercent = &memoryPercent
+		if autoscaling.TargetMemoryPercent != nil {
+			reasons = append(reasons, fmt.Sprintf("memory %d%% of target %d%%", memoryPercent, *autoscaling.TargetMemoryPercent))
+			recommended = recommendLeaders(current, memoryPercent, *autoscaling.TargetMemoryPercent, tolerancePercent)
+		}
+	}
+	if cpuPercent, measured := averagePercent(cpuPercents); measured {
+		status.CPUPercent = &cpuPercent
+		if autoscaling.TargetCPUPercent != nil {
+			reasons = append(reasons, fmt.Sprintf("CPU %d%% of target %d%%", cpuPercent, *autoscaling.TargetCPUPercent))
+			cpuRecommended := recommendLeaders(current, cpuPercent, *autoscaling.TargetCPUPercent, tolerancePercent)
+			// The leaders are scaled down only when all the signals agree on it
+			if len(reasons) == 1 || cpuRecommended > recommended {
+				recommended = cpuRecommended
+			}
+		}
+	}
+	if len(reasons) == 0 {
+		if current >= autoscaling.MinLeaders && current <= autoscaling.MaxLeaders {
+			status.DesiredLeaders = current
+			status.LastDecision = "No usage of the leaders could be read yet"
+			return nil
+		}
+		reasons = append(reasons, "the leader count is out of the bounds")
+	}
+	desired := clampLeaders(recommended, autoscaling.MinLeaders, autoscaling.MaxLeaders)
+	status.DesiredLeaders = desired
+	reason := strings.Join(reasons, ", ")
+	if desired == current {
+		status.LastDecision = fmt.Sprintf("Keep %d leaders: %s", current, reason)
+		return nil
+	}
+	// Every leader that is removed reshards its slots, one is removed at a time
+	if desired < current {
+		desired = current - 1
+	}
+
+	cooldown := autoscaling.ScaleUpCooldown
+	if desired < current {
+		cooldown = autoscaling.ScaleDownCooldown
+	}
+	if cooldown != nil && status.LastScaleTime != nil {
+		if remaining := cooldown.Duration - time.Since(status.LastScaleTime.Time); remaining > 0 {
+			status.LastDecision = fmt.Sprintf("Scale from %d to %d leaders is on cooldown for %v: %s", current, desired, remaining.Round(time.Second), reason)
+			r.Log.Info(status.LastDecision)
+			return nil
+		}
+	}
+	if r.skipInObserveOnly("autoscale from %d to %d leaders: %s", current, desired, reason) {
+		return nil
+	}
+	r.planReason("Autoscale from %d to %d leaders: %s", current, desired, reason)
+	if err := r.patchLeaderCount(redisCluster, desired); err != nil {
+		status.LastDecision = fmt.Sprintf("Could not scale from %d to %d leaders: %v", current, desired, err)
+		r.recordEvent(corev1.EventTypeWarning, eventAutoscaleFailed, "Could not scale from %d to %d leaders: %v", current, desired, err)
+		return err
+	}
+	status = redisCluster.Status.Autoscaling
+	now := metav1.Now()
+	status.LastScaleTime = &now
+	status.LastDecision = fmt.Sprintf("Scaled from %d to %d leaders: %s", current, desired, reason)
+	r.recordEvent(corev1.EventTypeNormal, eventAutoscaled, "Scaled from %d to %d leaders: %s", current, desired, reason)
+	return nil
+}
+
+// Reads the memory and CPU usage of the leader, the CPU seconds it spent are added to the samples
+func (r *RedisClusterReconciler) leaderUsage(n *view.NodeView, samples map[string]cpuSample) leaderUsage {
+	usage := leaderUsage{}
+	if n == nil {
+		return usage
+	}
+	info, _, err := r.RedisCLI.Info(n.Ip)
+	if err != nil || info == nil {
+		r.Log.Info(fmt.Sprintf("[Warn] Could not read the usage of leader [%s]: %v", n.Name, err))
+		return usage
+	}
+	usedMemory, err := strconv.ParseInt(info.Memory["used_memory"], 10, 64)
+	if err == nil {
+		maxMemory, _ := strconv.ParseInt(info.Memory["maxmemory"], 10, 64)
+		if maxMemory <= 0 {
+			maxMemory = redisMemoryLimit(n.Pod)
+		}
+		if maxMemory > 0 {
+			memoryPercent := float64(usedMemory) * 100 / float64(maxMemory)
+			usage.memoryPercent = &memoryPercent
+		}
+	}
+	systemSeconds, sysErr := strconv.ParseFloat(info.CPU["used_cpu_sys"], 64)
+	userSeconds, userErr := strconv.ParseFloat(info.CPU["used_cpu_user"], 64)
+	if sysErr != nil || userErr != nil {
+		return usage
+	}
+	sample := cpuSample{seconds: systemSeconds + userSeconds, time: time.Now()}
+	samples[n.Name] = sample
+	if previous, exists := r.cpuSamples[n.Name]; exists {
+		if cpuPercent, measured := cpuPercentSince(previous, sample, redisCPULimit(n.Pod)); measured {
+			usage.cpuPercent = &cpuPercent
+		}
+	}
+	return usage
+}
+
+// Returns the CPU seconds spent between the samples in percent of the CPU limit in millicores, or of a single core
+// when the limit is not set
+func cpuPercentSince(previous cpuSample, sample cpuSample, cpuLimit int64) (float64, bool) {
+	// A leader that was restarted since the previous sample starts counting its CPU seconds over
+	if sample.seconds < previous.seconds || !sample.time.After(previous.time) {
+		return 0, false
+	}
+	cores := float64(cpuLimit) / 1000
+	if cores <= 0 {
+		cores = 1
+	}
+	return (sample.seconds - previous.seconds) * 100 / sample.time.Sub(previous.time).Seconds() / cores, true
+}
+
+// Returns the CPU limit of the redis container of the pod in millicores, 0 when it is not set
+func redisCPULimit(pod corev1.Pod) int64 {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == dbv1.RedisContainerName {
+			return container.Resources.Limits.Cpu().MilliValue()
+		}
+	}
+	return 0
+}
+
+func averagePercent(percents []float64) (int, bool) {
+	if len(percents) == 0 {
+		return 0, false
+	}
+	total := float64(0)
+	for _, percent := range percents {
+		total += percent
+	}
+	return int(math.Round(total / float64(len(percents)))), true
+}
+
+// Returns the number of leaders that brings the average usage to the target, or the current number of leaders
+// when the usage is within the tolerance of the target
+func recommendLeaders(current int, usagePercent int, targetPercent int, tolerancePercent int) int {
+	if targetPercent <= 0 {
+		return current
+	}
+	ratio := float64(usagePercent) / float64(targetPercent)
+	if math.Abs(ratio-1)*100 <= float64(tolerancePercent) {
+		return current
+	}
+	return int(math.Ceil(float64(current) * ratio))
+}
+
+func clampLeaders(leaders int, minLeaders int, maxLeaders int) int {
+	if leaders < minLeaders {
+		return minLeaders
+	}
+	if leaders > maxLeaders {
+		return maxLeaders
+	}
+	return leaders
+}
+
+// Patches the leader count of the spec, the status of the RedisCluster is kept as it is for the reconciler to save it
+func (r *RedisClusterReconciler) patchLeaderCount(redisCluster *dbv1.RedisCluster, leaderCount int) error {
+	status := redisCluster.Status.DeepCopy()
+	patch := client.MergeFrom(redisCluster.DeepCopy())
+	previousLeaderCount := redisCluster.Spec.LeaderCount
+	redisCluster.Spec.LeaderCount = leaderCount
+	err := r.Patch(context.Background(), redisCluster, patch)
+	if err != nil {
+		redisCluster.Spec.LeaderCount = previousLeaderCount
+	}
+	status.DeepCopyInto(&redisCluster.Status)
+	return err
+}
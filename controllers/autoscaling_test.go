@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRecommendLeaders(test *testing.T) {
+	// The usage and the target in percent, with a tolerance of 10%, of 4 leaders
+	cases := map[[2]int]int{
+		{80, 80}:  4,
+		{86, 80}:  4,
+		{100, 80}: 5,
+		{160, 80}: 8,
+		{40, 80}:  2,
+		{10, 80}:  1,
+		{50, 0}:   4,
+	}
+	for usage, expected := range cases {
+		if leaders := recommendLeaders(4, usage[0], usage[1], 10); leaders != expected {
+			test.Errorf("Expected %d leaders for usage %d%% of target %d%%, got %d", expected, usage[0], usage[1], leaders)
+		}
+	}
+}
+
+func TestClampLeaders(test *testing.T) {
+	cases := map[int]int{
+		1:  3,
+		3:  3,
+		5:  5,
+		10: 10,
+		12: 10,
+	}
+	for leaders, expected := range cases {
+		if clamped := clampLeaders(leaders, 3, 10); clamped != expected {
+			test.Errorf("Expected %d leaders to be clamped to %d, got %d", leaders, expected, clamped)
+		}
+	}
+}
+
+func TestAveragePercent(test *testing.T) {
+	cases := map[string]struct {
+		percents []float64
+		average  int
+		measured bool
+	}{
+		"no leaders":    {nil, 0, false},
+		"one leader":    {[]float64{42.4}, 42, true},
+		"rounded up":    {[]float64{10, 11}, 11, true},
+		"three leaders": {[]float64{20, 40, 90}, 50, true},
+	}
+	for name, expected := range cases {
+		if average, measured := averagePercent(expected.percents); average != expected.average || measured != expected.measured {
+			test.Errorf("Expected the average of %s to be %d (%v), got %d (%v)", name, expected.average, expected.measured, average, measured)
+		}
+	}
+}
+
+func TestCPUPercentSince(test *testing.T) {
+	start := time.Date(2021, 6, 7, 12, 0, 0, 0, time.UTC)
+	previous := cpuSample{seconds: 100, time: start}
+	cases := map[string]struct {
+		sample   cpuSample
+		cpuLimit int64
+		percent  float64
+		measured bool
+	}{
+		"single core":  {cpuSample{seconds: 105, time: start.Add(10 * time.Second)}, 0, 50, true},
+		"cpu limit":    {cpuSample{seconds: 105, time: start.Add(10 * time.Second)}, 500, 100, true},
+		"two cores":    {cpuSample{seconds: 130, time: start.Add(20 * time.Second)}, 2000, 75, true},
+		"restarted":    {cpuSample{seconds: 3, time: start.Add(10 * time.Second)}, 0, 0, false},
+		"same instant": {cpuSample{seconds: 105, time: start}, 0, 0, false},
+	}
+	for name, expected := range cases {
+		if percent, measured := cpuPercentSince(previous, expected.sample, expected.cpuLimit); percent != expected.percent || measured != expected.measured {
+			test.Errorf("Expected the CPU usage of %s to be %v%% (%v), got %v%% (%v)", name, expected.percent, expected.measured, percent, measured)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/rediscli"
+	"github.com/PayU/redis-operator/controllers/view"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+	The status conditions reflect the decisions taken by the reconcile loop in a form that can be
+	consumed by standard tooling, for example:
+	kubectl wait rdc/<name> --for=condition=Available
+
+	Each handle*State function reports the conditions it is able to observe, the reconcile loop
+	completes them according to the final operator state before saving the status.
+*/
+
+// Reasons reported on the RedisCluster status conditions
+const (
+	reasonInitializing             = "Initializing"
+	reasonClusterCreated           = "ClusterCreated"
+	reasonClusterCreateFailed      = "ClusterCreateFailed"
+	reasonClusterHealthy           = "ClusterHealthy"
+	reasonNonReachableNodes        = "NonReachableNodes"
+	reasonLostNodesDetected        = "LostNodesDetected"
+	reasonHealthCheckFailed        = "HealthCheckFailed"
+	reasonUnhealthyNodes           = "UnhealthyNodes"
+	reasonRecovering               = "Recovering"
+	reasonRollingUpdate            = "RollingUpdate"
+	reasonScaling                  = "Scaling"
+	reasonScaleFailed              = "ScaleFailed"
+	reasonSlotsCovered             = "AllSlotsCovered"
+	reasonSlotsNotCovered          = "SlotsNotCovered"
+	reasonUpgradeRequired          = "UpgradeRequired"
+	reasonUpToDate                 = "UpToDate"
+	reasonACLSynced                = "ACLSynced"
+	reasonACLSyncFailed            = "ACLSyncFailed"
+	reasonObserveOnly              = "ObserveOnly"
+	reasonNoMaintenanceWindows     = "NoMaintenanceWindows"
+	reasonMaintenanceWindowOpen    = "MaintenanceWindowOpen"
+	reasonOutsideMaintenanceWindow = "OutsideMaintenanceWindow"
+	reasonPaused                   = "Paused"
+	reasonZonesBalanced            = "ZonesBalanced"
+	reasonLeadersSkewed            = "LeadersSkewed"
+	reasonFollowersColocated       = "FollowersColocated"
+)
+
+func conditionStatus(status bool) metav1.ConditionStatus {
+	if status {
+		return metav1.ConditionTrue
+	}
+	return metav1.ConditionFalse
+}
+
+func setCondition(redisCluster *dbv1.RedisCluster, conditionType dbv1.RedisClusterConditionType, status bool, reason string, message string) {
+	redisCluster.Status.SetCondition(dbv1.RedisClusterCondition{
+		Type:               conditionType,
+		Status:             conditionStatus(status),
+		ObservedGeneration: redisCluster.Generation,
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+func (r *RedisClusterReconciler) setUnavailable(redisCluster *dbv1.RedisCluster, reason string, message string) {
+	setCondition(redisCluster, dbv1.ConditionAvailable, false, reason, message)
+	setCondition(redisCluster, dbv1.ConditionDegraded, true, reason, message)
+}
+
+func (r *RedisClusterReconciler) setAvailable(redisCluster *dbv1.RedisCluster) {
+	setCondition(redisCluster, dbv1.ConditionAvailable, true, reasonClusterHealthy, "All the cluster nodes are healthy")
+	setCondition(redisCluster, dbv1.ConditionDegraded, false, reasonClusterHealthy, "All the cluster nodes are healthy")
+}
+
+// Checks the slots coverage by sampling the cluster info of the reachable nodes
+func (r *RedisClusterReconciler) updateSlotsCoveredCondition(redisCluster *dbv1.RedisCluster, v *view.RedisClusterView) {
+	allSlots := fmt.Sprint(rediscli.MAX_SLOTS_PER_LEADER)
+	for _, n := range v.Nodes {
+		if n == nil {
+			continue
+		}
+		clusterInfo, _, err := r.RedisCLI.ClusterInfo(n.Ip)
+		if err != nil || clusterInfo == nil {
+			continue
+		}
+		slotsOk := (*clusterInfo)["cluster_slots_ok"]
+		if (*clusterInfo)["cluster_state"] == "ok" && slotsOk == allSlots {
+			setCondition(redisCluster, dbv1.ConditionSlotsCovered, true, reasonSlotsCovered, fmt.Sprintf("All %s slots are covered", allSlots))
+		} else {
+			setCondition(redisCluster, dbv1.ConditionSlotsCovered, false, reasonSlotsNotCovered, fmt.Sprintf("%s/%s slots are covered, reported by node [%s]", slotsOk, allSlots, n.Name))
+		}
+		return
+	}
+}
+
+// Completes the conditions according to the operator state that was decided by the reconcile loop
+func (r *RedisClusterReconciler) updateClusterConditions(redisCluster *dbv1.RedisCluster) {
+	switch RedisClusterState(redisCluster.Status.ClusterState) {
+	case NotExists, Reset:
+		setCondition(redisCluster, dbv1.ConditionProgressing, true, reasonInitializing, "Redis cluster is being created")
+	case Recovering:
+		setCondition(redisCluster, dbv1.ConditionProgressing, true, reasonRecovering, "Non healthy nodes are being recovered")
+	case Updating:
+		setCondition(redisCluster, dbv1.ConditionProgressing, true, reasonRollingUpdate, "Redis cluster is in the middle of a rolling update")
+	case Scale:
+		setCondition(redisCluster, dbv1.ConditionProgressing, true, reasonScaling, "Redis cluster is being scaled")
+	case Ready:
+		if redisCluster.Status.IsConditionTrue(dbv1.ConditionAvailable) {
+			setCondition(redisCluster, dbv1.ConditionProgressing, false, reasonClusterHealthy, "Redis cluster is ready")
+		}
+	}
+	if r.requestUpgrade {
+		setCondition(redisCluster, dbv1.ConditionUpgradePending, true, reasonUpgradeRequired, "Nodes created by a previous version of the operator were detected, upgrade can be triggered by using entry point /upgrade")
+	} else {
+		setCondition(redisCluster, dbv1.ConditionUpgradePending, false, reasonUpToDate, "All the nodes are up to date")
+	}
+	r.disruptionsAllowed(redisCluster)
+	redisCluster.Status.ObservedGeneration = redisCluster.Generation
+}
+
+// Updates the ACLSynced condition of the RedisCluster, retries on conflicts with the updates of the main reconcile loop
+func (r *RedisConfigReconciler) updateACLSyncedCondition(key client.ObjectKey, syncErr error) error {
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		var redisCluster dbv1.RedisCluster
+		if err := r.Get(context.Background(), key, &redisCluster); err != nil {
+			return err
+		}
+		if syncErr != nil {
+			setCondition(&redisCluster, dbv1.ConditionACLSynced, false, reasonACLSyncFailed, syncErr.Error())
+		} else {
+			setCondition(&redisCluster, dbv1.ConditionACLSynced, true, reasonACLSynced, "ACL config is loaded on all the cluster nodes")
+		}
+		return r.Status().Update(context.Background(), &redisCluster)
+	})
+}
@@ -19,6 +19,29 @@ import (
 # The following indicator serves as a 'feature-bit' that tells the operator to hide those sensitive entry points in order to avoid harm on sensitive environment, naturally it is set to be 'false' (Recommended).
 # ExposeSensitiveEntryPoints
 
+# The operator runs the redis commands by starting a redis-cli process per command, the following indicator tells the operator
+# to run them over the redis protocol by a native go client instead, with pooled connections and without the redis-cli binary.
+# The '--cluster' subcommands (create, add-node, del-node, reshard, rebalance, fix, check) are performed by the operator itself in that case.
+# The indicator is read once on the operator startup.
+# UseNativeRedisClient
+
+# The following indicator keeps the reconcile loops running without letting them change the clusters: the cluster view is built,
+# the health is checked and the status, metrics and events are updated, while the actions the operator would have taken are only logged
+# as "Would have done". It is used when existing clusters are onboarded and during incidents, a single cluster is observed only
+# by the 'db.payu.com/observe-only: "true"' annotation.
+# ObserveOnly
+
+# The following indicator lets the operator spread the leaders of a healthy cluster evenly over the availability zones of its nodes,
+# by graceful 'cluster failover' commands run on followers of the zones that hold less leaders, one per reconcile loop.
+# A follower that shares the zone of its leader while another zone holds no node of its shard is recreated, so it is scheduled to that zone.
+# Both run within the maintenance windows of the cluster, the spread is reported on the cluster status regardless of the indicator.
+# BalanceZones
+
+# The following indicator lets the operator restore the designated leader of a shard (redis-node-N) after one of its followers was promoted
+# by a failover, by a graceful 'cluster failover' command run on it once it is ready, NodeOK and synced with the current leader, so the roles
+# of the nodes match their names again. One shard is switched back per reconcile loop, within the maintenance windows of the cluster.
+# SwitchbackLeaders
+
 # The thresholds value sets definite bounderies for the operator to perform during running concurrent operations
 # and during decision making based on given stated values
 
@@ -35,6 +58,20 @@ import (
 # this value set the maximum number of nodes to be deleted at once per update loop
 # MaxToleratedPodsUpdateAtOnce
 
+# The slots of a leader are moved by the operator slot by slot, the keys of a slot are moved by MIGRATE commands of
+# this number of keys each
+# ReshardMigratePipeline
+
+# The maximum number of keys moved per second during a reshard, to keep the latency of the clients low, 0 sets no limit
+# ReshardKeysPerSecond
+
+# The memory analysis of the cluster sizes this number of keys of every slot by MEMORY USAGE, and estimates the memory
+# of the slot by their average size
+# MemorySampledKeysPerSlot
+
+# The memory rebalance plan moves slots until the used memory of every leader is within this percentage of the mean
+# MemoryRebalanceTolerancePercent
+
 */
 
 /*
@@ -118,12 +155,20 @@ type RedisOperatorConfig struct {
 
 type OperatorSetters struct {
 	ExposeSensitiveEntryPoints bool `yaml:"ExposeSensitiveEntryPoints"`
+	UseNativeRedisClient       bool `yaml:"UseNativeRedisClient"`
+	ObserveOnly                bool `yaml:"ObserveOnly"`
+	BalanceZones               bool `yaml:"BalanceZones"`
+	SwitchbackLeaders          bool `yaml:"SwitchbackLeaders"`
 }
 
 type OperatorConfigThresholds struct {
-	SyncMatchThreshold            int `yaml:"SyncMatchThreshold"`
-	MaxToleratedPodsRecoverAtOnce int `yaml:"MaxToleratedPodsRecoverAtOnce"`
-	MaxToleratedPodsUpdateAtOnce  int `yaml:"MaxToleratedPodsUpdateAtOnce"`
+	SyncMatchThreshold              int `yaml:"SyncMatchThreshold"`
+	MaxToleratedPodsRecoverAtOnce   int `yaml:"MaxToleratedPodsRecoverAtOnce"`
+	MaxToleratedPodsUpdateAtOnce    int `yaml:"MaxToleratedPodsUpdateAtOnce"`
+	ReshardMigratePipeline          int `yaml:"ReshardMigratePipeline"`
+	ReshardKeysPerSecond            int `yaml:"ReshardKeysPerSecond"`
+	MemorySampledKeysPerSlot        int `yaml:"MemorySampledKeysPerSlot"`
+	MemoryRebalanceTolerancePercent int `yaml:"MemoryRebalanceTolerancePercent"`
 }
 
 type OperatorConfigTimes struct {
@@ -185,11 +230,19 @@ func DefaultRedisOperatorConfig(logger logr.Logger) *RedisOperatorConfig {
 		Config: OperatorConfig{
 			Setters: OperatorSetters{
 				ExposeSensitiveEntryPoints: false,
+				UseNativeRedisClient:       false,
+				ObserveOnly:                false,
+				BalanceZones:               false,
+				SwitchbackLeaders:          false,
 			},
 			Thresholds: OperatorConfigThresholds{
-				SyncMatchThreshold:            90,
-				MaxToleratedPodsRecoverAtOnce: 15,
-				MaxToleratedPodsUpdateAtOnce:  5,
+				SyncMatchThreshold:              90,
+				MaxToleratedPodsRecoverAtOnce:   15,
+				MaxToleratedPodsUpdateAtOnce:    5,
+				ReshardMigratePipeline:          10,
+				ReshardKeysPerSecond:            0,
+				MemorySampledKeysPerSlot:        5,
+				MemoryRebalanceTolerancePercent: 5,
 			},
 			Times: OperatorConfigTimes{
 				SyncCheckInterval:                            5 * 1000 * time.Millisecond,
@@ -10,10 +10,12 @@ import (
 	"time"
 
 	rediscli "github.com/PayU/redis-operator/controllers/rediscli"
+	view "github.com/PayU/redis-operator/controllers/view"
 	"github.com/go-logr/logr"
 	"github.com/pkg/errors"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -46,6 +48,7 @@ type RedisConfigReconciler struct {
 	K8sManager *K8sManager
 	RedisCLI   *rediscli.RedisCLI
 	Config     *OperatorConfig
+	Recorder   record.EventRecorder
 }
 
 //+kubebuilder:rbac:groups=core,resources=configmaps,verbs=get;list;watch;create;update;patch;delete
@@ -60,13 +63,12 @@ const ACLFileLoadDuration time.Duration = time.Millisecond * 500
 const redisConfigLabelKey string = "redis-cluster"
 const handleACLConfigErrorMessage = "Failed to handle ACL configuration"
 const operatorConfigLabelKey string = "redis-operator"
-const RedisClusterStateMapName string = "redis-cluster-state-map"
 
-func (r *RedisConfigReconciler) syncConfig(latestConfigHash string, redisPods ...corev1.Pod) error {
+func (r *RedisConfigReconciler) syncConfig(redisCLI *rediscli.RedisCLI, latestConfigHash string, redisPods ...corev1.Pod) error {
 	time.Sleep(ACLFilePropagationDuration)
 
 	for _, pod := range redisPods {
-		msg, err := r.RedisCLI.ACLLoad(pod.Status.PodIP)
+		msg, err := redisCLI.ACLLoad(view.NodeAddress(pod))
 		if err != nil {
 			r.Log.Info(fmt.Sprintf("Failed to load ACL file: %s | %+v", msg, err))
 			return err
@@ -74,7 +76,7 @@ func (r *RedisConfigReconciler) syncConfig(latestConfigHash string, redisPods ..
 
 		time.Sleep(ACLFileLoadDuration)
 
-		loadedConfig, _, err := r.RedisCLI.ACLList(pod.Status.PodIP)
+		loadedConfig, _, err := redisCLI.ACLList(view.NodeAddress(pod))
 		if err != nil {
 			r.Log.Error(err, fmt.Sprintf("Failed to list new ACL config from %s(%s)", pod.Name, pod.Status.PodIP))
 			return err
@@ -102,8 +104,8 @@ func (r *RedisConfigReconciler) updateACLHashStatus(status string, redisPods ...
 }
 
 // Retrieves the ACL config from a Redis node and returns its SHA256 hash
-func (r *RedisConfigReconciler) getACLConfigHash(pod *corev1.Pod) (string, error) {
-	acl, _, err := r.RedisCLI.ACLList(pod.Status.PodIP)
+func (r *RedisConfigReconciler) getACLConfigHash(redisCLI *rediscli.RedisCLI, pod *corev1.Pod) (string, error) {
+	acl, _, err := redisCLI.ACLList(view.NodeAddress(*pod))
 	if err != nil {
 		r.Log.Error(err, fmt.Sprintf("Failed to list previous ACL config from %s(%s) ", pod.Name, pod.Status.PodIP))
 		return "", err
@@ -125,8 +127,24 @@ func (r *RedisConfigReconciler) handleACLConfig(configMap *corev1.ConfigMap) err
 		return err
 	}
 
+	if isObserveOnly(r.Config, &rdc) {
+		r.Log.Info(fmt.Sprintf("[ObserveOnly] Would have synced ACL config map [%s] on Redis cluster [%s/%s]", configMap.Name, ns, rdcName))
+		return nil
+	}
+
+	// The nodes of each cluster are accessed with the credentials and the certificates of the cluster
+	auth, err := loadRedisAuth(r.Client, &rdc)
+	if err != nil {
+		return err
+	}
+	tls, err := loadRedisTLS(r.Client, &rdc)
+	if err != nil {
+		return err
+	}
+	redisCLI := r.RedisCLI.WithAuth(auth).WithTLS(tls)
+
 	rdcPods := corev1.PodList{}
-	err := r.List(context.Background(), &rdcPods,
+	err = r.List(context.Background(), &rdcPods,
 		client.InNamespace(configMap.Namespace),
 		client.MatchingLabels{"redis-cluster": rdc.Name})
 	if err != nil {
@@ -146,12 +164,12 @@ func (r *RedisConfigReconciler) handleACLConfig(configMap *corev1.ConfigMap) err
 	for i := range rdcPods.Items {
 		go func(failSignal *bool, pod *corev1.Pod) {
 			defer wg.Done()
-			if _, e := r.RedisCLI.Ping(pod.Status.PodIP); e != nil {
+			if _, e := redisCLI.Ping(view.NodeAddress(*pod)); e != nil {
 				r.Log.Info(fmt.Sprintf("[Warn] ACL config sync is not ready yet for pod: [%v]", pod.Name))
 				//*failSignal = true
 				return
 			}
-			redisNodeConfigHash, err := r.getACLConfigHash(pod)
+			redisNodeConfigHash, err := r.getACLConfigHash(redisCLI, pod)
 			if err != nil {
 				r.Log.Error(err, "Failed to get the config for %s(%s)", pod.Name, pod.Status.PodIP)
 				*failSignal = true
@@ -171,7 +189,7 @@ func (r *RedisConfigReconciler) handleACLConfig(configMap *corev1.ConfigMap) err
 						*failSignal = true
 						return
 					}
-					if err := r.syncConfig(configMapACLHash, *pod); err != nil {
+					if err := r.syncConfig(redisCLI, configMapACLHash, *pod); err != nil {
 						r.Log.Error(err, handleACLConfigErrorMessage)
 						*failSignal = true
 						return
@@ -185,7 +203,7 @@ func (r *RedisConfigReconciler) handleACLConfig(configMap *corev1.ConfigMap) err
 						*failSignal = true
 						return
 					}
-					if err := r.syncConfig(configMapACLHash, *pod); err != nil {
+					if err := r.syncConfig(redisCLI, configMapACLHash, *pod); err != nil {
 						*failSignal = true
 						r.Log.Error(err, handleACLConfigErrorMessage)
 						return
@@ -208,6 +226,17 @@ func (r *RedisConfigReconciler) handleACLConfig(configMap *corev1.ConfigMap) err
 		handleFail = errors.Errorf("Failed to sync all ACL configurations")
 	}
 
+	if err := r.updateACLSyncedCondition(client.ObjectKey{Namespace: ns, Name: rdc.Name}, handleFail); err != nil {
+		r.Log.Error(err, "Failed to update the ACLSynced condition of the Redis cluster")
+	}
+	if r.Recorder != nil {
+		if handleFail != nil {
+			r.Recorder.Eventf(&rdc, corev1.EventTypeWarning, eventACLSyncFailed, "Failed to sync ACL config map [%s]: %v", configMap.Name, handleFail)
+		} else {
+			r.Recorder.Eventf(&rdc, corev1.EventTypeNormal, eventACLSynced, "ACL config map [%s] is loaded on all the cluster nodes", configMap.Name)
+		}
+	}
+
 	return handleFail
 }
 
@@ -239,8 +268,8 @@ func (r *RedisConfigReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error)
 	var configMap corev1.ConfigMap
 
 	if err := r.Get(context.Background(), req.NamespacedName, &configMap); err != nil {
-		if strings.Contains(err.Error(), RedisClusterStateMapName) {
-			r.Log.Info("[Warn] Failed to fetch config map [" + RedisClusterStateMapName + "]")
+		if strings.HasSuffix(req.Name, clusterStateMapName("")) {
+			r.Log.Info("[Warn] Failed to fetch config map [" + req.Name + "]")
 		} else {
 			r.Log.Error(err, "Failed to fetch configmap")
 		}
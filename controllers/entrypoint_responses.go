@@ -0,0 +1,79 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+
+	"github.com/PayU/redis-operator/controllers/view"
+	"github.com/labstack/echo/v4"
+)
+
+/*
+	The entry points answer with JSON bodies of the response types below, their fields are kept stable for the
+	tools that parse them. Failures are answered by an ErrorResponse with the status:
+	404 when the cluster is not managed by the operator or the job is not found, 403 when a sensitive entry point
+	is not exposed, 409 when another job of the cluster is running and 500 when the cluster could not be read.
+	The API is described by the OpenAPI document served at /openapi.json.
+*/
+
+var errSensitiveEntryPoint = echo.NewHTTPError(http.StatusForbidden,
+	"Sensitive operation - Not allowed, the config param 'ExposeSensitiveEntryPoints' need to be set to 'true'")
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+type MessageResponse struct {
+	Message string `json:"message"`
+}
+
+type ClusterStateResponse struct {
+	Namespace     string `json:"namespace"`
+	Cluster       string `json:"cluster"`
+	OperatorState string `json:"operatorState"`
+	ClusterState  string `json:"clusterState"`
+}
+
+type ClusterInfoResponse struct {
+	Namespace string     `json:"namespace"`
+	Cluster   string     `json:"cluster"`
+	Nodes     []NodeInfo `json:"nodes"`
+}
+
+type NodeInfo struct {
+	Name       string `json:"name"`
+	ID         string `json:"id"`
+	IP         string `json:"ip"`
+	LeaderName string `json:"leaderName"`
+	IsLeader   bool   `json:"isLeader"`
+}
+
+// Answers by an ErrorResponse, with the status of an *echo.HTTPError or 500 for other errors
+func respondError(c echo.Context, err error) error {
+	status := http.StatusInternalServerError
+	message := err.Error()
+	if httpErr, ok := err.(*echo.HTTPError); ok {
+		status = httpErr.Code
+		message = fmt.Sprint(httpErr.Message)
+	}
+	return c.JSON(status, ErrorResponse{Error: message})
+}
+
+func respondMessage(c echo.Context, message string) error {
+	return c.JSON(http.StatusOK, MessageResponse{Message: message})
+}
+
+func newClusterInfoResponse(namespace string, name string, v *view.RedisClusterView) ClusterInfoResponse {
+	nodes := make([]NodeInfo, 0, len(v.Nodes))
+	for _, n := range v.Nodes {
+		if n == nil {
+			continue
+		}
+		nodes = append(nodes, NodeInfo{Name: n.Name, ID: n.Id, IP: n.Ip, LeaderName: n.LeaderName, IsLeader: n.IsLeader})
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	return ClusterInfoResponse{Namespace: namespace, Cluster: name, Nodes: nodes}
+}
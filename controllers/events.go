@@ -0,0 +1,85 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/*
+	Events are recorded on the RedisCluster object for every state transition and every destructive action
+	the operator performs, the timeline can be viewed by:
+	kubectl describe rdc/<name>
+*/
+
+// Reasons of the events recorded on the RedisCluster object
+const (
+	eventStateChanged       = "StateChanged"
+	eventReconcileFailed    = "ReconcileFailed"
+	eventFailedOver         = "FailedOver"
+	eventFailoverFailed     = "FailoverFailed"
+	eventForgotLostNode     = "ForgotLostNode"
+	eventForgetFailed       = "ForgetFailed"
+	eventDeletedPod         = "DeletedPod"
+	eventDeletePodFailed    = "DeletePodFailed"
+	eventResharded          = "Resharded"
+	eventReshardFailed      = "ReshardFailed"
+	eventACLSynced          = "ACLSynced"
+	eventACLSyncFailed      = "ACLSyncFailed"
+	eventStateViewMigrated  = "StateViewMigrated"
+	eventAuthLoaded         = "AuthLoaded"
+	eventAuthLoadFailed     = "AuthLoadFailed"
+	eventTLSLoaded          = "TLSLoaded"
+	eventTLSLoadFailed      = "TLSLoadFailed"
+	eventEntryPointCalled   = "EntryPointCalled"
+	eventEntryPointDenied   = "EntryPointDenied"
+	eventObserveOnly        = "ObserveOnly"
+	eventObserveOnlyOff     = "ObserveOnlyOff"
+	eventWouldHaveActed     = "WouldHaveActed"
+	eventDisruptionDeferred = "DisruptionDeferred"
+	eventSnapshotSaved      = "SnapshotSaved"
+	eventSnapshotSkipped    = "SnapshotSkipped"
+	eventCleanedUp          = "CleanedUp"
+	eventCleanupFailed      = "CleanupFailed"
+	eventDeletedVolume      = "DeletedVolume"
+	eventDeleteVolumeFailed = "DeleteVolumeFailed"
+	eventVolumesKept        = "VolumesKept"
+	eventNodesRestored      = "NodesRestored"
+	eventZonesBalanced      = "ZonesBalanced"
+	eventFollowerRelocated  = "FollowerRelocated"
+	eventLeaderSwitchedBack = "LeaderSwitchedBack"
+	eventAutoscaled         = "Autoscaled"
+	eventAutoscaleFailed    = "AutoscaleFailed"
+)
+
+// Records an event on the RedisCluster handled by the reconciler, the event is dropped if no recorder is set
+// or no RedisCluster was handled yet
+func (r *RedisClusterReconciler) recordEvent(eventType string, reason string, messageFmt string, args ...interface{}) {
+	if r.Recorder == nil || r.eventObject == nil {
+		return
+	}
+	r.Recorder.Eventf(r.eventObject, eventType, reason, messageFmt, args...)
+}
+
+// Records a call of the HTTP API on the RedisCluster it addresses, calls on clusters that are not managed are only audited
+func (r *RedisClusterReconciler) RecordEntryPointCall(key types.NamespacedName, allowed bool, messageFmt string, args ...interface{}) {
+	cr, managed := r.lookupClusterReconciler(key)
+	if !managed {
+		return
+	}
+	if allowed {
+		cr.recordEvent(corev1.EventTypeNormal, eventEntryPointCalled, messageFmt, args...)
+	} else {
+		cr.recordEvent(corev1.EventTypeWarning, eventEntryPointDenied, messageFmt, args...)
+	}
+}
+
+func (r *RedisClusterReconciler) recordStateTransition(from RedisClusterState, to RedisClusterState) {
+	if from == to {
+		return
+	}
+	eventType := corev1.EventTypeNormal
+	if to == Recovering || to == Reset {
+		eventType = corev1.EventTypeWarning
+	}
+	r.recordEvent(eventType, eventStateChanged, "Operator state changed from [%s] to [%s]", from, to)
+}
@@ -0,0 +1,185 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	view "github.com/PayU/redis-operator/controllers/view"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+/*
+	Every RedisCluster carries the 'db.payu.com/cleanup' finalizer, which holds its deletion until the operator
+	removed what the cluster owns: the pods, the services, the config map that kept the state view before it moved to
+	the status, the background jobs of the entry points, the metrics and the in-memory state of its reconciler.
+	A RedisCluster that is created later with the same name starts from a clean state.
+	With spec.deletionPolicy: Snapshot, every leader saves a final RDB snapshot before the pods are removed, the
+	deletion is retried until all the leaders saved it, and the volume claims of spec.persistence are released
+	from the cluster and kept. A node that can not be reached or can not save the snapshot holds the deletion for
+	a few cleanups only, then it is skipped and a SnapshotSkipped event names it. With Delete, the volume claims are
+	deleted. The finalizer is not added in observe-only mode, and a cluster that is deleted in this mode is released
+	without cleanup, its pods and service are collected by their owner reference.
+*/
+
+// +kubebuilder:rbac:groups=db.payu.com,resources=redisclusters/finalizers,verbs=update
+
+const redisClusterFinalizer = "db.payu.com/cleanup"
+
+// The cleanups of a Snapshot deletion that are retried while some of the nodes can not save the final snapshot
+const maxFinalSnapshotAttempts = 3
+
+// Adds the finalizer to the RedisCluster, the object is updated in place
+func (r *RedisClusterReconciler) ensureFinalizer(redisCluster *dbv1.RedisCluster) error {
+	if controllerutil.ContainsFinalizer(redisCluster, redisClusterFinalizer) || r.skipInObserveOnly("add finalizer [%s]", redisClusterFinalizer) {
+		return nil
+	}
+	controllerutil.AddFinalizer(redisCluster, redisClusterFinalizer)
+	return r.Update(context.Background(), redisCluster)
+}
+
+// Cleans up what the deleted RedisCluster owns, then removes its finalizer and releases its reconciler
+func (r *RedisClusterReconciler) finalizeRedisCluster(key types.NamespacedName, redisCluster *dbv1.RedisCluster) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(redisCluster, redisClusterFinalizer) {
+		r.removeClusterReconciler(key)
+		return ctrl.Result{}, nil
+	}
+	cr := r.getClusterReconciler(key)
+	cr.clusterMutex.Lock()
+	defer cr.clusterMutex.Unlock()
+	cr.eventObject = redisCluster
+	cr.reloadObserveOnly(redisCluster)
+	if !cr.RedisCLI.ObserveOnly {
+		if err := cr.cleanupRedisCluster(redisCluster); err != nil {
+			cr.Log.Error(err, "Could not clean up the deleted redis cluster")
+			cr.recordEvent(corev1.EventTypeWarning, eventCleanupFailed, "Could not clean up the deleted cluster: %v", err)
+			return ctrl.Result{Requeue: true, RequeueAfter: 20 * time.Second}, nil
+		}
+	}
+	controllerutil.RemoveFinalizer(redisCluster, redisClusterFinalizer)
+	if err := r.Update(context.Background(), redisCluster); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	r.jobRegistry().release(key)
+	r.removeClusterReconciler(key)
+	r.Log.Info(fmt.Sprintf("RedisCluster [%s] was cleaned up, releasing its reconciler", key))
+	return ctrl.Result{}, nil
+}
+
+func (r *RedisClusterReconciler) cleanupRedisCluster(redisCluster *dbv1.RedisCluster) error {
+	if err := r.reloadRedisAuth(redisCluster); err != nil {
+		return err
+	}
+	if err := r.reloadRedisTLS(redisCluster); err != nil {
+		return err
+	}
+	if redisCluster.Spec.DeletionPolicy == dbv1.DeletionPolicySnapshot {
+		if err := r.saveFinalSnapshot(redisCluster); err != nil {
+			return err
+		}
+		if err := r.keepNodeVolumes(redisCluster); err != nil {
+			return err
+		}
+	} else {
+		r.Log.Info("Deleting the pods of the deleted cluster...")
+		if err := r.deleteAllRedisClusterPods(redisCluster); err != nil {
+			return err
+		}
+		if err := r.deleteAllNodeVolumes(redisCluster); err != nil {
+			return err
+		}
+	}
+	for _, serviceName := range []string{clusterServiceName(redisCluster.Name), clusterHeadlessServiceName(redisCluster.Name)} {
+		service := corev1.Service{}
+		service.Name, service.Namespace = serviceName, redisCluster.Namespace
+		if err := r.Delete(context.Background(), &service); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+	}
+	if err := r.deleteClusterStateView(redisCluster); err != nil {
+		return err
+	}
+	r.recordEvent(corev1.EventTypeNormal, eventCleanedUp, "The pods and the services of the deleted cluster were removed")
+	return nil
+}
+
+// Releases the volume claims from the cluster and deletes the pods without them, the snapshots stay on the claims
+func (r *RedisClusterReconciler) keepNodeVolumes(redisCluster *dbv1.RedisCluster) error {
+	kept, err := r.orphanNodeVolumes(redisCluster)
+	if err != nil {
+		return err
+	}
+	if kept > 0 {
+		r.recordEvent(corev1.EventTypeNormal, eventVolumesKept, "%d volume claims of the deleted cluster are kept", kept)
+	}
+	r.Log.Info("Deleting the pods of the deleted cluster...")
+	pods, err := r.getRedisClusterPods(redisCluster)
+	if err != nil {
+		return err
+	}
+	for _, pod := range pods {
+		if err := r.deletePodKeepVolume(pod); err != nil {
+			return err
+		}
+	}
+	r.waitForPodDelete(pods...)
+	return nil
+}
+
+// Saves an RDB snapshot on every leader of the cluster. A node that could not be reached or could not save it fails
+// the cleanup, until the cleanups failed maxFinalSnapshotAttempts times and the node is skipped. A pod that has no IP
+// yet may be the leader of its shard, it fails the cleanup as well unless another pod of the shard saved the snapshot.
+func (r *RedisClusterReconciler) saveFinalSnapshot(redisCluster *dbv1.RedisCluster) error {
+	pods, err := r.getRedisClusterPods(redisCluster)
+	if err != nil {
+		return err
+	}
+	saved := 0
+	savedShards := map[string]bool{}
+	var pending []corev1.Pod
+	var failed []string
+	var lastErr error
+	for _, pod := range pods {
+		if pod.Status.PodIP == "" {
+			pending = append(pending, pod)
+			continue
+		}
+		isMaster, err := r.checkIfMaster(view.NodeAddress(pod))
+		if err == nil && !isMaster {
+			continue
+		}
+		if err == nil {
+			r.Log.Info(fmt.Sprintf("Saving the final snapshot of [%s]", pod.Name))
+			_, err = r.RedisCLI.Save(view.NodeAddress(pod))
+		}
+		if err != nil {
+			failed = append(failed, pod.Name)
+			lastErr = err
+			continue
+		}
+		savedShards[pod.Labels["leader-name"]] = true
+		saved++
+	}
+	for _, pod := range pending {
+		if !savedShards[pod.Labels["leader-name"]] {
+			failed = append(failed, pod.Name)
+			lastErr = errors.Errorf("pod [%s] has no IP", pod.Name)
+		}
+	}
+	if len(failed) > 0 {
+		r.failedSnapshotAttempts++
+		if r.failedSnapshotAttempts < maxFinalSnapshotAttempts {
+			return errors.Wrapf(lastErr, "could not save the final snapshot of %v (attempt %d of %d)", failed, r.failedSnapshotAttempts, maxFinalSnapshotAttempts)
+		}
+		r.Log.Info(fmt.Sprintf("[Warn] The final snapshot of %v is skipped after %d attempts: %v", failed, r.failedSnapshotAttempts, lastErr))
+		r.recordEvent(corev1.EventTypeWarning, eventSnapshotSkipped, "The final snapshot of %v is skipped after %d attempts: %v", failed, r.failedSnapshotAttempts, lastErr)
+	}
+	r.recordEvent(corev1.EventTypeNormal, eventSnapshotSaved, "A final snapshot was saved by %d leaders", saved)
+	return nil
+}
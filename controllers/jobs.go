@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/view"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/*
+	The long running entry points (rebalance, memoryRebalance, fix, test, testData and
+	populateMockData) start a job and return
+	its ID straight away with 202 Accepted. The job runs in the background and is followed by
+	GET /cluster/<namespace>/<name>/jobs/<id>, which reports its state, its progress and its result, and
+	is cancelled by DELETE on the same path. Cancellation takes effect between the steps of the job and kills the
+	redis-cli command that is running.
+	A cluster runs one job at a time, a request for another job while one is running is answered by
+	409 Conflict with the active job. The jobs that change the cluster (rebalance, memoryRebalance and fix) run
+	under the lock of the cluster, between its reconcile loops, on the RedisCluster as it is when they start, and
+	patch only the fields of the status they own. The last finished jobs are kept in memory and are lost on restart.
+*/
+
+type JobState string
+
+const (
+	JobRunning   JobState = "Running"
+	JobSucceeded JobState = "Succeeded"
+	JobFailed    JobState = "Failed"
+	JobCancelled JobState = "Cancelled"
+)
+
+const maxFinishedJobs = 100
+
+type JobProgress struct {
+	Done    int64  `json:"done"`
+	Total   int64  `json:"total"`
+	Message string `json:"message,omitempty"`
+}
+
+type Job struct {
+	ID         string      `json:"id"`
+	Kind       string      `json:"kind"`
+	Namespace  string      `json:"namespace"`
+	Cluster    string      `json:"cluster"`
+	State      JobState    `json:"state"`
+	Progress   JobProgress `json:"progress"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	CreatedAt  time.Time   `json:"createdAt"`
+	FinishedAt *time.Time  `json:"finishedAt,omitempty"`
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	mutex  sync.Mutex
+}
+
+// The result of the jobs that run a redis-cli cluster command
+type commandJobResult struct {
+	Node      string `json:"node"`
+	Succeeded bool   `json:"succeeded"`
+	Output    string `json:"output"`
+}
+
+type testJobResult struct {
+	Report string `json:"report"`
+}
+
+type populateJobResult struct {
+	Attempted int `json:"attempted"`
+	Written   int `json:"written"`
+}
+
+// The work of a job, it reports its progress on the job and returns its result
+type jobFunc func(job *Job) (interface{}, error)
+
+// The work of a job that changes the cluster, it runs on a reconciler of the cluster bound to the job
+type clusterJobFunc func(jr *RedisClusterReconciler, redisCluster *dbv1.RedisCluster) (interface{}, error)
+
+type jobRegistry struct {
+	mutex sync.Mutex
+	jobs  map[string]*Job
+}
+
+func (j *Job) Context() context.Context {
+	return j.ctx
+}
+
+func (j *Job) Cancelled() bool {
+	return j.ctx.Err() != nil
+}
+
+func (j *Job) SetProgress(done int64, total int64, message string) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	j.Progress = JobProgress{Done: done, Total: total, Message: message}
+}
+
+// Returns a copy of the job that is safe to serialize while the job runs
+func (j *Job) snapshot() Job {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return Job{
+		ID:         j.ID,
+		Kind:       j.Kind,
+		Namespace:  j.Namespace,
+		Cluster:    j.Cluster,
+		State:      j.State,
+		Progress:   j.Progress,
+		Result:     j.Result,
+		Error:      j.Error,
+		CreatedAt:  j.CreatedAt,
+		FinishedAt: j.FinishedAt,
+	}
+}
+
+func (j *Job) finish(result interface{}, err error) {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	now := time.Now()
+	j.FinishedAt = &now
+	j.Result = result
+	switch {
+	case j.ctx.Err() != nil:
+		j.State = JobCancelled
+	case err != nil:
+		j.State = JobFailed
+		j.Error = err.Error()
+	default:
+		j.State = JobSucceeded
+	}
+	j.cancel()
+}
+
+func (j *Job) active() bool {
+	j.mutex.Lock()
+	defer j.mutex.Unlock()
+	return j.State == JobRunning
+}
+
+func newJobID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Starts the job unless another job of the cluster is active, in which case the active job is returned
+func (reg *jobRegistry) start(key types.NamespacedName, kind string, work jobFunc) (*Job, bool) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+	for _, job := range reg.jobs {
+		if job.Namespace == key.Namespace && job.Cluster == key.Name && job.active() {
+			return job, false
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &Job{
+		ID:        newJobID(),
+		Kind:      kind,
+		Namespace: key.Namespace,
+		Cluster:   key.Name,
+		State:     JobRunning,
+		CreatedAt: time.Now(),
+		ctx:       ctx,
+		cancel:    cancel,
+	}
+	reg.jobs[job.ID] = job
+	reg.prune()
+	go func() {
+		result, err := work(job)
+		job.finish(result, err)
+	}()
+	return job, true
+}
+
+// Removes the oldest finished jobs above the retention limit
+func (reg *jobRegistry) prune() {
+	var finished []*Job
+	for _, job := range reg.jobs {
+		if !job.active() {
+			finished = append(finished, job)
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(i, j int) bool {
+		return finished[i].CreatedAt.Before(finished[j].CreatedAt)
+	})
+	for _, job := range finished[:len(finished)-maxFinishedJobs] {
+		delete(reg.jobs, job.ID)
+	}
+}
+
+func (reg *jobRegistry) get(key types.NamespacedName, id string) (*Job, bool) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+	job, exists := reg.jobs[id]
+	if !exists || job.Namespace != key.Namespace || job.Cluster != key.Name {
+		return nil, false
+	}
+	return job, true
+}
+
+// Cancels the active jobs of the cluster and forgets all its jobs
+func (reg *jobRegistry) release(key types.NamespacedName) {
+	reg.mutex.Lock()
+	defer reg.mutex.Unlock()
+	for id, job := range reg.jobs {
+		if job.Namespace == key.Namespace && job.Cluster == key.Name {
+			job.cancel()
+			delete(reg.jobs, id)
+		}
+	}
+}
+
+func (r *RedisClusterReconciler) jobRegistry() *jobRegistry {
+	r.clusterReconcilersMutex.Lock()
+	defer r.clusterReconcilersMutex.Unlock()
+	if r.jobs == nil {
+		r.jobs = &jobRegistry{jobs: map[string]*Job{}}
+	}
+	return r.jobs
+}
+
+// Runs the work of the job under the lock of the cluster, on the latest RedisCluster and its state view
+func (r *RedisClusterReconciler) runClusterJob(job *Job, work clusterJobFunc) (interface{}, error) {
+	r.clusterMutex.Lock()
+	defer r.clusterMutex.Unlock()
+	if job.Cancelled() {
+		return nil, nil
+	}
+	var redisCluster dbv1.RedisCluster
+	if err := r.Get(job.Context(), r.key, &redisCluster); err != nil {
+		return nil, errors.Wrap(err, "Could not fetch redis cluster resource")
+	}
+	if err := r.setClusterStateView(&redisCluster); err != nil {
+		return nil, errors.Wrap(err, "Could not load the cluster state view")
+	}
+	jr := r.jobReconciler(job, &redisCluster)
+	jr.State = r.State
+	jr.RedisClusterStateView = r.RedisClusterStateView
+	return work(jr, &redisCluster)
+}
+
+// Returns a reconciler of the cluster whose redis-cli commands are killed when the job is cancelled, it has no state
+// view of its own, the jobs that run under the lock of the cluster share the state view of the cluster
+func (r *RedisClusterReconciler) jobReconciler(job *Job, redisCluster *dbv1.RedisCluster) *RedisClusterReconciler {
+	return &RedisClusterReconciler{
+		Client:      r.Client,
+		Log:         r.Log.WithValues("job", job.ID),
+		Scheme:      r.Scheme,
+		RedisCLI:    r.RedisCLI.WithContext(job.Context()),
+		Config:      r.Config,
+		Recorder:    r.Recorder,
+		key:         r.key,
+		eventObject: redisCluster,
+	}
+}
+
+// Sets the state of the cluster view and patches it on the status, the other fields of the status are left to the reconcile loop
+func (r *RedisClusterReconciler) saveClusterViewState(redisCluster *dbv1.RedisCluster, state view.ClusterState) {
+	r.RedisClusterStateView.ClusterState = state
+	err := r.patchStatus(redisCluster, func(status *dbv1.RedisClusterStatus) {
+		status.ClusterViewState = string(state)
+	})
+	if err != nil {
+		r.Log.Error(err, fmt.Sprintf("Could not save cluster state [%s]", state))
+	}
+}
+
+// Starts the job on the cluster of the request and answers with the job, or with the active job of the cluster
+func (r *RedisClusterReconciler) startJob(c echo.Context, kind string, work jobFunc) error {
+	key := types.NamespacedName{Namespace: c.Param("namespace"), Name: c.Param("name")}
+	job, started := r.jobRegistry().start(key, kind, work)
+	if !started {
+		r.Log.Info(fmt.Sprintf("[Warn] Job [%s] was not started on cluster [%s], job [%s] of kind [%s] is still running", kind, key, job.ID, job.Kind))
+		return c.JSON(http.StatusConflict, job.snapshot())
+	}
+	r.Log.Info(fmt.Sprintf("Started job [%s] of kind [%s] on cluster [%s]", job.ID, kind, key))
+	c.Response().Header().Set(echo.HeaderLocation, fmt.Sprintf("/cluster/%s/%s/jobs/%s", key.Namespace, key.Name, job.ID))
+	return c.JSON(http.StatusAccepted, job.snapshot())
+}
+
+/**
+Gets the state, the progress and the result of a job of the cluster
+**/
+func (r *RedisClusterReconciler) GetJob(c echo.Context) error {
+	key := types.NamespacedName{Namespace: c.Param("namespace"), Name: c.Param("name")}
+	job, exists := r.jobRegistry().get(key, c.Param("id"))
+	if !exists {
+		return respondError(c, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Job [%s] of cluster [%s] not found", c.Param("id"), key)))
+	}
+	return c.JSON(http.StatusOK, job.snapshot())
+}
+
+/**
+Cancels a job of the cluster, the job stops at the end of its current step
+**/
+func (r *RedisClusterReconciler) CancelJob(c echo.Context) error {
+	key := types.NamespacedName{Namespace: c.Param("namespace"), Name: c.Param("name")}
+	job, exists := r.jobRegistry().get(key, c.Param("id"))
+	if !exists {
+		return respondError(c, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Job [%s] of cluster [%s] not found", c.Param("id"), key)))
+	}
+	if job.active() {
+		r.Log.Info(fmt.Sprintf("Cancelling job [%s] of kind [%s] on cluster [%s]", job.ID, job.Kind, key))
+		job.cancel()
+	}
+	return c.JSON(http.StatusOK, job.snapshot())
+}
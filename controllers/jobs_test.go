@@ -0,0 +1,114 @@
+package controllers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+)
+
+// Calls the job handler on the path of the job of the cluster, the answered job is decoded when there is one
+func callJobHandler(test *testing.T, handler echo.HandlerFunc, method string, cluster string, id string) (int, *Job) {
+	request := httptest.NewRequest(method, "/cluster/default/"+cluster+"/jobs/"+id, nil)
+	recorder := httptest.NewRecorder()
+	c := echo.New().NewContext(request, recorder)
+	c.SetParamNames("namespace", "name", "id")
+	c.SetParamValues("default", cluster, id)
+	if err := handler(c); err != nil {
+		test.Fatal(err)
+	}
+	job := &Job{}
+	if recorder.Code != http.StatusNotFound {
+		if err := json.Unmarshal(recorder.Body.Bytes(), job); err != nil {
+			test.Fatalf("Could not decode the job %s: %v", recorder.Body.String(), err)
+		}
+	}
+	return recorder.Code, job
+}
+
+// The work of a job that runs until it is cancelled
+func waitForCancel(job *Job) (interface{}, error) {
+	<-job.Context().Done()
+	return nil, nil
+}
+
+func waitForJobEnd(test *testing.T, job *Job) {
+	if err := wait.PollImmediate(10*time.Millisecond, time.Second, func() (bool, error) {
+		return !job.active(), nil
+	}); err != nil {
+		test.Fatalf("Expected job [%s] to end", job.ID)
+	}
+}
+
+func TestJobEntryPoints(test *testing.T) {
+	r := newTestReconciler(test)
+	startRebalance := func(c echo.Context) error {
+		return r.startJob(c, "rebalance", waitForCancel)
+	}
+
+	code, started := callJobHandler(test, startRebalance, http.MethodPost, "dev-rdc", "")
+	if code != http.StatusAccepted || started.State != JobRunning || started.Kind != "rebalance" {
+		test.Fatalf("Expected the job to be started, got %d %+v", code, started)
+	}
+	if code, active := callJobHandler(test, startRebalance, http.MethodPost, "dev-rdc", ""); code != http.StatusConflict || active.ID != started.ID {
+		test.Errorf("Expected a conflict with the active job [%s], got %d %+v", started.ID, code, active)
+	}
+	code, other := callJobHandler(test, startRebalance, http.MethodPost, "other-rdc", "")
+	if code != http.StatusAccepted {
+		test.Errorf("Expected the job of another cluster to be started, got %d", code)
+	}
+
+	if code, job := callJobHandler(test, r.GetJob, http.MethodGet, "dev-rdc", started.ID); code != http.StatusOK || job.ID != started.ID || job.State != JobRunning {
+		test.Errorf("Expected the running job, got %d %+v", code, job)
+	}
+	if code, _ := callJobHandler(test, r.GetJob, http.MethodGet, "other-rdc", started.ID); code != http.StatusNotFound {
+		test.Errorf("Expected the job not to be found on another cluster, got %d", code)
+	}
+	if code, _ := callJobHandler(test, r.GetJob, http.MethodGet, "dev-rdc", "unknown"); code != http.StatusNotFound {
+		test.Errorf("Expected an unknown job not to be found, got %d", code)
+	}
+
+	if code, _ := callJobHandler(test, r.CancelJob, http.MethodDelete, "dev-rdc", started.ID); code != http.StatusOK {
+		test.Errorf("Expected the job to be cancelled, got %d", code)
+	}
+	job, _ := r.jobRegistry().get(types.NamespacedName{Namespace: "default", Name: "dev-rdc"}, started.ID)
+	waitForJobEnd(test, job)
+	if code, cancelled := callJobHandler(test, r.GetJob, http.MethodGet, "dev-rdc", started.ID); code != http.StatusOK || cancelled.State != JobCancelled || cancelled.FinishedAt == nil {
+		test.Errorf("Expected the job to end as cancelled through its context, got %d %+v", code, cancelled)
+	}
+	if code, _ := callJobHandler(test, startRebalance, http.MethodPost, "dev-rdc", ""); code != http.StatusAccepted {
+		test.Errorf("Expected a job to be started once the active job ended, got %d", code)
+	}
+	if code, job := callJobHandler(test, r.GetJob, http.MethodGet, "other-rdc", other.ID); code != http.StatusOK || job.State != JobRunning {
+		test.Errorf("Expected the job of the other cluster to keep running, got %d %+v", code, job)
+	}
+}
+
+func TestReleaseJobs(test *testing.T) {
+	r := newTestReconciler(test)
+	registry := r.jobRegistry()
+	deleted := types.NamespacedName{Namespace: "default", Name: "dev-rdc"}
+	other := types.NamespacedName{Namespace: "default", Name: "other-rdc"}
+	job, _ := registry.start(deleted, "rebalance", waitForCancel)
+	otherJob, _ := registry.start(other, "rebalance", waitForCancel)
+
+	registry.release(deleted)
+	waitForJobEnd(test, job)
+	if !job.Cancelled() {
+		test.Errorf("Expected the job of the deleted cluster to be cancelled")
+	}
+	if _, exists := registry.get(deleted, job.ID); exists {
+		test.Errorf("Expected the job of the deleted cluster to be forgotten")
+	}
+	if _, exists := registry.get(other, otherJob.ID); !exists || !otherJob.active() {
+		test.Errorf("Expected the job of the other cluster to keep running")
+	}
+	if _, started := registry.start(deleted, "rebalance", waitForCancel); !started {
+		test.Errorf("Expected a cluster created again with the same name to start a job")
+	}
+}
@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"sort"
+	"strconv"
 	"strings"
 	"sync"
 
@@ -34,7 +36,11 @@ type K8sManager struct {
 
 func (r *RedisClusterReconciler) getRedisClusterPods(redisCluster *dbv1.RedisCluster, podType ...string) ([]corev1.Pod, error) {
 	pods := &corev1.PodList{}
-	matchingLabels := redisCluster.Spec.PodLabelSelector
+	matchingLabels := map[string]string{}
+	for k, v := range redisCluster.Spec.PodLabelSelector {
+		matchingLabels[k] = v
+	}
+	matchingLabels["redis-cluster"] = redisCluster.Name
 	if len(podType) > 0 && strings.TrimSpace(podType[0]) != "" {
 		pt := strings.TrimSpace(podType[0])
 		if pt == "follower" || pt == "leader" {
@@ -56,15 +62,64 @@ func getSelectorRequirementFromPodLabelSelector(redisCluster *dbv1.RedisCluster)
 	return lsr
 }
 
-func (r *RedisClusterReconciler) setClusterStateView(redisCluster *dbv1.RedisCluster) (error) {
+// Previous versions of the operator kept the state view of each RedisCluster in a config map named after the custom resource
+func clusterStateMapName(redisClusterName string) string {
+	return redisClusterName + "-state-map"
+}
+
+// The versions of the operator that managed a single RedisCluster kept its state view in a config map of a fixed name
+const legacyClusterStateMapName = "redis-cluster-state-map"
+
+func clusterServiceName(redisClusterName string) string {
+	return redisClusterName + "-service"
+}
+
+// The version of the nodes state schema kept in the RedisCluster status
+const clusterStateViewVersion = 1
+
+func (r *RedisClusterReconciler) setClusterStateView(redisCluster *dbv1.RedisCluster) error {
 	if r.RedisClusterStateView == nil {
-		r.RedisClusterStateView = &view.RedisClusterStateView{Name: RedisClusterStateMapName}
+		r.RedisClusterStateView = &view.RedisClusterStateView{Name: clusterStateMapName(redisCluster.Name)}
+	}
+	if redisCluster.Status.StateViewVersion == 0 {
+		return r.migrateClusterStateView(redisCluster)
+	}
+	redisClusterStateView := view.RedisClusterStateView{
+		Name:                                   r.RedisClusterStateView.Name,
+		ClusterState:                           view.ClusterState(redisCluster.Status.ClusterViewState),
+		NumOfReconcileLoopsSinceHealthyCluster: redisCluster.Status.NumOfReconcileLoopsSinceHealthyCluster,
+		NumOfHealthyReconcileLoopsInRow:        redisCluster.Status.NumOfHealthyReconcileLoopsInRow,
+		Nodes:                                  make(map[string]*view.NodeStateView),
+	}
+	for _, n := range redisCluster.Status.Nodes {
+		redisClusterStateView.Nodes[n.Name] = &view.NodeStateView{
+			Name:       n.Name,
+			LeaderName: n.LeaderName,
+			IsUpToDate: n.IsUpToDate,
+			NodeState:  view.NodeState(n.NodeState),
+		}
 	}
+	r.RedisClusterStateView = &redisClusterStateView
+	return nil
+}
+
+// Clusters created by previous versions of the operator keep their state view as a json in a config map,
+// the view is moved once to the RedisCluster status and the config map is deleted.
+// The config map of the single cluster versions of the operator is migrated when the cluster has no config map of its own.
+func (r *RedisClusterReconciler) migrateClusterStateView(redisCluster *dbv1.RedisCluster) error {
 	configMapName := r.RedisClusterStateView.Name
 	configMapNamespace := redisCluster.ObjectMeta.Namespace
 	var configMap corev1.ConfigMap
 	var redisClusterStateView view.RedisClusterStateView
 	err := r.Get(context.Background(), client.ObjectKey{Name: configMapName, Namespace: configMapNamespace}, &configMap)
+	if apierrors.IsNotFound(err) {
+		legacyErr := r.Get(context.Background(), client.ObjectKey{Name: legacyClusterStateMapName, Namespace: configMapNamespace}, &configMap)
+		if legacyErr == nil {
+			configMapName, err = legacyClusterStateMapName, nil
+		} else if !apierrors.IsNotFound(legacyErr) {
+			err = legacyErr
+		}
+	}
 	if err != nil {
 		return err
 	}
@@ -73,69 +128,132 @@ func (r *RedisClusterReconciler) setClusterStateView(redisCluster *dbv1.RedisClu
 	if err != nil {
 		return err
 	}
+	r.Log.Info(fmt.Sprintf("Migrating cluster state view from config map [%s] to the RedisCluster status", configMapName))
+	redisClusterStateView.Name = r.RedisClusterStateView.Name
 	r.RedisClusterStateView = &redisClusterStateView
-	return nil
+	r.setClusterStateViewStatus(redisCluster)
+	if err = r.saveOperatorState(redisCluster); err != nil {
+		return err
+	}
+	r.recordEvent(corev1.EventTypeNormal, eventStateViewMigrated, "Cluster state view was migrated from config map [%s] to the status", configMapName)
+	if r.skipInObserveOnly("delete config map [%s]", configMapName) {
+		return nil
+	}
+	return r.Delete(context.Background(), &configMap)
 }
 
 // Update methods
 
 func (r *RedisClusterReconciler) saveClusterStateView(redisCluster *dbv1.RedisCluster) {
-	r.Log.Info("Saving cluster state view")
-	configMapName := r.RedisClusterStateView.Name
-	configMapNamespace := redisCluster.ObjectMeta.Namespace
-	bytes, e := json.Marshal(r.RedisClusterStateView)
-	if e != nil {
-		r.Log.Error(e, "Error while attemting json marshal for cluster state view...")
+	if r.planning() {
 		return
 	}
-	data := map[string]string{
-		"data": string(bytes),
-	}
-	configMap := corev1.ConfigMap{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "ConfigMap",
-			APIVersion: "v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      configMapName,
-			Namespace: configMapNamespace,
-		},
-		Data: data,
-	}
-	e = r.Update(context.Background(), &configMap)
-	if e != nil {
+	r.Log.Info("Saving cluster state view")
+	r.setClusterStateViewStatus(redisCluster)
+	if e := r.saveOperatorState(redisCluster); e != nil {
 		r.Log.Error(e, "Error while attemting update for cluster state view...")
 		return
 	}
 	r.Log.Info("Cluster state view saved")
 }
 
-// Create/Make/Write methods
-
-func (r *RedisClusterReconciler) postNewClusterStateView(redisCluster *dbv1.RedisCluster) error {
-	configMapName := r.RedisClusterStateView.Name
-	configMapNamespace := redisCluster.ObjectMeta.Namespace
-	bytes, e := json.Marshal(r.RedisClusterStateView)
+// Writes the cluster state view into the RedisCluster status, completed by the details each reachable node reports on itself.
+// The details of non reachable nodes are kept as they were last observed.
+func (r *RedisClusterReconciler) setClusterStateViewStatus(redisCluster *dbv1.RedisCluster) {
+	observed := make(map[string]dbv1.RedisNodeStatus)
+	for _, n := range redisCluster.Status.Nodes {
+		observed[n.Name] = n
+	}
+	podIPs := make(map[string]string)
+	pods, e := r.getRedisClusterPods(redisCluster)
 	if e != nil {
-		return e
+		r.Log.Info(fmt.Sprintf("[Warn] Could not fetch cluster pods list for nodes status: %v", e.Error()))
 	}
-	data := map[string]string{
-		"data": string(bytes),
+	for _, pod := range pods {
+		podIPs[pod.Name] = view.NodeAddress(pod)
 	}
-	configMap := corev1.ConfigMap{
-		TypeMeta: metav1.TypeMeta{
-			Kind:       "ConfigMap",
-			APIVersion: "v1",
-		},
-		ObjectMeta: metav1.ObjectMeta{
-			Name:      configMapName,
-			Namespace: configMapNamespace,
-		},
-		Data: data,
+	zones := r.podZones(pods)
+	nodes := make([]dbv1.RedisNodeStatus, 0, len(r.RedisClusterStateView.Nodes))
+	for _, n := range r.RedisClusterStateView.Nodes {
+		nodeStatus := observed[n.Name]
+		nodeStatus.Name = n.Name
+		nodeStatus.LeaderName = n.LeaderName
+		nodeStatus.NodeState = string(n.NodeState)
+		nodeStatus.IsUpToDate = n.IsUpToDate
+		if zone, scheduled := zones[n.Name]; scheduled {
+			nodeStatus.Zone = zone
+		}
+		if ip := podIPs[n.Name]; len(ip) > 0 {
+			r.observeNodeStatus(ip, &nodeStatus)
+		}
+		nodes = append(nodes, nodeStatus)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	redisCluster.Status.StateViewVersion = clusterStateViewVersion
+	redisCluster.Status.ClusterViewState = string(r.RedisClusterStateView.ClusterState)
+	redisCluster.Status.NumOfReconcileLoopsSinceHealthyCluster = r.RedisClusterStateView.NumOfReconcileLoopsSinceHealthyCluster
+	redisCluster.Status.NumOfHealthyReconcileLoopsInRow = r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow
+	redisCluster.Status.Nodes = nodes
+	r.updateZoneSpread(redisCluster, zones)
+	r.updateSlotDistribution(redisCluster, pods)
+}
+
+// The details a node reported on itself, nil when the node could not report them
+type observedNode struct {
+	clusterNode *rediscli.RedisClusterNode
+	info        *rediscli.RedisInfo
+}
+
+// Completes the status of the node by the details it reports on itself, the node is asked once per reconcile loop
+func (r *RedisClusterReconciler) observeNodeStatus(ip string, nodeStatus *dbv1.RedisNodeStatus) {
+	observed, exists := r.observedNodes[ip]
+	if !exists {
+		observed = r.observeNode(ip)
+		if r.observedNodes == nil {
+			r.observedNodes = make(map[string]observedNode)
+		}
+		r.observedNodes[ip] = observed
 	}
-	return r.Create(context.Background(), &configMap)
+	if clusterNode := observed.clusterNode; clusterNode != nil {
+		nodeStatus.NodeID = clusterNode.ID
+		if strings.Contains(clusterNode.Flags, "master") {
+			nodeStatus.Role = "leader"
+			nodeStatus.Slots = strings.Fields(clusterNode.Slots)
+		} else {
+			nodeStatus.Role = "follower"
+			nodeStatus.Slots = nil
+		}
+	}
+	if info := observed.info; info != nil {
+		if offset, err := strconv.ParseInt(info.Replication["master_repl_offset"], 10, 64); err == nil {
+			nodeStatus.ReplicationOffset = offset
+		}
+		if usedMemory, err := strconv.ParseInt(info.Memory["used_memory"], 10, 64); err == nil {
+			nodeStatus.UsedMemory = usedMemory
+		}
+	}
+}
+
+func (r *RedisClusterReconciler) observeNode(ip string) observedNode {
+	var observed observedNode
+	if clusterNodes, _, e := r.RedisCLI.ClusterNodes(ip); e == nil && clusterNodes != nil {
+		for i, clusterNode := range *clusterNodes {
+			if strings.Contains(clusterNode.Flags, "myself") {
+				observed.clusterNode = &(*clusterNodes)[i]
+				break
+			}
+		}
+	}
+	if info, _, e := r.RedisCLI.Info(ip); e == nil && info != nil {
+		observed.info = info
+	}
+	return observed
 }
 
+// Create/Make/Write methods
+
 func (r *K8sManager) WritePodAnnotations(annotations map[string]string, pods ...corev1.Pod) error {
 	annotationsString := ""
 	for key, val := range annotations {
@@ -166,7 +284,7 @@ func (r *RedisClusterReconciler) makeRedisPod(redisCluster *dbv1.RedisCluster, n
 	podLabels["node-name"] = nodeName
 	podLabels["redis-cluster"] = redisCluster.Name
 
-	if redisCluster.Spec.EnableDefaultAffinity {
+	if redisCluster.Spec.EnableDefaultAffinity == nil || *redisCluster.Spec.EnableDefaultAffinity {
 		if redisCluster.Spec.RedisPodSpec.Affinity == nil {
 			affinity = corev1.Affinity{}
 		} else {
@@ -211,6 +329,9 @@ func (r *RedisClusterReconciler) makeRedisPod(redisCluster *dbv1.RedisCluster, n
 
 	spec := redisCluster.Spec.RedisPodSpec.DeepCopy()
 	spec.Affinity = &affinity
+	r.addRedisTLS(redisCluster, spec)
+	r.addRedisPersistence(redisCluster, spec, nodeName)
+	r.addRedisHostname(redisCluster, spec, nodeName)
 
 	pod := corev1.Pod{
 		TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "Pod"},
@@ -238,10 +359,29 @@ func (r *RedisClusterReconciler) createRedisService(redisCluster *dbv1.RedisClus
 	return &svc, nil
 }
 
+// Creates the service of the cluster if it does not exist. A cluster created by the single cluster versions of the
+// operator keeps its 'redis-cluster-service' next to it for the clients, until the cluster is deleted.
+func (r *RedisClusterReconciler) ensureRedisService(redisCluster *dbv1.RedisCluster) error {
+	key := client.ObjectKey{Namespace: redisCluster.Namespace, Name: clusterServiceName(redisCluster.Name)}
+	err := r.Get(context.Background(), key, &corev1.Service{})
+	if !apierrors.IsNotFound(err) {
+		return err
+	}
+	if _, err := r.createRedisService(redisCluster); err != nil {
+		return err
+	}
+	r.Log.Info(fmt.Sprintf("Created the service [%s] of the cluster", key.Name))
+	return nil
+}
+
 func (r *RedisClusterReconciler) makeService(redisCluster *dbv1.RedisCluster) (corev1.Service, error) {
+	selector := map[string]string{"redis-cluster": redisCluster.Name}
+	for k, v := range redisCluster.Spec.PodLabelSelector {
+		selector[k] = v
+	}
 	service := corev1.Service{
 		ObjectMeta: metav1.ObjectMeta{
-			Name:      "redis-cluster-service",
+			Name:      clusterServiceName(redisCluster.Name),
 			Namespace: redisCluster.ObjectMeta.Namespace,
 		},
 		Spec: corev1.ServiceSpec{
@@ -252,7 +392,7 @@ func (r *RedisClusterReconciler) makeService(redisCluster *dbv1.RedisCluster) (c
 					TargetPort: intstr.FromInt(6379),
 				},
 			},
-			Selector: redisCluster.Spec.PodLabelSelector,
+			Selector: selector,
 		},
 	}
 
@@ -272,16 +412,23 @@ func (r *RedisClusterReconciler) makeAndCreateRedisPod(redisCluster *dbv1.RedisC
 		role = "follower"
 	}
 	pod := r.makeRedisPod(redisCluster, role, n.LeaderName, n.Name, preferredLabelSelectorRequirement)
+	if r.skipInObserveOnly("create %s pod [%s]", role, n.Name) {
+		return pod, rediscli.ErrObserveOnly
+	}
 	err := ctrl.SetControllerReference(redisCluster, &pod, r.Scheme)
 	if err != nil {
 		r.Log.Error(err, fmt.Sprintf("Could not re create pod [%s]", n.Name))
-		r.deletePod(pod)
+		r.deletePodKeepVolume(pod)
+		return pod, err
+	}
+	if err = r.createNodeVolume(redisCluster, n.Name); err != nil {
+		r.Log.Error(err, fmt.Sprintf("Could not re create pod [%s]", n.Name))
 		return pod, err
 	}
 	err = r.Create(context.Background(), &pod, createOpts...)
 	if err != nil && !strings.Contains(err.Error(), "already exists") {
 		r.Log.Error(err, fmt.Sprintf("Could not re create pod [%s]", n.Name))
-		r.deletePod(pod)
+		r.deletePodKeepVolume(pod)
 		return pod, err
 	}
 	return pod, nil
@@ -341,6 +488,7 @@ func (r *RedisClusterReconciler) createMissingRedisPods(redisCluster *dbv1.Redis
 			if err != nil {
 				return
 			}
+			r.countRecreatedPod()
 			mutex.Lock()
 			pods = append(pods, pod)
 			mutex.Unlock()
@@ -352,7 +500,7 @@ func (r *RedisClusterReconciler) createMissingRedisPods(redisCluster *dbv1.Redis
 		newPods, err := r.waitForPodNetworkInterface(pods...)
 		if err != nil {
 			r.Log.Error(err, fmt.Sprintf("Could not re create missing pods"))
-			r.deletePods(pods)
+			r.deletePodsKeepVolume(pods)
 			return map[string]corev1.Pod{}
 		}
 		wg.Add(len(newPods))
@@ -361,7 +509,7 @@ func (r *RedisClusterReconciler) createMissingRedisPods(redisCluster *dbv1.Redis
 				defer wg.Done()
 				readyPod, err := r.waitForRedisPod(p)
 				if err != nil {
-					r.deletePod(p)
+					r.deletePodKeepVolume(p)
 					return
 				}
 				mutex.Lock()
@@ -381,14 +529,14 @@ func (r *RedisClusterReconciler) waitForRedisPod(p corev1.Pod) (corev1.Pod, erro
 	podArray, err := r.waitForPodReady(p)
 	if err != nil || len(podArray) == 0 {
 		r.Log.Error(err, fmt.Sprintf("Could not re create pod [%s]", p.Name))
-		r.deletePod(p)
+		r.deletePodKeepVolume(p)
 		return corev1.Pod{}, err
 	}
 	pod := podArray[0]
-	err = r.waitForRedis(pod.Status.PodIP)
+	err = r.waitForRedis(view.NodeAddress(pod))
 	if err != nil {
 		r.Log.Error(err, fmt.Sprintf("Could not re create pod [%s]", p.Name))
-		r.deletePod(p)
+		r.deletePodKeepVolume(p)
 		return corev1.Pod{}, err
 	}
 	return pod, nil
@@ -407,14 +555,20 @@ func (r *RedisClusterReconciler) createRedisLeaderPods(redisCluster *dbv1.RedisC
 		err = ctrl.SetControllerReference(redisCluster, &pod, r.Scheme)
 		if err != nil {
 			r.Log.Error(err, fmt.Sprintf("Could not re create pod [%s]", pod.Name))
-			r.deletePod(pod)
+			r.deletePodKeepVolume(pod)
 			return leaderPods, err
 		}
 		leaderPods = append(leaderPods, pod)
 	}
 
+	if r.skipInObserveOnly("create leader pods %v", nodeNames) {
+		return nil, rediscli.ErrObserveOnly
+	}
 	applyOpts := []client.CreateOption{client.FieldOwner("redis-operator-controller")}
 	for _, pod := range leaderPods {
+		if err := r.createNodeVolume(redisCluster, pod.Name); err != nil {
+			return nil, err
+		}
 		err := r.Create(context.Background(), &pod, applyOpts...)
 		if err != nil && !apierrors.IsAlreadyExists(err) && !apierrors.IsConflict(err) {
 			return nil, err
@@ -445,6 +599,9 @@ func (r *RedisClusterReconciler) makeLeaderPod(redisCluster *dbv1.RedisCluster,
 // Wait methods
 
 func (r *RedisClusterReconciler) waitForPodReady(pods ...corev1.Pod) ([]corev1.Pod, error) {
+	if r.planning() {
+		return r.planner.addressPlannedPods(pods), nil
+	}
 	var readyPods []corev1.Pod
 	for _, pod := range pods {
 		key, err := client.ObjectKeyFromObject(&pod)
@@ -474,6 +631,9 @@ func (r *RedisClusterReconciler) waitForPodReady(pods ...corev1.Pod) ([]corev1.P
 }
 
 func (r *RedisClusterReconciler) waitForPodNetworkInterface(pods ...corev1.Pod) ([]corev1.Pod, error) {
+	if r.planning() {
+		return r.planner.addressPlannedPods(pods), nil
+	}
 	r.Log.Info(fmt.Sprintf("Waiting for pod network interfaces..."))
 	var readyPods []corev1.Pod
 	for _, pod := range pods {
@@ -498,6 +658,9 @@ func (r *RedisClusterReconciler) waitForPodNetworkInterface(pods ...corev1.Pod)
 }
 
 func (r *RedisClusterReconciler) waitForPodDelete(pods ...corev1.Pod) {
+	if r.planning() {
+		return
+	}
 	var wg sync.WaitGroup
 	wg.Add(len(pods))
 	for _, p := range pods {
@@ -529,12 +692,12 @@ func (r *RedisClusterReconciler) waitForPodDelete(pods ...corev1.Pod) {
 
 // Delete methods
 
-func (r *RedisClusterReconciler) deleteAllRedisClusterPods() error {
-	pods, e := r.getRedisClusterPods(cluster)
+func (r *RedisClusterReconciler) deleteAllRedisClusterPods(redisCluster *dbv1.RedisCluster) error {
+	pods, e := r.getRedisClusterPods(redisCluster)
 	if e != nil {
 		return e
 	}
-	deletedPods, err := r.deletePods(pods)
+	deletedPods, err := r.deletePodsKeepVolume(pods)
 	if err != nil {
 		return err
 	}
@@ -543,9 +706,18 @@ func (r *RedisClusterReconciler) deleteAllRedisClusterPods() error {
 }
 
 func (r *RedisClusterReconciler) deletePods(pods []corev1.Pod) ([]corev1.Pod, error) {
+	return r.deletePodsBy(r.deletePod, pods)
+}
+
+// Deletes the pods to create them again, the nodes of the pods come back on their claims
+func (r *RedisClusterReconciler) deletePodsKeepVolume(pods []corev1.Pod) ([]corev1.Pod, error) {
+	return r.deletePodsBy(r.deletePodKeepVolume, pods)
+}
+
+func (r *RedisClusterReconciler) deletePodsBy(deletePod func(corev1.Pod) error, pods []corev1.Pod) ([]corev1.Pod, error) {
 	deletedPods := []corev1.Pod{}
 	for _, pod := range pods {
-		err := r.deletePod(pod)
+		err := deletePod(pod)
 		if err != nil {
 			return deletedPods, err
 		}
@@ -554,28 +726,44 @@ func (r *RedisClusterReconciler) deletePods(pods []corev1.Pod) ([]corev1.Pod, er
 	return deletedPods, nil
 }
 
+// Deletes the pod and its volume claim, the node of the pod starts over when it is created again
 func (r *RedisClusterReconciler) deletePod(pod corev1.Pod) error {
+	if err := r.deletePodKeepVolume(pod); err != nil {
+		return err
+	}
+	return r.deletePodVolume(pod)
+}
+
+func (r *RedisClusterReconciler) deletePodKeepVolume(pod corev1.Pod) error {
+	if r.skipInObserveOnly("delete pod [%s]", pod.Name) {
+		return rediscli.ErrObserveOnly
+	}
 	if err := r.Delete(context.Background(), &pod); err != nil {
 		if !strings.Contains(err.Error(), "not found") {
 			r.Log.Error(err, "Could not delete pod: "+pod.Name)
+			r.recordEvent(corev1.EventTypeWarning, eventDeletePodFailed, "Could not delete pod [%s]: %v", pod.Name, err)
 			return err
 		}
+		return nil
 	}
+	r.recordEvent(corev1.EventTypeNormal, eventDeletedPod, "Deleted pod [%s]", pod.Name)
 	return nil
 }
 
+// Clears the nodes state kept in the RedisCluster status, and the config map used by previous versions of the operator if it still exists
 func (r *RedisClusterReconciler) deleteClusterStateView(redisCluster *dbv1.RedisCluster) error {
+	if r.skipInObserveOnly("clear the cluster state view") {
+		return nil
+	}
+	redisCluster.Status.Nodes = nil
 	configMapName := r.RedisClusterStateView.Name
 	configMapNamespace := redisCluster.ObjectMeta.Namespace
 	var configMap corev1.ConfigMap
 	e := r.Get(context.Background(), client.ObjectKey{Name: configMapName, Namespace: configMapNamespace}, &configMap)
 	if e != nil {
-		return e
+		return client.IgnoreNotFound(e)
 	}
-	if len(configMap.Data) > 0 {
-		return r.Delete(context.Background(), &configMap)
-	}
-	return nil
+	return r.Delete(context.Background(), &configMap)
 }
 
 func (r *RedisClusterReconciler) ClusterNodesWaitForRedisLoadDataSetInMemory(ips ...string) (ipsToNodes map[string]*rediscli.RedisClusterNodes, err error) {
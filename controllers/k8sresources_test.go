@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/view"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// The state map as it was written by the single cluster versions of the operator
+const legacyStateMap = `{"Name":"redis-cluster-state-map","ClusterState":"ClusterOK","NumOfReconcileLoopsSinceHealthyCluster":0,` +
+	`"NumOfHealthyReconcileLoopsInRow":10,"Nodes":{` +
+	`"redis-node-0":{"Name":"redis-node-0","LeaderName":"redis-node-0","IsUpToDate":true,"NodeState":"NodeOK"},` +
+	`"redis-node-0-1":{"Name":"redis-node-0-1","LeaderName":"redis-node-0","IsUpToDate":false,"NodeState":"FailoverNode"}}}`
+
+func newTestReconciler(test *testing.T, objects ...runtime.Object) *RedisClusterReconciler {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		test.Fatal(err)
+	}
+	if err := dbv1.AddToScheme(scheme); err != nil {
+		test.Fatal(err)
+	}
+	return &RedisClusterReconciler{
+		Client: fake.NewFakeClientWithScheme(scheme, objects...),
+		Log:    ctrl.Log.WithName("test"),
+		Scheme: scheme,
+	}
+}
+
+func TestMigrateLegacyClusterStateView(test *testing.T) {
+	redisCluster := &dbv1.RedisCluster{ObjectMeta: metav1.ObjectMeta{Name: "dev-rdc", Namespace: "default"}}
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Name: legacyClusterStateMapName, Namespace: "default"},
+		Data:       map[string]string{"data": legacyStateMap},
+	}
+	r := newTestReconciler(test, redisCluster.DeepCopy(), configMap)
+	if err := r.Get(context.Background(), client.ObjectKey{Name: "dev-rdc", Namespace: "default"}, redisCluster); err != nil {
+		test.Fatal(err)
+	}
+
+	if err := r.setClusterStateView(redisCluster); err != nil {
+		test.Fatalf("Unexpected error %v", err)
+	}
+	if r.RedisClusterStateView.Name != clusterStateMapName("dev-rdc") || r.RedisClusterStateView.ClusterState != view.ClusterOK {
+		test.Errorf("Unexpected state view %+v", r.RedisClusterStateView)
+	}
+	follower := r.RedisClusterStateView.Nodes["redis-node-0-1"]
+	if follower == nil || follower.NodeState != view.FailoverNode || follower.IsUpToDate {
+		test.Errorf("Expected the state of the follower to be kept, got %+v", follower)
+	}
+
+	var saved dbv1.RedisCluster
+	if err := r.Get(context.Background(), client.ObjectKey{Name: "dev-rdc", Namespace: "default"}, &saved); err != nil {
+		test.Fatal(err)
+	}
+	if saved.Status.StateViewVersion != clusterStateViewVersion || len(saved.Status.Nodes) != 2 || saved.Status.NumOfHealthyReconcileLoopsInRow != 10 {
+		test.Errorf("Expected the state view to be saved to the status, got %+v", saved.Status)
+	}
+	for _, n := range saved.Status.Nodes {
+		if n.Name == "redis-node-0" && (n.NodeState != string(view.NodeOK) || !n.IsUpToDate) {
+			test.Errorf("Expected the state of the leader to be kept, got %+v", n)
+		}
+	}
+	err := r.Get(context.Background(), client.ObjectKey{Name: legacyClusterStateMapName, Namespace: "default"}, &corev1.ConfigMap{})
+	if !apierrors.IsNotFound(err) {
+		test.Errorf("Expected the legacy state map to be deleted, got %v", err)
+	}
+}
+
+func TestMigrateMissingClusterStateView(test *testing.T) {
+	redisCluster := &dbv1.RedisCluster{ObjectMeta: metav1.ObjectMeta{Name: "dev-rdc", Namespace: "default"}}
+	r := newTestReconciler(test, redisCluster.DeepCopy())
+	if err := r.setClusterStateView(redisCluster); !apierrors.IsNotFound(err) {
+		test.Errorf("Expected a not found error without a state map, got %v", err)
+	}
+}
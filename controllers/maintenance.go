@@ -0,0 +1,65 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+/*
+	The disruptive flows of the operator, the rolling updates, the reshards of the leader scaling, the rebalances, the zone balancing,
+	the leader switchbacks and the resets, run only while spec.paused is not set and one of spec.maintenanceWindows is open, or at any time
+	when no window is set. A flow that is due outside of the windows is deferred and logged, the cluster keeps
+	its current state until the next window opens. The deferral of a flow is logged and recorded as an event once,
+	when it starts. The recovery of the failed nodes runs at any time.
+	The windows are cron expressions in UTC with a duration:
+
+		maintenanceWindows:
+		- schedule: "0 2 * * 1-5"
+		  duration: 2h
+
+	The entry points of the HTTP API are called on purpose and are not deferred.
+*/
+
+// Checks if the disruptive flows may run on the cluster now and reports it on the DisruptionsAllowed condition
+func (r *RedisClusterReconciler) disruptionsAllowed(redisCluster *dbv1.RedisCluster) bool {
+	allowed, reason := redisCluster.Spec.DisruptionsAllowed(time.Now())
+	if allowed {
+		r.deferredFlows = nil
+	}
+	switch {
+	case allowed && len(redisCluster.Spec.MaintenanceWindows) == 0:
+		setCondition(redisCluster, dbv1.ConditionDisruptionsAllowed, true, reasonNoMaintenanceWindows, "No maintenance window is set, disruptive flows may run at any time")
+	case allowed:
+		setCondition(redisCluster, dbv1.ConditionDisruptionsAllowed, true, reasonMaintenanceWindowOpen, "A maintenance window is open")
+	case redisCluster.Spec.Paused:
+		setCondition(redisCluster, dbv1.ConditionDisruptionsAllowed, false, reasonPaused, reason)
+	default:
+		setCondition(redisCluster, dbv1.ConditionDisruptionsAllowed, false, reasonOutsideMaintenanceWindow, reason)
+	}
+	return allowed
+}
+
+// Returns true when the disruptive flow may not run on the cluster now, the deferral is logged when it starts
+func (r *RedisClusterReconciler) deferDisruption(redisCluster *dbv1.RedisCluster, flow string) bool {
+	if r.disruptionsAllowed(redisCluster) {
+		return false
+	}
+	condition := redisCluster.Status.GetCondition(dbv1.ConditionDisruptionsAllowed)
+	if r.planning() {
+		r.planner.add(PlanDefer, "", "", fmt.Sprintf("%s is deferred: %s", flow, condition.Message))
+		return true
+	}
+	if r.deferredFlows[flow] {
+		return true
+	}
+	if r.deferredFlows == nil {
+		r.deferredFlows = map[string]bool{}
+	}
+	r.deferredFlows[flow] = true
+	r.Log.Info(fmt.Sprintf("[Warn] %s is deferred: %s", flow, condition.Message))
+	r.recordEvent(corev1.EventTypeNormal, eventDisruptionDeferred, "%s is deferred: %s", flow, condition.Message)
+	return true
+}
@@ -0,0 +1,41 @@
+package controllers
+
+import (
+	"testing"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestDeferDisruptionRecordedOnce(test *testing.T) {
+	redisCluster := &dbv1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "dev-rdc", Namespace: "default"},
+		Spec:       dbv1.RedisClusterSpec{Paused: true},
+	}
+	recorder := record.NewFakeRecorder(10)
+	r := newTestReconciler(test)
+	r.Recorder, r.eventObject = recorder, redisCluster
+
+	// Every loop checks the windows, the deferral of each flow is recorded when it starts
+	for loop := 0; loop < 3; loop++ {
+		r.disruptionsAllowed(redisCluster)
+		for _, flow := range []string{"Rolling update", "Leader switchback"} {
+			if !r.deferDisruption(redisCluster, flow) {
+				test.Fatalf("Expected %s of a paused cluster to be deferred", flow)
+			}
+		}
+	}
+	if len(recorder.Events) != 2 {
+		test.Errorf("Expected a single event for the deferral of each flow, got %d", len(recorder.Events))
+	}
+
+	redisCluster.Spec.Paused = false
+	if r.deferDisruption(redisCluster, "Rolling update") {
+		test.Errorf("Expected the rolling update to run once the cluster is resumed")
+	}
+	redisCluster.Spec.Paused = true
+	if !r.deferDisruption(redisCluster, "Rolling update") || len(recorder.Events) != 3 {
+		test.Errorf("Expected the deferral to be recorded again after the disruptions were allowed, got %d events", len(recorder.Events))
+	}
+}
@@ -0,0 +1,213 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/rediscli"
+	"github.com/PayU/redis-operator/controllers/view"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+)
+
+/*
+	The same number of slots does not hold the same memory when some hash tags are hot or large. The memory rebalance
+	job analyzes every leader of the cluster view: its used_memory by INFO memory, and the memory of each of its slots
+	as estimated by the analyzer of rediscli from a sample of MemorySampledKeysPerSlot keys. It plans the moves of the
+	slots that bring the used memory of every leader within MemoryRebalanceTolerancePercent of the mean, and returns
+	the plan as the result of the job.
+	With the 'apply' query param the moves of the plan are carried out by the resharding engine under the lock of the
+	cluster, journaled on status.reshard like the other reshards, and the cluster state is set to ClusterFix if one of
+	them fails. The moves are applied to a Ready cluster that is not in the middle of a reshard only, otherwise the
+	request is answered by 409 Conflict. A plan is
+	a snapshot of the memory at the time of the analysis, it is not kept.
+*/
+
+type MemoryRebalancePlan struct {
+	Namespace        string                `json:"namespace"`
+	Cluster          string                `json:"cluster"`
+	MeanUsedMemory   int64                 `json:"meanUsedMemory"`
+	TolerancePercent int                   `json:"tolerancePercent"`
+	Leaders          []LeaderMemoryUsage   `json:"leaders"`
+	Moves            []MemoryRebalanceMove `json:"moves"`
+	Applied          bool                  `json:"applied"`
+}
+
+type LeaderMemoryUsage struct {
+	Name       string `json:"name"`
+	ID         string `json:"id"`
+	UsedMemory int64  `json:"usedMemory"`
+	Slots      int    `json:"slots"`
+	Keys       int64  `json:"keys"`
+	// The estimated memory of the keys of the slots of the leader
+	SlotsMemory int64 `json:"slotsMemory"`
+	// The used memory of the leader after the moves of the plan
+	PlannedMemory int64 `json:"plannedMemory"`
+}
+
+type MemoryRebalanceMove struct {
+	Source   string   `json:"source"`
+	SourceID string   `json:"sourceId"`
+	Target   string   `json:"target"`
+	TargetID string   `json:"targetId"`
+	Slots    []string `json:"slots"`
+	Keys     int64    `json:"keys"`
+	Bytes    int64    `json:"bytes"`
+}
+
+/**
+Starts a job that analyzes the memory of the slots of every leader and plans the moves of slots that even out
+the used memory of the leaders. With the 'apply' query param set to 'true' the moves are carried out by the resharding engine.
+**/
+func (r *RedisClusterReconciler) ClusterMemoryRebalance(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
+	}
+	apply := false
+	if value := c.QueryParam("apply"); value != "" {
+		if apply, err = strconv.ParseBool(value); err != nil {
+			return respondError(c, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid apply [%s]", value)))
+		}
+	}
+	if apply {
+		if err = memoryRebalanceConflict(redisCluster); err != nil {
+			return respondError(c, echo.NewHTTPError(http.StatusConflict, err.Error()))
+		}
+	}
+	return r.startJob(c, "memoryRebalance", func(job *Job) (interface{}, error) {
+		if !apply {
+			return cr.jobReconciler(job, redisCluster).memoryRebalance(job, redisCluster, apply)
+		}
+		return cr.runClusterJob(job, func(jr *RedisClusterReconciler, redisCluster *dbv1.RedisCluster) (interface{}, error) {
+			// The cluster may have changed while the job waited for the lock of the cluster
+			if err := memoryRebalanceConflict(redisCluster); err != nil {
+				return nil, err
+			}
+			return jr.memoryRebalance(job, redisCluster, apply)
+		})
+	})
+}
+
+// Returns the reason the moves of a memory rebalance can not be applied to the cluster, nil when they can
+func memoryRebalanceConflict(redisCluster *dbv1.RedisCluster) error {
+	if redisCluster.Status.Reshard != nil {
+		return errors.Errorf("Redis cluster [%s] is in the middle of a reshard", redisCluster.Name)
+	}
+	if redisCluster.Status.ClusterState != string(Ready) {
+		return errors.Errorf("Redis cluster [%s] is in state [%s], the moves are applied to a Ready cluster only", redisCluster.Name, redisCluster.Status.ClusterState)
+	}
+	return nil
+}
+
+func (r *RedisClusterReconciler) memoryRebalance(job *Job, redisCluster *dbv1.RedisCluster, apply bool) (interface{}, error) {
+	v, ok := r.NewRedisClusterView(redisCluster)
+	if !ok {
+		return nil, errors.New("Could not retrieve redis cluster view")
+	}
+	plan, moves, err := r.planMemoryRebalance(job, redisCluster, v)
+	if err != nil || !apply || len(moves) == 0 || job.Cancelled() {
+		return plan, err
+	}
+	healthyLeaderName, found := r.findHealthyLeader(v)
+	if !found {
+		return plan, errors.New("Could not find healthy reachable leader to serve the memory rebalance request")
+	}
+	healthyLeaderIp := v.Nodes[healthyLeaderName].Ip
+	r.saveClusterViewState(redisCluster, view.ClusterRebalance)
+	for i, move := range moves {
+		if job.Cancelled() {
+			break
+		}
+		job.SetProgress(int64(len(plan.Leaders)+i), int64(len(plan.Leaders)+len(moves)),
+			fmt.Sprintf("Moving %d slots from %s to %s", len(move.Slots), plan.Moves[i].Source, plan.Moves[i].Target))
+		if err := r.reshardSlots(redisCluster, healthyLeaderIp, move.SourceID, move.TargetID, move.Slots); err != nil {
+			r.saveClusterViewState(redisCluster, view.ClusterFix)
+			return plan, errors.Wrapf(err, "Could not move the slots from [%s] to [%s]", plan.Moves[i].Source, plan.Moves[i].Target)
+		}
+	}
+	r.saveClusterViewState(redisCluster, view.ClusterOK)
+	plan.Applied = !job.Cancelled()
+	job.SetProgress(int64(len(plan.Leaders)+len(moves)), int64(len(plan.Leaders)+len(moves)), "Memory rebalance plan applied")
+	return plan, nil
+}
+
+// Analyzes the memory of the leaders of the cluster and plans the moves of slots that even it out
+func (r *RedisClusterReconciler) planMemoryRebalance(job *Job, redisCluster *dbv1.RedisCluster, v *view.RedisClusterView) (*MemoryRebalancePlan, []rediscli.MemoryMove, error) {
+	isLeader, _, err := r.currentRoles(v)
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(isLeader))
+	for name := range isLeader {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	plan := &MemoryRebalancePlan{
+		Namespace:        redisCluster.Namespace,
+		Cluster:          redisCluster.Name,
+		TolerancePercent: r.Config.Thresholds.MemoryRebalanceTolerancePercent,
+		Leaders:          []LeaderMemoryUsage{},
+		Moves:            []MemoryRebalanceMove{},
+	}
+	leaders := make([]rediscli.LeaderMemory, 0, len(names))
+	namesById := map[string]string{}
+	for i, name := range names {
+		if job.Cancelled() {
+			return plan, nil, nil
+		}
+		job.SetProgress(int64(i), int64(len(names)), "Analyzing the memory of "+name)
+		node := v.Nodes[name]
+		info, _, err := r.RedisCLI.Info(node.Ip)
+		if err != nil || info == nil {
+			return plan, nil, errors.Errorf("Could not read the memory info of [%s]: %v", name, err)
+		}
+		usedMemory, err := strconv.ParseInt(info.Memory["used_memory"], 10, 64)
+		if err != nil {
+			return plan, nil, errors.Errorf("Could not read the used memory of [%s]: %v", name, err)
+		}
+		slots, err := r.RedisCLI.SampleSlotsMemory(node.Ip, r.Config.Thresholds.MemorySampledKeysPerSlot)
+		if err != nil {
+			return plan, nil, err
+		}
+		usage := LeaderMemoryUsage{Name: name, ID: node.Id, UsedMemory: usedMemory, Slots: len(slots), PlannedMemory: usedMemory}
+		for _, slot := range slots {
+			usage.Keys += slot.Keys
+			usage.SlotsMemory += slot.Bytes
+		}
+		plan.Leaders = append(plan.Leaders, usage)
+		leaders = append(leaders, rediscli.LeaderMemory{ID: node.Id, UsedMemory: usedMemory, Slots: slots})
+		namesById[node.Id] = name
+		plan.MeanUsedMemory += usedMemory
+	}
+	if len(leaders) > 0 {
+		plan.MeanUsedMemory /= int64(len(leaders))
+	}
+
+	moves := rediscli.PlanMemoryRebalance(leaders, float64(r.Config.Thresholds.MemoryRebalanceTolerancePercent))
+	for _, move := range moves {
+		plan.Moves = append(plan.Moves, MemoryRebalanceMove{
+			Source:   namesById[move.SourceID],
+			SourceID: move.SourceID,
+			Target:   namesById[move.TargetID],
+			TargetID: move.TargetID,
+			Slots:    rediscli.SlotRanges(move.Slots),
+			Keys:     move.Keys,
+			Bytes:    move.Bytes,
+		})
+		for i := range plan.Leaders {
+			switch plan.Leaders[i].ID {
+			case move.SourceID:
+				plan.Leaders[i].PlannedMemory -= move.Bytes
+			case move.TargetID:
+				plan.Leaders[i].PlannedMemory += move.Bytes
+			}
+		}
+	}
+	r.Log.Info(fmt.Sprintf("Memory rebalance plan of %d leaders: %d moves", len(leaders), len(moves)))
+	return plan, moves, nil
+}
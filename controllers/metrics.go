@@ -0,0 +1,228 @@
+package controllers
+
+import (
+	"time"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/view"
+	"github.com/prometheus/client_golang/prometheus"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+/*
+	The metrics are served by the manager metrics endpoint (-metrics-addr), all of them are labelled by the
+	namespace and the name of the RedisCluster. For example, a cluster stuck in recovery can be alerted on by:
+	redis_operator_cluster_state{state="Recovering"} == 1 and on(namespace, cluster) redis_operator_reconcile_loops_since_healthy_cluster > 20
+*/
+
+const metricsNamespace = "redis_operator"
+
+var (
+	operatorStates = []RedisClusterState{NotExists, Reset, Ready, Recovering, Updating, Scale}
+
+	clusterViewStates = []view.ClusterState{view.ClusterCreate, view.ClusterFix, view.ClusterRebalance, view.ClusterOK}
+
+	nodeStates = []view.NodeState{
+		view.CreateNode, view.AddNode, view.ReplicateNode, view.SyncNode, view.FailoverNode, view.ReshardNode,
+		view.ReshardNodeKeepInMap, view.NewEmptyNode, view.DeleteNode, view.DeleteNodeKeepInMap, view.NodeOK,
+	}
+)
+
+var (
+	clusterStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cluster_state",
+		Help:      "The operator state of the cluster, 1 for the current state and 0 for the others.",
+	}, []string{"namespace", "cluster", "state"})
+
+	clusterViewStateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "cluster_view_state",
+		Help:      "The state of the cluster view, 1 for the current state and 0 for the others.",
+	}, []string{"namespace", "cluster", "state"})
+
+	reconcileLoopsSinceHealthyClusterGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "reconcile_loops_since_healthy_cluster",
+		Help:      "The number of reconcile loops since the cluster was last seen healthy.",
+	}, []string{"namespace", "cluster"})
+
+	healthyReconcileLoopsInRowGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "healthy_reconcile_loops_in_row",
+		Help:      "The number of healthy reconcile loops in a row.",
+	}, []string{"namespace", "cluster"})
+
+	nodesGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "nodes",
+		Help:      "The number of nodes in the cluster state view per node state.",
+	}, []string{"namespace", "cluster", "node_state"})
+
+	failoversCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "failovers_total",
+		Help:      "The number of failovers performed by the operator.",
+	}, []string{"namespace", "cluster"})
+
+	forgottenNodesCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "forgotten_nodes_total",
+		Help:      "The number of lost nodes forgotten by the operator.",
+	}, []string{"namespace", "cluster"})
+
+	reshardsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "reshards_total",
+		Help:      "The number of leader reshards performed by the operator.",
+	}, []string{"namespace", "cluster"})
+
+	recreatedPodsCounter = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: metricsNamespace,
+		Name:      "recreated_pods_total",
+		Help:      "The number of pods recreated by the operator for missing nodes.",
+	}, []string{"namespace", "cluster"})
+
+	handleStateDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: metricsNamespace,
+		Name:      "handle_state_duration_seconds",
+		Help:      "The duration of the handling of each operator state by the reconcile loop.",
+		Buckets:   []float64{0.1, 0.5, 1, 5, 15, 30, 60, 120, 300, 600, 1800},
+	}, []string{"namespace", "cluster", "state"})
+
+	nodeReplicationLagGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_replication_lag_bytes",
+		Help:      "The difference between the replication offset of the leader and the replication offset of the follower.",
+	}, []string{"namespace", "cluster", "node"})
+
+	nodeUsedMemoryGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: metricsNamespace,
+		Name:      "node_used_memory_bytes",
+		Help:      "The memory used by the node.",
+	}, []string{"namespace", "cluster", "node"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		clusterStateGauge,
+		clusterViewStateGauge,
+		reconcileLoopsSinceHealthyClusterGauge,
+		healthyReconcileLoopsInRowGauge,
+		nodesGauge,
+		failoversCounter,
+		forgottenNodesCounter,
+		reshardsCounter,
+		recreatedPodsCounter,
+		handleStateDuration,
+		nodeReplicationLagGauge,
+		nodeUsedMemoryGauge,
+	)
+}
+
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func (r *RedisClusterReconciler) observeHandleStateDuration(state RedisClusterState, start time.Time) {
+	handleStateDuration.WithLabelValues(r.key.Namespace, r.key.Name, string(state)).Observe(time.Since(start).Seconds())
+}
+
+// Updates the gauges of the cluster by the operator state, the state view and the nodes status that were saved by the reconcile loop
+func (r *RedisClusterReconciler) updateClusterMetrics(redisCluster *dbv1.RedisCluster) {
+	if r.planning() {
+		return
+	}
+	ns, name := r.key.Namespace, r.key.Name
+	for _, state := range operatorStates {
+		clusterStateGauge.WithLabelValues(ns, name, string(state)).Set(boolToFloat(string(state) == redisCluster.Status.ClusterState))
+	}
+	for _, state := range clusterViewStates {
+		clusterViewStateGauge.WithLabelValues(ns, name, string(state)).Set(boolToFloat(state == r.RedisClusterStateView.ClusterState))
+	}
+	reconcileLoopsSinceHealthyClusterGauge.WithLabelValues(ns, name).Set(float64(r.RedisClusterStateView.NumOfReconcileLoopsSinceHealthyCluster))
+	healthyReconcileLoopsInRowGauge.WithLabelValues(ns, name).Set(float64(r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow))
+
+	nodesPerState := make(map[view.NodeState]int)
+	for _, n := range r.RedisClusterStateView.Nodes {
+		nodesPerState[n.NodeState]++
+	}
+	for _, state := range nodeStates {
+		nodesGauge.WithLabelValues(ns, name, string(state)).Set(float64(nodesPerState[state]))
+	}
+
+	leaderOffsets := make(map[string]int64)
+	for _, n := range redisCluster.Status.Nodes {
+		if n.Role == "leader" {
+			leaderOffsets[n.LeaderName] = n.ReplicationOffset
+		}
+	}
+	for _, n := range redisCluster.Status.Nodes {
+		nodeUsedMemoryGauge.WithLabelValues(ns, name, n.Name).Set(float64(n.UsedMemory))
+		if leaderOffset, exists := leaderOffsets[n.LeaderName]; exists && n.Role == "follower" {
+			lag := leaderOffset - n.ReplicationOffset
+			if lag < 0 {
+				lag = 0
+			}
+			nodeReplicationLagGauge.WithLabelValues(ns, name, n.Name).Set(float64(lag))
+		} else {
+			nodeReplicationLagGauge.DeleteLabelValues(ns, name, n.Name)
+		}
+	}
+}
+
+// Removes the series of a cluster that is no longer managed by the operator
+func deleteClusterMetrics(key types.NamespacedName, nodeNames []string) {
+	ns, name := key.Namespace, key.Name
+	for _, state := range operatorStates {
+		clusterStateGauge.DeleteLabelValues(ns, name, string(state))
+		handleStateDuration.DeleteLabelValues(ns, name, string(state))
+	}
+	for _, state := range clusterViewStates {
+		clusterViewStateGauge.DeleteLabelValues(ns, name, string(state))
+	}
+	for _, state := range nodeStates {
+		nodesGauge.DeleteLabelValues(ns, name, string(state))
+	}
+	for _, counter := range []*prometheus.CounterVec{failoversCounter, forgottenNodesCounter, reshardsCounter, recreatedPodsCounter} {
+		counter.DeleteLabelValues(ns, name)
+	}
+	reconcileLoopsSinceHealthyClusterGauge.DeleteLabelValues(ns, name)
+	healthyReconcileLoopsInRowGauge.DeleteLabelValues(ns, name)
+	for _, nodeName := range nodeNames {
+		nodeUsedMemoryGauge.DeleteLabelValues(ns, name, nodeName)
+		nodeReplicationLagGauge.DeleteLabelValues(ns, name, nodeName)
+	}
+}
+
+func (r *RedisClusterReconciler) countFailover() {
+	if r.planning() {
+		return
+	}
+	failoversCounter.WithLabelValues(r.key.Namespace, r.key.Name).Inc()
+}
+
+func (r *RedisClusterReconciler) countForgottenNodes(count int) {
+	if r.planning() {
+		return
+	}
+	forgottenNodesCounter.WithLabelValues(r.key.Namespace, r.key.Name).Add(float64(count))
+}
+
+func (r *RedisClusterReconciler) countReshard() {
+	if r.planning() {
+		return
+	}
+	reshardsCounter.WithLabelValues(r.key.Namespace, r.key.Name).Inc()
+}
+
+func (r *RedisClusterReconciler) countRecreatedPod() {
+	if r.planning() {
+		return
+	}
+	recreatedPodsCounter.WithLabelValues(r.key.Namespace, r.key.Name).Inc()
+}
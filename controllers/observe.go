@@ -0,0 +1,103 @@
+package controllers
+
+import (
+	"fmt"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+/*
+	In observe-only mode the reconcile loops keep running without changing the clusters. The cluster view is
+	built, the health is checked and the status, the conditions, the metrics and the events are updated, while
+	the state handlers only build the plan of the actions the operator would have taken, see plan.go, which is
+	logged as "Would have done".
+	The redis-cli commands that change a cluster and the creation and deletion of its pods are skipped as well,
+	which covers the entry points of the HTTP API.
+	The mode is set for the whole operator by the ObserveOnly setter of the operator config, or for a single
+	cluster by the annotation:
+
+		db.payu.com/observe-only: "true"
+
+	It is used when existing clusters are onboarded and during incidents, to freeze the automation.
+*/
+
+const observeOnlyAnnotation = "db.payu.com/observe-only"
+
+// A cluster is observed only when the operator setter is set, or when the cluster is annotated
+func isObserveOnly(config *OperatorConfig, redisCluster *dbv1.RedisCluster) bool {
+	if config != nil && config.Setters.ObserveOnly {
+		return true
+	}
+	return redisCluster.Annotations[observeOnlyAnnotation] == "true"
+}
+
+// Switches the RedisCLI of the reconciler to the observe-only mode of the cluster when it changes
+func (r *RedisClusterReconciler) reloadObserveOnly(redisCluster *dbv1.RedisCluster) {
+	observeOnly := isObserveOnly(r.Config, redisCluster)
+	if observeOnly == r.RedisCLI.ObserveOnly {
+		return
+	}
+	r.RedisCLI = r.RedisCLI.WithObserveOnly(observeOnly)
+	if observeOnly {
+		r.Log.Info("[ObserveOnly] Observe-only mode is on, the actions of the operator will only be logged")
+		r.recordEvent(corev1.EventTypeWarning, eventObserveOnly, "Observe-only mode is on, the operator will not change the cluster")
+	} else {
+		r.Log.Info("Observe-only mode is off, the operator resumes acting on the cluster")
+		r.recordEvent(corev1.EventTypeNormal, eventObserveOnlyOff, "Observe-only mode is off, the operator resumes acting on the cluster")
+	}
+}
+
+// Returns true and logs the action that would have been taken when the reconciler is in observe-only mode
+func (r *RedisClusterReconciler) skipInObserveOnly(format string, args ...interface{}) bool {
+	if r.RedisCLI == nil || !r.RedisCLI.ObserveOnly {
+		return false
+	}
+	r.Log.Info("[ObserveOnly] Would have done: " + fmt.Sprintf(format, args...))
+	return true
+}
+
+// Replaces the state handlers in observe-only mode: checks the health of the cluster, updates its conditions
+// and logs the plan of the actions that the handlers would have taken
+func (r *RedisClusterReconciler) handleObserveOnly(redisCluster *dbv1.RedisCluster) error {
+	r.Log.Info("[ObserveOnly] Observing cluster...")
+	v, ok := r.NewRedisClusterView(redisCluster)
+	if !ok {
+		r.setUnavailable(redisCluster, reasonNonReachableNodes, "Some of the cluster nodes are not reachable")
+		return nil
+	}
+	r.updateSlotsCoveredCondition(redisCluster, v)
+	plan, err := r.buildPlan(redisCluster)
+	if err != nil {
+		return err
+	}
+	if len(plan.Steps) == 0 {
+		r.setAvailable(redisCluster)
+		r.lastObservedPlan = nil
+		return nil
+	}
+	setCondition(redisCluster, dbv1.ConditionDegraded, true, reasonObserveOnly,
+		fmt.Sprintf("The operator would have taken %d actions on the cluster, see the plan entry point", len(plan.Steps)))
+	for _, step := range plan.Steps {
+		r.Log.Info(fmt.Sprintf("[ObserveOnly] Would have done: %s", describePlanStep(step)))
+	}
+	if !samePlanSteps(plan.Steps, r.lastObservedPlan) {
+		r.recordEvent(corev1.EventTypeWarning, eventWouldHaveActed, "The operator would have taken %d actions, first: %s", len(plan.Steps), describePlanStep(plan.Steps[0]))
+		r.lastObservedPlan = plan.Steps
+	}
+	return nil
+}
+
+func describePlanStep(step PlanStep) string {
+	description := string(step.Action)
+	if step.Node != "" {
+		description += fmt.Sprintf(" [%s]", step.Node)
+	}
+	if step.Target != "" {
+		description += fmt.Sprintf(" -> [%s]", step.Target)
+	}
+	if step.Reason != "" {
+		description += ": " + step.Reason
+	}
+	return description
+}
@@ -1,54 +1,72 @@
 package controllers
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"net/http"
-	"os"
-	"sync"
 	"time"
 
-	rediscli "github.com/PayU/redis-operator/controllers/rediscli"
+	dbv1 "github.com/PayU/redis-operator/api/v1"
 	"github.com/PayU/redis-operator/controllers/redisclient"
 	"github.com/PayU/redis-operator/controllers/testlab"
 	view "github.com/PayU/redis-operator/controllers/view"
 	"github.com/labstack/echo/v4"
-	corev1 "k8s.io/api/core/v1"
+	"github.com/pkg/errors"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
+/**
+Resolves the managed redis cluster addressed by the ':namespace' and ':name' path params of the request,
+together with the reconciler that is dedicated to it.
+**/
+func (r *RedisClusterReconciler) clusterFromRequest(c echo.Context) (*RedisClusterReconciler, *dbv1.RedisCluster, error) {
+	key := types.NamespacedName{Namespace: c.Param("namespace"), Name: c.Param("name")}
+	cr, managed := r.lookupClusterReconciler(key)
+	if !managed {
+		r.Log.Info(fmt.Sprintf("[Warn] Entry point request for redis cluster [%s] that is not managed by the operator", key))
+		return nil, nil, echo.NewHTTPError(http.StatusNotFound, fmt.Sprintf("Redis cluster [%s] is not managed by the operator", key))
+	}
+	var redisCluster dbv1.RedisCluster
+	if err := r.Get(context.Background(), key, &redisCluster); err != nil {
+		cr.Log.Error(err, "Could not fetch redis cluster resource for entry point request")
+		return nil, nil, errors.Wrap(err, "Could not fetch redis cluster resource")
+	}
+	return cr, &redisCluster, nil
+}
+
 /**
 Gets cluster info in a form of cluster pods view
 **/
-func ClusterInfo(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusOK, "Could not get redis cluster info")
+func (r *RedisClusterReconciler) ClusterInfo(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
 	}
-	v, ok := reconciler.NewRedisClusterView(cluster)
+	v, ok := cr.NewRedisClusterView(redisCluster)
 	if !ok {
-		return c.String(http.StatusInternalServerError, "Could not retrieve redis cluster view")
+		return respondError(c, errors.New("Could not retrieve redis cluster view"))
 	}
-	for _, n := range v.Nodes {
-		n.Pod = corev1.Pod{}
-	}
-	data, err := json.Marshal(v)
-	if err != nil {
-		return c.String(http.StatusInternalServerError, "Could not get redis cluster info: "+err.Error())
-	}
-	return c.String(http.StatusOK, string(data))
+	return c.JSON(http.StatusOK, newClusterInfoResponse(redisCluster.Namespace, redisCluster.Name, v))
 }
 
 /**
 Get operator state and cluster state
 **/
-func ClusterState(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not get redis cluster state")
+func (r *RedisClusterReconciler) ClusterState(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
+	}
+	response := ClusterStateResponse{
+		Namespace:     redisCluster.Namespace,
+		Cluster:       redisCluster.Name,
+		OperatorState: redisCluster.Status.ClusterState,
 	}
-	operatorState := cluster.Status.ClusterState
-	clusterState := reconciler.RedisClusterStateView.ClusterState
-	return c.String(http.StatusOK, fmt.Sprintf("Operator state [%v], Cluster state [%v]", operatorState, clusterState))
+	if cr.RedisClusterStateView != nil {
+		response.ClusterState = string(cr.RedisClusterStateView.ClusterState)
+	}
+	return c.JSON(http.StatusOK, response)
 }
 
 /**
@@ -59,99 +77,135 @@ In the next reconcile loop, the operator will enter a RESET mode, which will lea
 3. Create new redis cluster pods according to the spec
 [WARN] This entry point is concidered sensitive, and is not allowed naturally. In order to enable it, the config param 'ExposeSensitiveEntryPoints' need to be set to 'true'.
 **/
-func DoResetCluster(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster reset action")
+func (r *RedisClusterReconciler) DoResetCluster(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
+	}
+	if cr.Config.Setters.ExposeSensitiveEntryPoints == false {
+		return respondError(c, errSensitiveEntryPoint)
 	}
-	if reconciler.Config.Setters.ExposeSensitiveEntryPoints == false {
-		return c.String(http.StatusUnauthorized, "Sensitive operation - Not allowed")
+	cr.Log.Info("[WARN] Sensitive entry point, on the way to pre-prod / prod environments, the access should be removed from router list")
+	cr.clusterMutex.Lock()
+	defer cr.clusterMutex.Unlock()
+	err = cr.patchStatus(redisCluster, func(status *dbv1.RedisClusterStatus) {
+		status.ClusterState = string(Reset)
+	})
+	if err != nil {
+		return respondError(c, errors.Wrap(err, "Could not set cluster state to reset mode"))
 	}
-	reconciler.Log.Info("[WARN] Sensitive entry point, on the way to pre-prod / prod environments, the access should be removed from router list")
-	cluster.Status.ClusterState = string(Reset)
-	reconciler.saveOperatorState(cluster)
-	return c.String(http.StatusOK, "Set cluster state to reset mode")
+	cr.requestReset = true
+	return respondMessage(c, "Set cluster state to reset mode")
 }
 
 /**
-Triggers the redis-cli command CLUSTER REBALANCE
+Starts a job that triggers the redis-cli command CLUSTER REBALANCE
 In case of failure, the cluster state will be set to ClusterFix, which will lead to a trigger of ClusterFix redis-cli command within the next reconcile loop
 **/
-func ClusterRebalance(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster rebalance action")
-	}
-	reconciler.saveClusterStateView(cluster)
-	v, ok := reconciler.NewRedisClusterView(cluster)
+func (r *RedisClusterReconciler) ClusterRebalance(c echo.Context) error {
+	cr, _, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
+	}
+	return r.startJob(c, "rebalance", func(job *Job) (interface{}, error) {
+		job.SetProgress(0, 3, "Waiting for the reconcile loop of the cluster")
+		return cr.runClusterJob(job, func(jr *RedisClusterReconciler, redisCluster *dbv1.RedisCluster) (interface{}, error) {
+			return jr.rebalanceCluster(job, redisCluster)
+		})
+	})
+}
+
+func (r *RedisClusterReconciler) rebalanceCluster(job *Job, redisCluster *dbv1.RedisCluster) (interface{}, error) {
+	job.SetProgress(0, 3, "Fetching cluster view")
+	v, ok := r.NewRedisClusterView(redisCluster)
 	if !ok {
-		return c.String(http.StatusInternalServerError, "Could not retrieve redis cluster view")
+		return nil, errors.New("Could not retrieve redis cluster view")
 	}
-	reconciler.removeSoloLeaders(v)
-	healthyServerName, found := reconciler.findHealthyLeader(v)
+	r.removeSoloLeaders(v)
+	healthyServerName, found := r.findHealthyLeader(v)
 	if !found {
-		return c.String(http.StatusOK, "Could not find healthy server to serve the rebalance request")
+		return nil, errors.New("Could not find healthy server to serve the rebalance request")
+	}
+	if job.Cancelled() {
+		return nil, nil
 	}
-	mutex := &sync.Mutex{}
-	mutex.Lock()
-	reconciler.RedisClusterStateView.ClusterState = view.ClusterRebalance
+	job.SetProgress(1, 3, "Waiting for all nodes to agree about slots configuration")
+	r.saveClusterViewState(redisCluster, view.ClusterRebalance)
 	healthyServerIp := v.Nodes[healthyServerName].Ip
-	reconciler.waitForAllNodesAgreeAboutSlotsConfiguration(v, nil)
-	_, _, err := reconciler.RedisCLI.ClusterRebalance(healthyServerIp, true)
+	r.waitForAllNodesAgreeAboutSlotsConfiguration(v, nil)
+	job.SetProgress(2, 3, "Rebalancing from "+healthyServerName)
+	rebalanced, stdout, err := r.rebalanceSlots(redisCluster, v, healthyServerIp)
 	if err != nil {
-		reconciler.RedisClusterStateView.ClusterState = view.ClusterFix
-		reconciler.Log.Error(err, "Could not perform cluster rebalance")
+		r.Log.Error(err, "Could not perform cluster rebalance")
+		r.saveClusterViewState(redisCluster, view.ClusterFix)
+	} else {
+		r.saveClusterViewState(redisCluster, view.ClusterOK)
 	}
-	reconciler.RedisClusterStateView.ClusterState = view.ClusterOK
-	mutex.Unlock()
-	reconciler.saveClusterStateView(cluster)
-	return c.String(http.StatusOK, "Cluster rebalance attempt executed")
+	job.SetProgress(3, 3, "Cluster rebalance attempt executed")
+	return commandJobResult{Node: healthyServerName, Succeeded: rebalanced, Output: stdout}, err
 }
 
 /**
-Triggers the redis-cli command CLUSTER FIX
+Starts a job that triggers the redis-cli command CLUSTER FIX
 In case of failure, the cluster state will remain ClusterFix, which will lead to a triger of additional attempt to fix in the next reconcile loop
 In case of success, the cluster state will be set to ClusterReblance, which will lead to a trigger of redic-cli command CLUSTER REBALANCE in the next reconcile loop
 **/
-func ClusterFix(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster fix action")
-	}
-	reconciler.saveClusterStateView(cluster)
-	v, ok := reconciler.NewRedisClusterView(cluster)
+func (r *RedisClusterReconciler) ClusterFix(c echo.Context) error {
+	cr, _, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
+	}
+	return r.startJob(c, "fix", func(job *Job) (interface{}, error) {
+		job.SetProgress(0, 2, "Waiting for the reconcile loop of the cluster")
+		return cr.runClusterJob(job, func(jr *RedisClusterReconciler, redisCluster *dbv1.RedisCluster) (interface{}, error) {
+			return jr.fixCluster(job, redisCluster)
+		})
+	})
+}
+
+func (r *RedisClusterReconciler) fixCluster(job *Job, redisCluster *dbv1.RedisCluster) (interface{}, error) {
+	job.SetProgress(0, 2, "Fetching cluster view")
+	v, ok := r.NewRedisClusterView(redisCluster)
 	if !ok {
-		return c.String(http.StatusInternalServerError, "Could not retrieve redis cluster view")
+		return nil, errors.New("Could not retrieve redis cluster view")
 	}
-	healthyServerName, found := reconciler.findHealthyLeader(v)
+	healthyServerName, found := r.findHealthyLeader(v)
 	if !found {
-		return c.String(http.StatusInternalServerError, "Could not find healthy server to serve the fix request")
+		return nil, errors.New("Could not find healthy server to serve the fix request")
+	}
+	if job.Cancelled() {
+		return nil, nil
 	}
+	job.SetProgress(1, 2, "Fixing from "+healthyServerName)
 	healthyServerIp := v.Nodes[healthyServerName].Ip
-	mutex := &sync.Mutex{}
-	mutex.Lock()
-	reconciler.RedisClusterStateView.ClusterState = view.ClusterFix
-	_, _, err := reconciler.RedisCLI.ClusterFix(healthyServerIp)
+	r.saveClusterViewState(redisCluster, view.ClusterFix)
+	fixed, stdout, err := r.RedisCLI.ClusterFix(healthyServerIp)
 	if err != nil {
-		reconciler.Log.Error(err, "Could not perform cluster fix")
+		r.Log.Error(err, "Could not perform cluster fix")
+		return commandJobResult{Node: healthyServerName, Succeeded: fixed, Output: stdout}, err
 	}
-	reconciler.RedisClusterStateView.ClusterState = view.ClusterRebalance
-	reconciler.Log.Info("It is recommended to run rebalance after each cluster fix, changing state to [ClusterRebalance]")
-	mutex.Unlock()
-	reconciler.saveClusterStateView(cluster)
-	return c.String(http.StatusOK, "Cluster fix attempt executed")
+	r.Log.Info("It is recommended to run rebalance after each cluster fix, changing state to [ClusterRebalance]")
+	r.saveClusterViewState(redisCluster, view.ClusterRebalance)
+	job.SetProgress(2, 2, "Cluster fix attempt executed")
+	return commandJobResult{Node: healthyServerName, Succeeded: fixed, Output: stdout}, err
 }
 
 /**
 Triggers an atomic flow of forgetting all redis cluster lost nodes: non-responsive nodes that still exists in the tables of some of the responsive ones.
 **/
-func ForgetLostNodes(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster forget lost nodes action")
+func (r *RedisClusterReconciler) ForgetLostNodes(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
 	}
-	v, ok := reconciler.NewRedisClusterView(cluster)
+	cr.clusterMutex.Lock()
+	defer cr.clusterMutex.Unlock()
+	v, ok := cr.NewRedisClusterView(redisCluster)
 	if !ok {
-		return c.String(http.StatusInternalServerError, "Could not retrieve redis cluster view")
+		return respondError(c, errors.New("Could not retrieve redis cluster view"))
 	}
-	reconciler.forgetLostNodes(cluster, v)
-	return c.String(http.StatusOK, "Finish execution for attempt to forget lost nodes")
+	cr.forgetLostNodes(redisCluster, v)
+	return respondMessage(c, "Finish execution for attempt to forget lost nodes")
 }
 
 /**
@@ -160,16 +214,17 @@ Can be useful in case of event that prevents from manager to proceed with it's c
 [WARN] Direct reconcile trigger might run the loop without enqueue it again causing the operator to not scheduling another run within requested time.
 In case of need run eforced reconcile manually several times until recovery is complete, and restart manager when cluster is stable.
 **/
-func ForceReconcile(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster reconcile action")
+func (r *RedisClusterReconciler) ForceReconcile(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
 	}
-	reconciler.saveClusterStateView(cluster)
-	_, err := reconciler.Reconcile(ctrl.Request{types.NamespacedName{Name: cluster.Name, Namespace: cluster.Namespace}})
+	_, err = r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Name: redisCluster.Name, Namespace: redisCluster.Namespace}})
 	if err != nil {
-		reconciler.Log.Error(err, "Could not perform reconcile trigger")
+		cr.Log.Error(err, "Could not perform reconcile trigger")
+		return respondError(c, errors.Wrap(err, "Could not perform reconcile trigger"))
 	}
-	return c.String(http.StatusOK, "Force Reconcile request triggered, direct reconcile trigger might run the loop without enqueue it again causing the operator to not scheduling another run within requested time. "+
+	return respondMessage(c, "Force Reconcile request triggered, direct reconcile trigger might run the loop without enqueue it again causing the operator to not scheduling another run within requested time. "+
 		"\nIn case of need run eforced reconcile manually several times until recovery is complete, and restart manager when cluster is stable")
 }
 
@@ -178,69 +233,88 @@ Sets the value of parameter 'IsUpToDate' to false for each one of the reported n
 In the next healthy reconcile loop, upgrade process will take part: each marked node will failover, forgotten, removed, deleted, re created and marked again with 'IsUpToDate' value of true
 This process takes part moderately according to a suggested heuristic that relays on cluster size, and separates upgrade steps of leaders from upgrade steps of followers.
 **/
-func UpgradeCluster(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster upgarde action")
+func (r *RedisClusterReconciler) UpgradeCluster(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
 	}
-	for _, n := range reconciler.RedisClusterStateView.Nodes {
+	cr.clusterMutex.Lock()
+	defer cr.clusterMutex.Unlock()
+	err = cr.patchStatus(redisCluster, func(status *dbv1.RedisClusterStatus) {
+		for i := range status.Nodes {
+			status.Nodes[i].IsUpToDate = false
+		}
+	})
+	if err != nil {
+		return respondError(c, errors.Wrap(err, "Could not mark the nodes for upgrade"))
+	}
+	for _, n := range cr.RedisClusterStateView.Nodes {
 		n.IsUpToDate = false
 	}
-	requestUpgrade = false
-	reconciler.saveClusterStateView(cluster)
-	return c.String(http.StatusOK, "Cluster upgarde request triggered")
+	cr.requestUpgrade = false
+	return respondMessage(c, "Cluster upgarde request triggered")
 }
 
 /**
-Triggers a flow of testing routine that induces events with different severities in order to challenge the operator by simulating possible dissaster scenarios.
+Starts a job that triggers a flow of testing routine that induces events with different severities in order to challenge the operator by simulating possible dissaster scenarios.
 **/
-func ClusterTest(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster test")
+func (r *RedisClusterReconciler) ClusterTest(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
 	}
-	return c.String(http.StatusOK, setAndStartTestLab(&c, false))
+	return r.startJob(c, "test", func(job *Job) (interface{}, error) {
+		return testJobResult{Report: cr.setAndStartTestLab(job, redisCluster, false)}, nil
+	})
 }
 
 /**
-Triggers a flow of testing routine that induces events with different severities in order to challenge the operator by simulating possible dissaster scenarios.
+Starts a job that triggers a flow of testing routine that induces events with different severities in order to challenge the operator by simulating possible dissaster scenarios.
 The flow creates mock data and sends it to the redis cluster nodes, later attempts to report estimated possible data loss that might be expirienced during each dissaster scenario.
 [WARN] This entry point is concidered sensitive, and is not allowed naturally. In order to enable it, the config param 'ExposeSensitiveEntryPoints' need to be set to 'true'.
 **/
-func ClusterTestWithData(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster test")
+func (r *RedisClusterReconciler) ClusterTestWithData(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
 	}
-	if reconciler.Config.Setters.ExposeSensitiveEntryPoints == false {
-		return c.String(http.StatusUnauthorized, "Sensitive operation - Not allowed")
+	if cr.Config.Setters.ExposeSensitiveEntryPoints == false {
+		return respondError(c, errSensitiveEntryPoint)
 	}
-	reconciler.Log.Info("[WARN] Sensitive entry point, on the way to pre-prod / prod environments, the access should be removed from router list")
-	return c.String(http.StatusOK, setAndStartTestLab(&c, true))
+	cr.Log.Info("[WARN] Sensitive entry point, on the way to pre-prod / prod environments, the access should be removed from router list")
+	return r.startJob(c, "testData", func(job *Job) (interface{}, error) {
+		return testJobResult{Report: cr.setAndStartTestLab(job, redisCluster, true)}, nil
+	})
 }
 
 /**
-Populates the redis cluster nodes with mock data for debug purposes.
+Starts a job that populates the redis cluster nodes with mock data for debug purposes.
 [WARN] This entry point is concidered sensitive, and is not allowed naturally. In order to enable it, the config param 'ExposeSensitiveEntryPoints' need to be set to 'true'.
 **/
-func PopulateClusterWithMockData(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster popluate data")
+func (r *RedisClusterReconciler) PopulateClusterWithMockData(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
 	}
-	if reconciler.Config.Setters.ExposeSensitiveEntryPoints == false {
-		return c.String(http.StatusUnauthorized, "Sensitive operation - Not allowed")
+	if cr.Config.Setters.ExposeSensitiveEntryPoints == false {
+		return respondError(c, errSensitiveEntryPoint)
 	}
-	v, ok := reconciler.NewRedisClusterView(cluster)
+	cr.Log.Info("[WARN] Sensitive entry point, on the way to pre-prod / prod environments, the access should be removed from router list")
+	return r.startJob(c, "populateMockData", func(job *Job) (interface{}, error) {
+		return cr.populateMockData(job, redisCluster)
+	})
+}
+
+func (r *RedisClusterReconciler) populateMockData(job *Job, redisCluster *dbv1.RedisCluster) (interface{}, error) {
+	v, ok := r.NewRedisClusterView(redisCluster)
 	if !ok || v == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster populate data")
-	}
-	reconciler.Log.Info("[WARN] Sensitive entry point, on the way to pre-prod / prod environments, the access should be removed from router list")
-	redisCli := rediscli.NewRedisCLI(&reconciler.Log)
-	user := os.Getenv("REDIS_USERNAME")
-	if user != "" {
-		redisCli.Auth = &rediscli.RedisAuth{
-			User: user,
-		}
+		return nil, errors.New("Could not retrieve redis cluster view")
+	}
+	clusterCli, err := redisclient.GetRedisClusterClient(v, r.RedisCLI)
+	if err != nil {
+		return nil, err
 	}
-	clusterCli := redisclient.GetRedisClusterClient(v, redisCli)
-	printUsedMemoryForAllNodes(v)
+	r.printUsedMemoryForAllNodes(v)
 
 	total := 5000000
 	init := 0
@@ -249,7 +323,8 @@ func PopulateClusterWithMockData(c echo.Context) error {
 	updateClientPer := 15000
 	loopsBeforeClientUpdate := 0
 
-	for i := init; i < init+total; i++ {
+	i := init
+	for ; i < init+total && !job.Cancelled(); i++ {
 		key := "key" + fmt.Sprintf("%v", i)
 		val := "val" + fmt.Sprintf("%v", i)
 		err := clusterCli.Set(key, val, 3)
@@ -258,69 +333,73 @@ func PopulateClusterWithMockData(c echo.Context) error {
 		}
 		loopsBeforeClientUpdate++
 		if loopsBeforeClientUpdate == updateClientPer {
-			v, ok := reconciler.NewRedisClusterView(cluster)
+			v, ok := r.NewRedisClusterView(redisCluster)
 			if ok && v != nil {
-				clusterCli = redisclient.GetRedisClusterClient(v, redisCli)
+				if refreshed, err := redisclient.GetRedisClusterClient(v, r.RedisCLI); err == nil {
+					clusterCli = refreshed
+				}
 				loopsBeforeClientUpdate = 0
 			}
+			job.SetProgress(int64(i-init+1), int64(total), fmt.Sprintf("%d keys written", sw))
 		}
 	}
-	printUsedMemoryForAllNodes(v)
-	return c.String(http.StatusOK, "Cluster populated with data")
+	job.SetProgress(int64(i-init), int64(total), fmt.Sprintf("%d keys written", sw))
+	r.printUsedMemoryForAllNodes(v)
+	return populateJobResult{Attempted: i - init, Written: sw}, nil
 }
 
 /**
 Flushes all the data of redis cluster nodes.
 [WARN] This entry point is concidered sensitive, and is not allowed naturally. In order to enable it, the config param 'ExposeSensitiveEntryPoints' need to be set to 'true'.
 **/
-func FlushClusterData(c echo.Context) error {
-	if reconciler == nil || cluster == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster flush data")
+func (r *RedisClusterReconciler) FlushClusterData(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
 	}
-	if reconciler.Config.Setters.ExposeSensitiveEntryPoints == false {
-		return c.String(http.StatusUnauthorized, "Sensitive operation - Not allowed")
+	if cr.Config.Setters.ExposeSensitiveEntryPoints == false {
+		return respondError(c, errSensitiveEntryPoint)
 	}
-	var cl *redisclient.RedisClusterClient = nil
-	v, ok := reconciler.NewRedisClusterView(cluster)
+	v, ok := cr.NewRedisClusterView(redisCluster)
 	if !ok || v == nil {
-		return c.String(http.StatusInternalServerError, "Could not perform cluster flush data")
+		return respondError(c, errors.New("Could not retrieve redis cluster view"))
+	}
+	cr.Log.Info("[WARN] Sensitive entry point, on the way to pre-prod / prod environments, the access should be removed from router list")
+	cl, err := redisclient.GetRedisClusterClient(v, cr.RedisCLI)
+	if err != nil {
+		return respondError(c, err)
 	}
-	reconciler.Log.Info("[WARN] Sensitive entry point, on the way to pre-prod / prod environments, the access should be removed from router list")
-	cl = redisclient.GetRedisClusterClient(v, reconciler.RedisCLI)
 	cl.FlushAllData()
 	time.Sleep(10 * time.Second)
-	printUsedMemoryForAllNodes(v)
-	return c.String(http.StatusOK, "Cluster data flushed")
+	cr.printUsedMemoryForAllNodes(v)
+	return respondMessage(c, "Cluster data flushed")
 }
 
-func setAndStartTestLab(c *echo.Context, data bool) string {
-	cli := rediscli.NewRedisCLI(&reconciler.Log)
-	user := os.Getenv("REDIS_USERNAME")
-	if user != "" {
-		cli.Auth = &rediscli.RedisAuth{
-			User: user,
-		}
-	}
+func (r *RedisClusterReconciler) setAndStartTestLab(job *Job, redisCluster *dbv1.RedisCluster, data bool) string {
 	t := &testlab.TestLab{
-		Client:             reconciler.Client,
-		RedisCLI:           cli,
-		Cluster:            cluster,
+		Client:             r.Client,
+		RedisCLI:           r.RedisCLI,
+		Cluster:            redisCluster,
 		RedisClusterClient: nil,
-		Log:                reconciler.Log,
+		Log:                r.Log,
 		Report:             "",
+		Context:            job.Context(),
+		Progress: func(done int, total int, message string) {
+			job.SetProgress(int64(done), int64(total), message)
+		},
 	}
-	t.RunTest(&reconciler.RedisClusterStateView.Nodes, data)
+	t.RunTest(&r.RedisClusterStateView.Nodes, data)
 	return t.Report
 }
 
-func printUsedMemoryForAllNodes(v *view.RedisClusterView) {
+func (r *RedisClusterReconciler) printUsedMemoryForAllNodes(v *view.RedisClusterView) {
 	for _, n := range v.Nodes {
-		printUsedMemory(n.Name, n.Ip)
+		r.printUsedMemory(n.Name, n.Ip)
 	}
 }
 
-func printUsedMemory(name string, ip string) {
-	info, _, err := reconciler.RedisCLI.Info(ip)
+func (r *RedisClusterReconciler) printUsedMemory(name string, ip string) {
+	info, _, err := r.RedisCLI.Info(ip)
 	if err != nil || info == nil {
 		return
 	}
@@ -0,0 +1,320 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/rediscli"
+	"github.com/PayU/redis-operator/controllers/view"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+	With spec.persistence, every logical node of the cluster (redis-node-N-M) has its own persistent volume claim,
+	'redis-data-<node name>', which is mounted at /data and is the working directory of redis. It keeps the RDB
+	snapshots and the append only file of the node, by spec.persistence.mode, and the nodes.conf file that holds
+	the cluster node ID. When the pod of a node is lost and recreated, the claim is attached again and the node
+	rejoins the cluster with its ID and its data, the operator does not forget the ID of a node that is expected
+	to come back this way. When all the pods of the cluster are lost, the pods of the nodes are created again on
+	their claims and meet each other at their new addresses, instead of resetting the cluster.
+	A pod that is deleted to be created again, when it is not reachable or could not be created or become ready,
+	keeps its claim. The claim is deleted with the pod when the operator removes the node from the cluster, such as
+	on scale down or rolling update, since the node starts over with a new ID, and all the claims are deleted by an
+	explicit reset of the cluster. The claims are owned by the RedisCluster, a claim that exists but
+	is not owned by it is not attached, it was kept by a previous cluster of the same name.
+*/
+
+// +kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
+
+const (
+	redisDataVolumeName = "redis-data"
+	redisDataMountPath  = "/data"
+)
+
+var rdbSaveParams = []string{"900 1", "300 10", "60 10000"}
+
+func nodeVolumeClaimName(nodeName string) string {
+	return redisDataVolumeName + "-" + nodeName
+}
+
+// Mounts the claim of the node into the redis container and starts redis with its working directory on it
+func (r *RedisClusterReconciler) addRedisPersistence(redisCluster *dbv1.RedisCluster, spec *corev1.PodSpec, nodeName string) {
+	persistence := redisCluster.Spec.Persistence
+	if persistence == nil {
+		return
+	}
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: redisDataVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: nodeVolumeClaimName(nodeName)},
+		},
+	})
+	for i := range spec.Containers {
+		container := &spec.Containers[i]
+		if container.Name != dbv1.RedisContainerName {
+			continue
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      redisDataVolumeName,
+			MountPath: redisDataMountPath,
+		})
+		if len(container.Command) == 0 && len(container.Args) == 0 {
+			container.Args = []string{"redis-server", redisConfigPath(container)}
+		}
+		container.Args = append(container.Args, "--dir", redisDataMountPath, "--cluster-config-file", "nodes.conf")
+		if persistence.Mode != dbv1.PersistenceAOF {
+			for _, params := range rdbSaveParams {
+				container.Args = append(container.Args, "--save", params)
+			}
+		}
+		if persistence.Mode == dbv1.PersistenceAOF || persistence.Mode == dbv1.PersistenceRDBAndAOF {
+			container.Args = append(container.Args, "--appendonly", "yes", "--appendfsync", "everysec")
+		}
+	}
+}
+
+func (r *RedisClusterReconciler) makeNodeVolumeClaim(redisCluster *dbv1.RedisCluster, nodeName string) (corev1.PersistentVolumeClaim, error) {
+	persistence := redisCluster.Spec.Persistence
+	pvc := corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      nodeVolumeClaimName(nodeName),
+			Namespace: redisCluster.Namespace,
+			Labels:    map[string]string{"redis-cluster": redisCluster.Name, "node-name": nodeName},
+		},
+		Spec: corev1.PersistentVolumeClaimSpec{
+			AccessModes:      []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+			StorageClassName: persistence.StorageClassName,
+			Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceStorage: persistence.Size},
+			},
+		},
+	}
+	err := ctrl.SetControllerReference(redisCluster, &pvc, r.Scheme)
+	return pvc, err
+}
+
+// Creates the claim of the node if it does not exist yet, an existing claim is attached again to the new pod
+func (r *RedisClusterReconciler) createNodeVolume(redisCluster *dbv1.RedisCluster, nodeName string) error {
+	if redisCluster.Spec.Persistence == nil {
+		return nil
+	}
+	key := client.ObjectKey{Namespace: redisCluster.Namespace, Name: nodeVolumeClaimName(nodeName)}
+	var existing corev1.PersistentVolumeClaim
+	if pollErr := wait.PollImmediate(r.Config.Times.PodDeleteCheckInterval, r.Config.Times.PodDeleteCheckTimeout, func() (bool, error) {
+		if err := r.Get(context.Background(), key, &existing); err != nil {
+			if apierrors.IsNotFound(err) {
+				return true, nil
+			}
+			return false, err
+		}
+		// A claim that is being deleted can not be attached, its deletion completes once its previous pod is gone
+		return existing.DeletionTimestamp == nil, nil
+	}); pollErr != nil {
+		return errors.Wrapf(pollErr, "Failed to wait for the deletion of volume claim [%s]", key.Name)
+	}
+	if existing.Name != "" && existing.DeletionTimestamp == nil {
+		if !metav1.IsControlledBy(&existing, redisCluster) {
+			return errors.Errorf("Volume claim [%s] exists and is not owned by the cluster, it should be deleted to create node [%s]", key.Name, nodeName)
+		}
+		r.Log.Info(fmt.Sprintf("Attaching the existing volume claim [%s] to node [%s]", key.Name, nodeName))
+		return nil
+	}
+	pvc, err := r.makeNodeVolumeClaim(redisCluster, nodeName)
+	if err != nil {
+		return err
+	}
+	if err := r.Create(context.Background(), &pvc); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+	r.Log.Info(fmt.Sprintf("Created volume claim [%s]", pvc.Name))
+	return nil
+}
+
+func podDataClaimName(pod corev1.Pod) (string, bool) {
+	for _, volume := range pod.Spec.Volumes {
+		if volume.Name == redisDataVolumeName && volume.PersistentVolumeClaim != nil {
+			return volume.PersistentVolumeClaim.ClaimName, true
+		}
+	}
+	return "", false
+}
+
+func hasPersistentVolume(pod corev1.Pod) bool {
+	_, found := podDataClaimName(pod)
+	return found
+}
+
+// Deletes the claim mounted by the pod, if it has one. The claim is removed once the pod is gone.
+func (r *RedisClusterReconciler) deletePodVolume(pod corev1.Pod) error {
+	if claimName, found := podDataClaimName(pod); found {
+		pvc := corev1.PersistentVolumeClaim{}
+		pvc.Name, pvc.Namespace = claimName, pod.Namespace
+		if err := r.Delete(context.Background(), &pvc); client.IgnoreNotFound(err) != nil {
+			r.Log.Error(err, "Could not delete volume claim: "+pvc.Name)
+			r.recordEvent(corev1.EventTypeWarning, eventDeleteVolumeFailed, "Could not delete volume claim [%s]: %v", pvc.Name, err)
+			return err
+		}
+		r.recordEvent(corev1.EventTypeNormal, eventDeletedVolume, "Deleted volume claim [%s]", pvc.Name)
+	}
+	return nil
+}
+
+func (r *RedisClusterReconciler) getNodeVolumes(redisCluster *dbv1.RedisCluster) ([]corev1.PersistentVolumeClaim, error) {
+	var pvcs corev1.PersistentVolumeClaimList
+	err := r.List(context.Background(), &pvcs, client.InNamespace(redisCluster.Namespace), client.MatchingLabels{"redis-cluster": redisCluster.Name})
+	if err != nil {
+		return nil, err
+	}
+	owned := []corev1.PersistentVolumeClaim{}
+	for _, pvc := range pvcs.Items {
+		if metav1.IsControlledBy(&pvc, redisCluster) {
+			owned = append(owned, pvc)
+		}
+	}
+	return owned, nil
+}
+
+// Deletes all the claims owned by the cluster, including the ones of nodes that have no pod
+func (r *RedisClusterReconciler) deleteAllNodeVolumes(redisCluster *dbv1.RedisCluster) error {
+	if r.skipInObserveOnly("delete the volume claims of the cluster") {
+		return rediscli.ErrObserveOnly
+	}
+	pvcs, err := r.getNodeVolumes(redisCluster)
+	if err != nil {
+		return err
+	}
+	for i := range pvcs {
+		if err := r.Delete(context.Background(), &pvcs[i]); client.IgnoreNotFound(err) != nil {
+			r.recordEvent(corev1.EventTypeWarning, eventDeleteVolumeFailed, "Could not delete volume claim [%s]: %v", pvcs[i].Name, err)
+			return err
+		}
+		r.recordEvent(corev1.EventTypeNormal, eventDeletedVolume, "Deleted volume claim [%s]", pvcs[i].Name)
+	}
+	return nil
+}
+
+// Removes the owner reference of the claims owned by the cluster, so they are kept when the cluster is deleted
+func (r *RedisClusterReconciler) orphanNodeVolumes(redisCluster *dbv1.RedisCluster) (int, error) {
+	pvcs, err := r.getNodeVolumes(redisCluster)
+	if err != nil {
+		return 0, err
+	}
+	for i := range pvcs {
+		pvc := &pvcs[i]
+		refs := []metav1.OwnerReference{}
+		for _, ref := range pvc.OwnerReferences {
+			if ref.UID != redisCluster.UID {
+				refs = append(refs, ref)
+			}
+		}
+		pvc.OwnerReferences = refs
+		if err := r.Update(context.Background(), pvc); err != nil {
+			return i, err
+		}
+		r.Log.Info(fmt.Sprintf("Volume claim [%s] is kept", pvc.Name))
+	}
+	return len(pvcs), nil
+}
+
+// Returns the cluster node IDs of the nodes that lost their pod and will come back with the same ID,
+// since their claim still exists
+func (r *RedisClusterReconciler) returningNodeIDs(redisCluster *dbv1.RedisCluster, v *view.RedisClusterView) map[string]bool {
+	returning := map[string]bool{}
+	if redisCluster.Spec.Persistence == nil {
+		return returning
+	}
+	pvcs, err := r.getNodeVolumes(redisCluster)
+	if err != nil {
+		r.Log.Info(fmt.Sprintf("[Warn] Could not list the volume claims of the cluster: %v", err))
+		return returning
+	}
+	claims := map[string]bool{}
+	for _, pvc := range pvcs {
+		if pvc.DeletionTimestamp == nil {
+			claims[pvc.Name] = true
+		}
+	}
+	for _, n := range redisCluster.Status.Nodes {
+		if n.NodeID == "" || !claims[nodeVolumeClaimName(n.Name)] {
+			continue
+		}
+		if node, exists := v.Nodes[n.Name]; exists && node != nil {
+			continue
+		}
+		if s, inMap := r.RedisClusterStateView.Nodes[n.Name]; !inMap || s.NodeState == view.DeleteNode || s.NodeState == view.DeleteNodeKeepInMap {
+			continue
+		}
+		returning[n.NodeID] = true
+	}
+	return returning
+}
+
+// Returns the nodes of the state map that have a claim to be created again on
+func (r *RedisClusterReconciler) restorableNodes(redisCluster *dbv1.RedisCluster) []*view.NodeStateView {
+	if redisCluster.Spec.Persistence == nil || r.RedisClusterStateView == nil {
+		return nil
+	}
+	pvcs, err := r.getNodeVolumes(redisCluster)
+	if err != nil {
+		r.Log.Info(fmt.Sprintf("[Warn] Could not list the volume claims of the cluster: %v", err))
+		return nil
+	}
+	claims := map[string]bool{}
+	for _, pvc := range pvcs {
+		if pvc.DeletionTimestamp == nil {
+			claims[pvc.Name] = true
+		}
+	}
+	nodes := []*view.NodeStateView{}
+	for _, n := range r.RedisClusterStateView.Nodes {
+		if n.NodeState == view.DeleteNode || n.NodeState == view.DeleteNodeKeepInMap || !claims[nodeVolumeClaimName(n.Name)] {
+			continue
+		}
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
+
+// Creates the pods of the nodes again on their claims after all the pods of the cluster were lost. The nodes come
+// back with their data and their node IDs, and meet each other at their new addresses.
+func (r *RedisClusterReconciler) restoreNodesFromVolumes(redisCluster *dbv1.RedisCluster, nodes []*view.NodeStateView) error {
+	createOpts := []client.CreateOption{client.FieldOwner("redis-operator-controller")}
+	var pods []corev1.Pod
+	for _, n := range nodes {
+		pod, err := r.makeAndCreateRedisPod(redisCluster, n, createOpts)
+		if err != nil {
+			return err
+		}
+		pods = append(pods, pod)
+	}
+	pods, err := r.waitForPodNetworkInterface(pods...)
+	if err != nil {
+		return err
+	}
+	var addresses []string
+	for _, pod := range pods {
+		readyPod, err := r.waitForRedisPod(pod)
+		if err != nil {
+			return err
+		}
+		addresses = append(addresses, view.NodeAddress(readyPod))
+	}
+	for _, address := range addresses[1:] {
+		if _, err := r.RedisCLI.ClusterMeet(addresses[0], address, r.RedisCLI.Port); err != nil {
+			return err
+		}
+	}
+	r.recordEvent(corev1.EventTypeNormal, eventNodesRestored, "%d nodes were created again on their volume claims", len(pods))
+	return nil
+}
@@ -0,0 +1,140 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/view"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newTestPersistentCluster(mode dbv1.PersistenceMode) *dbv1.RedisCluster {
+	return &dbv1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "dev-rdc", Namespace: "default", UID: "dev-rdc-uid"},
+		Spec: dbv1.RedisClusterSpec{
+			Persistence: &dbv1.RedisClusterPersistence{Size: resource.MustParse("1Gi"), Mode: mode},
+		},
+	}
+}
+
+func TestAddRedisPersistence(test *testing.T) {
+	base := []string{"redis-server", defaultRedisConfig, "--dir", "/data", "--cluster-config-file", "nodes.conf"}
+	rdb := []string{"--save", "900 1", "--save", "300 10", "--save", "60 10000"}
+	aof := []string{"--appendonly", "yes", "--appendfsync", "everysec"}
+	cases := map[dbv1.PersistenceMode][]string{
+		dbv1.PersistenceRDB:       append(append([]string{}, base...), rdb...),
+		dbv1.PersistenceAOF:       append(append([]string{}, base...), aof...),
+		dbv1.PersistenceRDBAndAOF: append(append(append([]string{}, base...), rdb...), aof...),
+	}
+	r := newTestReconciler(test)
+	for mode, expected := range cases {
+		spec := corev1.PodSpec{Containers: []corev1.Container{{Name: dbv1.RedisContainerName}, {Name: "exporter"}}}
+		r.addRedisPersistence(newTestPersistentCluster(mode), &spec, "redis-node-0-1")
+		if args := spec.Containers[0].Args; !reflect.DeepEqual(args, expected) {
+			test.Errorf("Expected the redis args of mode %s to be %v, got %v", mode, expected, args)
+		}
+		if len(spec.Containers[1].Args) != 0 || len(spec.Containers[1].VolumeMounts) != 0 {
+			test.Errorf("Expected the other containers to be kept as they are, got %+v", spec.Containers[1])
+		}
+		if claimName, found := podDataClaimName(corev1.Pod{Spec: spec}); !found || claimName != "redis-data-redis-node-0-1" {
+			test.Errorf("Expected the pod to mount the claim of its node, got [%s]", claimName)
+		}
+	}
+
+	spec := corev1.PodSpec{Containers: []corev1.Container{{Name: dbv1.RedisContainerName}}}
+	r.addRedisPersistence(&dbv1.RedisCluster{}, &spec, "redis-node-0")
+	if len(spec.Volumes) != 0 || len(spec.Containers[0].Args) != 0 {
+		test.Errorf("Expected no volume without spec.persistence, got %+v", spec)
+	}
+}
+
+func TestCreateNodeVolume(test *testing.T) {
+	redisCluster := newTestPersistentCluster(dbv1.PersistenceRDB)
+	r := newTestReconciler(test)
+	r.Config = &DefaultRedisOperatorConfig(r.Log).Config
+	existing, err := r.makeNodeVolumeClaim(redisCluster, "redis-node-0")
+	if err != nil {
+		test.Fatal(err)
+	}
+	existing.Spec.Resources.Requests[corev1.ResourceStorage] = resource.MustParse("5Gi")
+	kept := corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: nodeVolumeClaimName("redis-node-1"), Namespace: "default"}}
+	for _, pvc := range []corev1.PersistentVolumeClaim{existing, kept} {
+		if err := r.Create(context.Background(), pvc.DeepCopy()); err != nil {
+			test.Fatal(err)
+		}
+	}
+
+	if err := r.createNodeVolume(redisCluster, "redis-node-0"); err != nil {
+		test.Errorf("Expected the claim of the node to be attached again, got %v", err)
+	}
+	var attached corev1.PersistentVolumeClaim
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: existing.Name}, &attached); err != nil {
+		test.Fatal(err)
+	}
+	if size := attached.Spec.Resources.Requests[corev1.ResourceStorage]; size.String() != "5Gi" {
+		test.Errorf("Expected the existing claim to be kept as it is, got size %s", size.String())
+	}
+
+	if err := r.createNodeVolume(redisCluster, "redis-node-1"); err == nil || !strings.Contains(err.Error(), "not owned by the cluster") {
+		test.Errorf("Expected the claim of another cluster to be rejected, got %v", err)
+	}
+
+	if err := r.createNodeVolume(redisCluster, "redis-node-2"); err != nil {
+		test.Fatalf("Unexpected error %v", err)
+	}
+	var created corev1.PersistentVolumeClaim
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: "default", Name: nodeVolumeClaimName("redis-node-2")}, &created); err != nil {
+		test.Fatalf("Expected the missing claim to be created, got %v", err)
+	}
+	if !metav1.IsControlledBy(&created, redisCluster) || created.Labels["node-name"] != "redis-node-2" {
+		test.Errorf("Expected the created claim to be owned by the cluster, got %+v", created.ObjectMeta)
+	}
+}
+
+func TestReturningNodeIDs(test *testing.T) {
+	redisCluster := newTestPersistentCluster(dbv1.PersistenceRDB)
+	redisCluster.Status.Nodes = []dbv1.RedisNodeStatus{
+		{Name: "redis-node-0", NodeID: "id-0"},
+		{Name: "redis-node-0-1", NodeID: "id-0-1"},
+		{Name: "redis-node-1", NodeID: "id-1"},
+		{Name: "redis-node-1-1", NodeID: "id-1-1"},
+		{Name: "redis-node-2", NodeID: "id-2"},
+	}
+	r := newTestReconciler(test)
+	// redis-node-2 has no claim
+	for _, name := range []string{"redis-node-0", "redis-node-0-1", "redis-node-1", "redis-node-1-1"} {
+		pvc, err := r.makeNodeVolumeClaim(redisCluster, name)
+		if err != nil {
+			test.Fatal(err)
+		}
+		if err := r.Create(context.Background(), &pvc); err != nil {
+			test.Fatal(err)
+		}
+	}
+	r.RedisClusterStateView = &view.RedisClusterStateView{Nodes: map[string]*view.NodeStateView{
+		"redis-node-0":   {Name: "redis-node-0", LeaderName: "redis-node-0", NodeState: view.NodeOK},
+		"redis-node-0-1": {Name: "redis-node-0-1", LeaderName: "redis-node-0", NodeState: view.CreateNode},
+		"redis-node-1":   {Name: "redis-node-1", LeaderName: "redis-node-1", NodeState: view.NodeOK},
+		"redis-node-1-1": {Name: "redis-node-1-1", LeaderName: "redis-node-1", NodeState: view.DeleteNode},
+		"redis-node-2":   {Name: "redis-node-2", LeaderName: "redis-node-2", NodeState: view.NodeOK},
+	}}
+	// redis-node-0 is still running
+	v := &view.RedisClusterView{Nodes: map[string]*view.NodeView{"redis-node-0": {Name: "redis-node-0"}}}
+
+	returning := r.returningNodeIDs(redisCluster, v)
+	expected := map[string]bool{"id-0-1": true, "id-1": true}
+	if !reflect.DeepEqual(returning, expected) {
+		test.Errorf("Expected the returning nodes %v, got %v", expected, returning)
+	}
+
+	redisCluster.Spec.Persistence = nil
+	if returning := r.returningNodeIDs(redisCluster, v); len(returning) != 0 {
+		test.Errorf("Expected no returning nodes without spec.persistence, got %v", returning)
+	}
+}
@@ -0,0 +1,326 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/view"
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+/*
+	The plan is the ordered list of the actions that the next reconcile loops would take on the cluster: the pods
+	to create and delete, the redis-cli commands that change the cluster and the deferred disruptive flows.
+	It is built by the state handlers of the reconciler themselves, run on a copy of the cluster by a reconciler
+	whose client and RedisCLI record the changes instead of making them: the planned changes succeed, the waits
+	for them to take effect return at once and the read-only commands are sent to the cluster as usual.
+	The handlers are run for up to planStateLoops operator states, as long as a state changes without planning an action.
+	GET /cluster/<namespace>/<name>/plan returns the plan for the current spec, the 'leaderCount' and
+	'leaderFollowersCount' query params replace the ones of the spec to review the plan of a change before it is applied.
+	The flows that follow the creation of a pod read its node, which does not exist yet, so the plan ends with the
+	creation of the pod, the reconciler decides the next steps from the cluster it finds.
+	In observe-only mode the plan replaces the state handlers, see observe.go.
+*/
+
+// The number of operator states the state handlers are run for when the cluster is planned
+const planStateLoops = 3
+
+type PlanAction string
+
+// The actions of the redis-cli commands are named by the commands of rediscli
+const (
+	PlanCreateCluster  PlanAction = "CreateCluster"
+	PlanAddNode        PlanAction = "AddNode"
+	PlanRemoveNode     PlanAction = "RemoveNode"
+	PlanForgetNode     PlanAction = "ForgetNode"
+	PlanFailover       PlanAction = "Failover"
+	PlanMeetNode       PlanAction = "MeetNode"
+	PlanResetNode      PlanAction = "ResetNode"
+	PlanRebalance      PlanAction = "Rebalance"
+	PlanReshard        PlanAction = "Reshard"
+	PlanFlushAll       PlanAction = "FlushAll"
+	PlanReplicate      PlanAction = "Replicate"
+	PlanLoadACL        PlanAction = "LoadACL"
+	PlanFix            PlanAction = "Fix"
+	PlanCreatePod      PlanAction = "CreatePod"
+	PlanDeletePod      PlanAction = "DeletePod"
+	PlanCreateVolume   PlanAction = "CreateVolume"
+	PlanDeleteVolume   PlanAction = "DeleteVolume"
+	PlanSetLeaderCount PlanAction = "SetLeaderCount"
+	PlanDefer          PlanAction = "Defer"
+)
+
+type PlanStep struct {
+	// The operator state whose handler planned the step
+	State   string     `json:"state"`
+	Action  PlanAction `json:"action"`
+	Node    string     `json:"node,omitempty"`
+	Target  string     `json:"target,omitempty"`
+	Reason  string     `json:"reason,omitempty"`
+	Details string     `json:"details,omitempty"`
+}
+
+type ClusterPlan struct {
+	Namespace            string     `json:"namespace"`
+	Cluster              string     `json:"cluster"`
+	OperatorState        string     `json:"operatorState"`
+	ClusterState         string     `json:"clusterState"`
+	LeaderCount          int        `json:"leaderCount"`
+	LeaderFollowersCount int        `json:"leaderFollowersCount"`
+	Steps                []PlanStep `json:"steps"`
+}
+
+// Records the steps of the plan, the handlers run some of their flows in goroutines
+type planRecorder struct {
+	mutex  sync.Mutex
+	state  RedisClusterState
+	reason string
+	// The pod names by node address, to name the nodes of the redis-cli commands
+	names map[string]string
+	steps []PlanStep
+}
+
+func newPlanRecorder(pods []corev1.Pod) *planRecorder {
+	p := &planRecorder{names: map[string]string{}}
+	for _, pod := range pods {
+		if address := view.NodeAddress(pod); address != "" {
+			p.names[address] = pod.Name
+		}
+	}
+	return p
+}
+
+func (p *planRecorder) add(action PlanAction, node string, target string, details string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.steps = append(p.steps, PlanStep{State: string(p.state), Action: action, Node: node, Target: target, Reason: p.reason, Details: details})
+}
+
+// Records a command of the RedisCLI, it is the CommandPlanner of the planning reconciler
+func (p *planRecorder) addCommand(command string, nodeAddress string, description string) {
+	p.mutex.Lock()
+	var nodes []string
+	for _, address := range strings.Split(nodeAddress, ",") {
+		if name, known := p.names[address]; known {
+			address = name
+		}
+		nodes = append(nodes, address)
+	}
+	p.mutex.Unlock()
+	p.add(PlanAction(command), strings.Join(nodes, ","), "", description)
+}
+
+func (p *planRecorder) setReason(reason string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.reason = reason
+}
+
+// The pods created by the plan have no address yet, they are addressed by their names
+func (p *planRecorder) addressPlannedPods(pods []corev1.Pod) []corev1.Pod {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	addressed := make([]corev1.Pod, 0, len(pods))
+	for _, pod := range pods {
+		if view.NodeAddress(pod) == "" {
+			pod.Status.PodIP = pod.Name
+		}
+		p.names[view.NodeAddress(pod)] = pod.Name
+		addressed = append(addressed, pod)
+	}
+	return addressed
+}
+
+// The client of the planning reconciler, the reads are passed to the cluster and the writes are recorded
+type planClient struct {
+	client.Client
+	recorder *planRecorder
+}
+
+func (c *planClient) Create(ctx context.Context, obj runtime.Object, opts ...client.CreateOption) error {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		c.recorder.add(PlanCreatePod, o.Name, o.Labels["leader-name"], fmt.Sprintf("create pod [%s] of leader [%s]", o.Name, o.Labels["leader-name"]))
+	case *corev1.PersistentVolumeClaim:
+		c.recorder.add(PlanCreateVolume, o.Name, "", fmt.Sprintf("create volume claim [%s]", o.Name))
+	}
+	return nil
+}
+
+func (c *planClient) Delete(ctx context.Context, obj runtime.Object, opts ...client.DeleteOption) error {
+	switch o := obj.(type) {
+	case *corev1.Pod:
+		c.recorder.add(PlanDeletePod, o.Name, "", fmt.Sprintf("delete pod [%s]", o.Name))
+	case *corev1.PersistentVolumeClaim:
+		c.recorder.add(PlanDeleteVolume, o.Name, "", fmt.Sprintf("delete volume claim [%s]", o.Name))
+	}
+	return nil
+}
+
+func (c *planClient) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return nil
+}
+
+func (c *planClient) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if redisCluster, isCluster := obj.(*dbv1.RedisCluster); isCluster {
+		leaderCount := strconv.Itoa(redisCluster.Spec.LeaderCount)
+		c.recorder.add(PlanSetLeaderCount, "", leaderCount, fmt.Sprintf("set the leader count of the spec to %s", leaderCount))
+	}
+	return nil
+}
+
+func (c *planClient) DeleteAllOf(ctx context.Context, obj runtime.Object, opts ...client.DeleteAllOfOption) error {
+	return nil
+}
+
+func (c *planClient) Status() client.StatusWriter {
+	return planStatusWriter{}
+}
+
+// The status of the planned cluster is kept on its copy only
+type planStatusWriter struct{}
+
+func (planStatusWriter) Update(ctx context.Context, obj runtime.Object, opts ...client.UpdateOption) error {
+	return nil
+}
+
+func (planStatusWriter) Patch(ctx context.Context, obj runtime.Object, patch client.Patch, opts ...client.PatchOption) error {
+	return nil
+}
+
+// Returns true when the reconciler plans the cluster instead of changing it
+func (r *RedisClusterReconciler) planning() bool {
+	return r.planner != nil
+}
+
+// Sets the reason of the steps that the flow plans next, when the reconciler plans the cluster
+func (r *RedisClusterReconciler) planReason(format string, args ...interface{}) {
+	if r.planning() {
+		r.planner.setReason(fmt.Sprintf(format, args...))
+	}
+}
+
+// Returns a reconciler that runs the state handlers of the cluster by the recorder
+func (r *RedisClusterReconciler) planningReconciler(redisCluster *dbv1.RedisCluster, recorder *planRecorder) *RedisClusterReconciler {
+	return &RedisClusterReconciler{
+		Client:                &planClient{Client: r.Client, recorder: recorder},
+		Log:                   log.NullLogger{},
+		Scheme:                r.Scheme,
+		RedisCLI:              r.RedisCLI.WithObserveOnly(false).WithPlanner(recorder.addCommand),
+		Config:                r.Config,
+		RedisClusterStateView: &view.RedisClusterStateView{Name: clusterStateMapName(redisCluster.Name)},
+		key:                   r.key,
+		planner:               recorder,
+		lastZoneRelocation:    r.lastZoneRelocation,
+		cpuSamples:            r.cpuSamples,
+	}
+}
+
+// Builds the plan of the cluster by its state handlers without changing it, the spec of the given cluster can be
+// modified by the caller. The caller holds the cluster lock, so the reconcile loop does not change the reconciler meanwhile.
+func (r *RedisClusterReconciler) buildPlan(redisCluster *dbv1.RedisCluster) (*ClusterPlan, error) {
+	planned := redisCluster.DeepCopy()
+	pods, err := r.getRedisClusterPods(planned)
+	if err != nil {
+		return nil, errors.Wrap(err, "Could not fetch the pods of the cluster")
+	}
+	recorder := newPlanRecorder(pods)
+	pr := r.planningReconciler(planned, recorder)
+	pr.State = RedisClusterState(planned.Status.ClusterState)
+	if pr.State == "" {
+		pr.State = NotExists
+	}
+	if pr.State != NotExists && pr.State != Reset {
+		if planned.Status.StateViewVersion == 0 {
+			return nil, errors.New("The state view of the cluster was not migrated to its status yet")
+		}
+		if err := pr.setClusterStateView(planned); err != nil {
+			return nil, err
+		}
+	}
+	plan := &ClusterPlan{
+		Namespace:            redisCluster.Namespace,
+		Cluster:              redisCluster.Name,
+		OperatorState:        string(pr.State),
+		ClusterState:         redisCluster.Status.ClusterViewState,
+		LeaderCount:          redisCluster.Spec.LeaderCount,
+		LeaderFollowersCount: redisCluster.Spec.LeaderFollowersCount,
+	}
+	for i := 0; i < planStateLoops; i++ {
+		recorder.state = pr.State
+		recorder.setReason("")
+		if err := pr.handleState(planned); err != nil {
+			return nil, errors.Wrapf(err, "Could not plan the [%s] state", pr.State)
+		}
+		next := RedisClusterState(planned.Status.ClusterState)
+		if len(recorder.steps) > 0 || next == pr.State || next == "" {
+			break
+		}
+		pr.State = next
+	}
+	plan.Steps = append([]PlanStep{}, recorder.steps...)
+	return plan, nil
+}
+
+// Returns true if the plans have the same steps, in any order, since some of the flows plan their steps in goroutines
+func samePlanSteps(steps []PlanStep, otherSteps []PlanStep) bool {
+	if len(steps) != len(otherSteps) {
+		return false
+	}
+	sorted, otherSorted := sortedPlanSteps(steps), sortedPlanSteps(otherSteps)
+	for i := range sorted {
+		if sorted[i] != otherSorted[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func sortedPlanSteps(steps []PlanStep) []PlanStep {
+	sorted := append([]PlanStep{}, steps...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return describePlanStep(sorted[i])+sorted[i].Details < describePlanStep(sorted[j])+sorted[j].Details
+	})
+	return sorted
+}
+
+/**
+Gets the plan of the actions the reconciler would take on the cluster, without carrying them out.
+The 'leaderCount' and 'leaderFollowersCount' query params plan a change of the spec.
+**/
+func (r *RedisClusterReconciler) ClusterPlan(c echo.Context) error {
+	cr, redisCluster, err := r.clusterFromRequest(c)
+	if err != nil {
+		return respondError(c, err)
+	}
+	for param, field := range map[string]*int{
+		"leaderCount":          &redisCluster.Spec.LeaderCount,
+		"leaderFollowersCount": &redisCluster.Spec.LeaderFollowersCount,
+	} {
+		if value := c.QueryParam(param); value != "" {
+			count, convErr := strconv.Atoi(value)
+			if convErr != nil || count < 0 {
+				return respondError(c, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Invalid %s [%s]", param, value)))
+			}
+			*field = count
+		}
+	}
+	cr.clusterMutex.Lock()
+	plan, err := cr.buildPlan(redisCluster)
+	cr.clusterMutex.Unlock()
+	if err != nil {
+		return respondError(c, err)
+	}
+	return c.JSON(http.StatusOK, plan)
+}
@@ -0,0 +1,83 @@
+package controllers
+
+import (
+	"context"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/rediscli"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestPlanNewCluster(test *testing.T) {
+	redisCluster := &dbv1.RedisCluster{
+		ObjectMeta: metav1.ObjectMeta{Name: "dev-rdc", Namespace: "default"},
+		Spec:       dbv1.RedisClusterSpec{LeaderCount: 2, LeaderFollowersCount: 1},
+	}
+	stalePod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "redis-node-5", Namespace: "default", Labels: map[string]string{"redis-cluster": "dev-rdc"}}}
+	r := newTestReconciler(test, redisCluster.DeepCopy(), stalePod)
+	r.Config = &DefaultRedisOperatorConfig(r.Log).Config
+	r.RedisCLI = rediscli.NewRedisCLI(&r.Log)
+
+	plan, err := r.buildPlan(redisCluster)
+	if err != nil {
+		test.Fatalf("Unexpected error %v", err)
+	}
+	var actions []string
+	for _, step := range plan.Steps {
+		actions = append(actions, string(step.Action))
+		if step.State != string(NotExists) || !strings.HasPrefix(step.Reason, "The cluster is created from scratch") {
+			test.Errorf("Expected the steps to be planned by the initializing cluster flow, got %+v", step)
+		}
+	}
+	expected := []string{"DeletePod", "CreatePod", "CreatePod", "FlushAll", "ResetNode", "FlushAll", "ResetNode", "CreateCluster"}
+	if !reflect.DeepEqual(actions, expected) {
+		test.Errorf("Expected the actions %v, got %v", expected, actions)
+	}
+	if plan.Steps[0].Node != "redis-node-5" {
+		test.Errorf("Expected the stale pod to be deleted first, got %+v", plan.Steps[0])
+	}
+	leaders := strings.Split(plan.Steps[len(plan.Steps)-1].Node, ",")
+	sort.Strings(leaders)
+	if !reflect.DeepEqual(leaders, []string{"redis-node-0", "redis-node-1"}) {
+		test.Errorf("Expected the cluster to be created of the new leaders, got %v", leaders)
+	}
+
+	var pods corev1.PodList
+	if err := r.List(context.Background(), &pods); err != nil {
+		test.Fatal(err)
+	}
+	if len(pods.Items) != 1 || pods.Items[0].Name != "redis-node-5" {
+		test.Errorf("Expected the pods to be kept as they are, got %d pods", len(pods.Items))
+	}
+	var saved dbv1.RedisCluster
+	if err := r.Get(context.Background(), client.ObjectKey{Name: "dev-rdc", Namespace: "default"}, &saved); err != nil {
+		test.Fatal(err)
+	}
+	if saved.Status.ClusterState != "" || redisCluster.Status.ClusterState != "" {
+		test.Errorf("Expected the cluster to be kept as it is, got state [%s]", saved.Status.ClusterState)
+	}
+}
+
+func TestSamePlanSteps(test *testing.T) {
+	failover := PlanStep{State: "Ready", Action: PlanFailover, Node: "redis-node-0-1"}
+	deletePod := PlanStep{State: "Ready", Action: PlanDeletePod, Node: "redis-node-0"}
+	steps := []PlanStep{failover, deletePod}
+	cases := map[string][]PlanStep{
+		"same order":   {failover, deletePod},
+		"other order":  {deletePod, failover},
+		"missing step": {failover},
+		"other step":   {failover, failover},
+	}
+	for name, otherSteps := range cases {
+		expected := name == "same order" || name == "other order"
+		if same := samePlanSteps(steps, otherSteps); same != expected {
+			test.Errorf("Expected the plans with %s to be the same: %v, got %v", name, expected, same)
+		}
+	}
+}
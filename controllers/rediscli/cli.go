@@ -4,8 +4,11 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"net"
+	"os"
 	"os/exec"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -21,12 +24,20 @@ const (
 )
 
 type RedisAuth struct {
-	User string
+	User     string
+	Password string
+}
+
+// The files of the CA certificate and of the client certificate and key that are used to connect to TLS enabled nodes
+type RedisTLS struct {
+	CACertFile string
+	CertFile   string
+	KeyFile    string
 }
 
 type CommandHandler interface {
-	buildCommand(routingPort string, args []string, auth *RedisAuth, opt ...string) ([]string, map[string]string)
-	executeCommand(pipedArgs []string, args []string, useBash bool, multipFactorForTimeout ...float64) (string, string, error)
+	buildCommand(routingPort string, args []string, auth *RedisAuth, tls *RedisTLS, opt ...string) ([]string, map[string]string)
+	executeCommand(ctx context.Context, auth *RedisAuth, pipedArgs []string, args []string, useBash bool, multipFactorForTimeout ...float64) (string, string, error)
 	buildRedisInfoModel(stdoutInfo string) (*RedisInfo, error)
 	buildRedisClusterInfoModel(stdoutInfo string) (*RedisClusterInfo, error)
 }
@@ -36,19 +47,119 @@ type RunTimeCommandHandler struct{}
 type RedisCLI struct {
 	Log     logr.Logger
 	Auth    *RedisAuth
+	TLS     *RedisTLS
 	Port    string
 	Handler CommandHandler
+	// The commands that change the cluster are skipped and logged when set
+	ObserveOnly bool
+	// Bounds the commands, the timeout of every command is added to it
+	ctx context.Context
+	// Records the commands that change the cluster instead of running them
+	planner CommandPlanner
+}
+
+// Records a command that changes the cluster: its name, the address of the node it is sent to and its description
+type CommandPlanner func(command string, nodeAddress string, description string)
+
+// Returned by the commands that change the cluster when they are skipped in observe-only mode
+var ErrObserveOnly = errors.New("Skipped in observe-only mode")
+
+// NewRedisAuthFromEnv returns the credentials set by the REDIS_USERNAME and REDISCLI_AUTH environment variables,
+// or nil if none of them is set
+func NewRedisAuthFromEnv() *RedisAuth {
+	user := os.Getenv("REDIS_USERNAME")
+	password := os.Getenv("REDISCLI_AUTH")
+	if user == "" && password == "" {
+		return nil
+	}
+	return &RedisAuth{
+		User:     user,
+		Password: password,
+	}
+}
+
+// The password is passed to redis-cli by the REDISCLI_AUTH environment variable, which is inherited from the
+// operator process when no password is set
+func (a *RedisAuth) password() string {
+	if a != nil && a.Password != "" {
+		return a.Password
+	}
+	return os.Getenv("REDISCLI_AUTH")
 }
 
 func NewRedisCLI(log *logr.Logger) *RedisCLI {
 	return &RedisCLI{
 		Log:     *log,
 		Auth:    nil,
+		TLS:     nil,
 		Port:    REDIS_DEFAULT_PORT,
 		Handler: &RunTimeCommandHandler{},
 	}
 }
 
+// WithContext returns a copy of the RedisCLI whose commands are cancelled when the context is done
+func (r *RedisCLI) WithContext(ctx context.Context) *RedisCLI {
+	cli := *r
+	cli.ctx = ctx
+	return &cli
+}
+
+func (r *RedisCLI) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+// WithAuth returns a copy of the RedisCLI that connects to the nodes with the given credentials
+func (r *RedisCLI) WithAuth(auth *RedisAuth) *RedisCLI {
+	cli := *r
+	cli.Auth = auth
+	return &cli
+}
+
+// WithTLS returns a copy of the RedisCLI that connects to the nodes over TLS, or without TLS if nil is given
+func (r *RedisCLI) WithTLS(tls *RedisTLS) *RedisCLI {
+	cli := *r
+	cli.TLS = tls
+	return &cli
+}
+
+// WithObserveOnly returns a copy of the RedisCLI that skips the commands that change the cluster when observeOnly is set
+func (r *RedisCLI) WithObserveOnly(observeOnly bool) *RedisCLI {
+	cli := *r
+	cli.ObserveOnly = observeOnly
+	return &cli
+}
+
+// WithPlanner returns a copy of the RedisCLI that reports the commands that change the cluster to the planner
+// instead of running them, the planned commands succeed
+func (r *RedisCLI) WithPlanner(planner CommandPlanner) *RedisCLI {
+	cli := *r
+	cli.planner = planner
+	return &cli
+}
+
+func (r *RedisCLI) skipMutation(command string, nodeAddress string, format string, args ...interface{}) bool {
+	if r.planner != nil {
+		r.planner(command, nodeAddress, fmt.Sprintf(format, args...))
+		return true
+	}
+	if !r.ObserveOnly {
+		return false
+	}
+	r.Log.Info("[ObserveOnly] Would have done: " + fmt.Sprintf(format, args...))
+	return true
+}
+
+// The error of a skipped command, nil when the command was planned
+func (r *RedisCLI) skippedError() error {
+	if r.planner != nil {
+		return nil
+	}
+	return ErrObserveOnly
+}
+
 func (h *RunTimeCommandHandler) buildRedisInfoModel(stdoutInfo string) (*RedisInfo, error) {
 	return NewRedisInfo(stdoutInfo)
 }
@@ -57,14 +168,18 @@ func (h *RunTimeCommandHandler) buildRedisClusterInfoModel(stdoutInfo string) (*
 	return NewRedisClusterInfo(stdoutInfo)
 }
 
-func (h *RunTimeCommandHandler) buildCommand(routingPort string, args []string, auth *RedisAuth, opt ...string) ([]string, map[string]string) {
+func (h *RunTimeCommandHandler) buildCommand(routingPort string, args []string, auth *RedisAuth, tls *RedisTLS, opt ...string) ([]string, map[string]string) {
 
 	routingPort, opt = routingPortDecider(routingPort, opt)
 	if len(opt) > 0 {
 		opt = trimCommandSpaces(opt)
 		args = append(args, opt...)
 	}
-	if auth != nil {
+	if tls != nil {
+		args = append([]string{"--tls", "--cacert", tls.CACertFile, "--cert", tls.CertFile, "--key", tls.KeyFile}, args...)
+	}
+	// Without a user the password authenticates the default user, by the single argument AUTH
+	if auth != nil && auth.User != "" {
 		args = append([]string{"--user", auth.User}, args...)
 	}
 	args = append([]string{"-p", routingPort}, args...)
@@ -72,7 +187,7 @@ func (h *RunTimeCommandHandler) buildCommand(routingPort string, args []string,
 	return args, argListToArgMap(args)
 }
 
-func (h *RunTimeCommandHandler) executeCommand(pipedArgs []string, args []string, useBash bool, multipFactorForTimeout ...float64) (string, string, error) {
+func (h *RunTimeCommandHandler) executeCommand(ctx context.Context, auth *RedisAuth, pipedArgs []string, args []string, useBash bool, multipFactorForTimeout ...float64) (string, string, error) {
 
 	var stdout, stderr bytes.Buffer
 
@@ -80,7 +195,7 @@ func (h *RunTimeCommandHandler) executeCommand(pipedArgs []string, args []string
 	if len(multipFactorForTimeout) > 0 {
 		multipFactor = multipFactorForTimeout[0]
 	}
-	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(multipFactor)*defaultRedisCliTimeout)
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(multipFactor)*defaultRedisCliTimeout)
 	defer cancel()
 
 	var cmd *exec.Cmd
@@ -100,6 +215,9 @@ func (h *RunTimeCommandHandler) executeCommand(pipedArgs []string, args []string
 	}else{
 		cmd = exec.CommandContext(ctx, "redis-cli", args...)
 	}
+	if auth != nil && auth.Password != "" {
+		cmd.Env = append(os.Environ(), "REDISCLI_AUTH="+auth.Password)
+	}
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
@@ -218,15 +336,22 @@ func routingPortDecider(cliDefaultPort string, opt []string) (string, []string)
 	return port, opt
 }
 
-// Receives: address in a format of <ip>:<port> or <ip>: or <ip>
-// Returns: full address in a format of <ip>:<port>
-//          if port was provided, the exact address will be returned. otherwise the returned address will be <ip>:<default cli port>
+// Receives: address in a format of <host>:<port> or <host>: or <host>, the host is a host name, an IPv4 address
+//           or an IPv6 address, which is enclosed in brackets when it is followed by a port
+// Returns: full address in a format of <host>:<port>, or [<host>]:<port> for IPv6 addresses
+//          if port was provided, the exact address will be returned. otherwise the returned address will be <host>:<default cli port>
 func addressPortDecider(address string, cliDefaultPort string) string {
-	addr := strings.Split(address, ":")
-	if len(addr) == 1 || (len(addr) == 2 && addr[1] == "") {
-		return addr[0] + ":" + cliDefaultPort
+	if host := strings.Trim(address, "[]"); net.ParseIP(host) != nil {
+		return net.JoinHostPort(host, cliDefaultPort)
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return net.JoinHostPort(address, cliDefaultPort)
+	}
+	if port == "" {
+		port = cliDefaultPort
 	}
-	return address
+	return net.JoinHostPort(host, port)
 }
 
 func addressesPortDecider(addresses []string, cliDefaultPort string) []string {
@@ -237,15 +362,44 @@ func addressesPortDecider(addresses []string, cliDefaultPort string) []string {
 	return updatedAddresses
 }
 
+// CLUSTER MEET accepts IP addresses only, the host names of the nodes that join the cluster are resolved first
+func resolvedAddressPortDecider(address string, cliDefaultPort string) string {
+	host, port, _ := net.SplitHostPort(addressPortDecider(address, cliDefaultPort))
+	return net.JoinHostPort(resolveHost(host), port)
+}
+
+func resolvedAddressesPortDecider(addresses []string, cliDefaultPort string) []string {
+	var updatedAddresses []string
+	for _, addr := range addresses {
+		updatedAddresses = append(updatedAddresses, resolvedAddressPortDecider(addr, cliDefaultPort))
+	}
+	return updatedAddresses
+}
+
+// Returns the IP address of the host, or the host itself when it is an IP address or can not be resolved
+func resolveHost(host string) string {
+	if net.ParseIP(host) != nil {
+		return host
+	}
+	ips, err := net.LookupHost(host)
+	if err != nil || len(ips) == 0 {
+		return host
+	}
+	return ips[0]
+}
+
 // End of Helpers
 
 // ClusterCreate uses the '--cluster create' option on redis-cli to create a cluster using a list of nodes
 func (r *RedisCLI) ClusterCreate(leadersAddresses []string, opt ...string) (string, error) {
-	fullAddresses := addressesPortDecider(leadersAddresses, r.Port)
+	if r.skipMutation("CreateCluster", strings.Join(leadersAddresses, ","), "cluster create of %v", leadersAddresses) {
+		return "", r.skippedError()
+	}
+	fullAddresses := resolvedAddressesPortDecider(leadersAddresses, r.Port)
 	args := append([]string{"--cluster", "create"}, fullAddresses...)
 	args = append(args, "--cluster-yes") // this will run the command non-interactively
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return stdout, errors.Errorf("Failed to execute cluster create (%v): %s | %s | %v", fullAddresses, stdout, stderr, err)
 	}
@@ -254,8 +408,8 @@ func (r *RedisCLI) ClusterCreate(leadersAddresses []string, opt ...string) (stri
 
 func (r *RedisCLI) ClusterCheck(nodeAddr string, opt ...string) (string, error) {
 	args := []string{"--cluster", "check", addressPortDecider(nodeAddr, r.Port)}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return stdout, errors.Errorf("Cluster check result: (%s): %s | %s | %v", nodeAddr, stdout, stderr, err)
 	}
@@ -263,14 +417,17 @@ func (r *RedisCLI) ClusterCheck(nodeAddr string, opt ...string) (string, error)
 }
 
 // AddFollower uses the '--cluster add-node' option on redis-cli to add a node to the cluster
-// newNodeAddr: Address of the follower that will join the cluster in a format of <ip>:<port> or <ip>: or <ip>
-// existingNodeAddr: IP of a node in the cluster in a format of <ip>:<port> or <ip>: or <ip>
+// newNodeAddr: Address of the follower that will join the cluster in a format of <host>:<port> or <host>: or <host>
+// existingNodeAddr: Address of a node in the cluster in a format of <host>:<port> or <host>: or <host>
 // leaderID: 	Redis ID of the leader that the new follower will replicate
 // In case port won't bw provided as part of the given addresses, cli default port will be added automatically to the address
 func (r *RedisCLI) AddFollower(newNodeAddr string, existingNodeAddr string, leaderID string, opt ...string) (string, error) {
-	args := []string{"--cluster", "add-node", addressPortDecider(newNodeAddr, r.Port), addressPortDecider(existingNodeAddr, r.Port), "--cluster-slave", "--cluster-master-id", leaderID}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	if r.skipMutation("AddNode", newNodeAddr, "add follower [%s] of leader [%s] by [%s]", newNodeAddr, leaderID, existingNodeAddr) {
+		return "", r.skippedError()
+	}
+	args := []string{"--cluster", "add-node", resolvedAddressPortDecider(newNodeAddr, r.Port), resolvedAddressPortDecider(existingNodeAddr, r.Port), "--cluster-slave", "--cluster-master-id", leaderID}
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return stdout, errors.Errorf("Failed to execute cluster add node (%s, %s, %s): %s | %s | %v", newNodeAddr, existingNodeAddr, leaderID, stdout, stderr, err)
 	}
@@ -278,14 +435,17 @@ func (r *RedisCLI) AddFollower(newNodeAddr string, existingNodeAddr string, lead
 }
 
 // AddLeader uses the '--cluster add-node' option on redis-cli to add a node to the cluster
-// newNodeAddr: Address of the follower that will join the cluster in a format of <ip>:<port> or <ip>: or <ip>
-// existingNodeAddr: IP of a node in the cluster in a format of <ip>:<port> or <ip>: or <ip>
+// newNodeAddr: Address of the follower that will join the cluster in a format of <host>:<port> or <host>: or <host>
+// existingNodeAddr: Address of a node in the cluster in a format of <host>:<port> or <host>: or <host>
 // leaderID: 	Redis ID of the leader that the new follower will replicate
 // In case port won't bw provided as part of the given addresses, cli default port will be added automatically to the address
 func (r *RedisCLI) AddLeader(newNodeAddr string, existingNodeAddr string, opt ...string) (string, error) {
-	args := []string{"--cluster", "add-node", addressPortDecider(newNodeAddr, r.Port), addressPortDecider(existingNodeAddr, r.Port)}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false, 2)
+	if r.skipMutation("AddNode", newNodeAddr, "add leader [%s] by [%s]", newNodeAddr, existingNodeAddr) {
+		return "", r.skippedError()
+	}
+	args := []string{"--cluster", "add-node", resolvedAddressPortDecider(newNodeAddr, r.Port), resolvedAddressPortDecider(existingNodeAddr, r.Port)}
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false, 2)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return stdout, errors.Errorf("Failed to execute cluster add node (%s, %s): %s | %s | %v", newNodeAddr, existingNodeAddr, stdout, stderr, err)
 	}
@@ -296,9 +456,12 @@ func (r *RedisCLI) AddLeader(newNodeAddr string, existingNodeAddr string, opt ..
 // nodeIP: any node of the cluster
 // nodeID: node that needs to be removed
 func (r *RedisCLI) DelNode(nodeIP string, nodeID string, opt ...string) (string, error) {
+	if r.skipMutation("RemoveNode", nodeIP, "delete node [%s] by [%s]", nodeID, nodeIP) {
+		return "", r.skippedError()
+	}
 	args := []string{"--cluster", "del-node", addressPortDecider(nodeIP, r.Port), nodeID}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || stderr != "" || IsError(strings.TrimSpace(stdout)) {
 		return stdout, errors.Errorf("Failed to execute cluster del-node (%s, %s): %s | %s | %v", nodeIP, nodeID, stdout, stderr, err)
 	}
@@ -309,8 +472,8 @@ func (r *RedisCLI) DelNode(nodeIP string, nodeID string, opt ...string) (string,
 func (r *RedisCLI) ClusterInfo(nodeIP string, opt ...string) (*RedisClusterInfo, string, error) {
 
 	args := []string{"-h", nodeIP, "cluster", "info"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return nil, "", errors.Errorf("Failed to execute CLUSTER INFO (%s): %s | %s | %v", nodeIP, stdout, stderr, err)
 	}
@@ -322,8 +485,8 @@ func (r *RedisCLI) ClusterInfo(nodeIP string, opt ...string) (*RedisClusterInfo,
 func (r *RedisCLI) Info(nodeIP string, opt ...string) (*RedisInfo, string, error) {
 
 	args := []string{"-h", nodeIP, "info"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return nil, "", errors.Errorf("Failed to execute INFO (%s): %s | %s | %v", nodeIP, stdout, stderr, err)
 	}
@@ -333,8 +496,8 @@ func (r *RedisCLI) Info(nodeIP string, opt ...string) (*RedisInfo, string, error
 
 func (r *RedisCLI) DBSIZE(nodeIP string, opt ...string) (int64, string, error) {
 	args := []string{"-h", nodeIP, "DBSIZE"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return 0, stdout, errors.Errorf("Failed to execute INFO (%s): %s | %s | %v", nodeIP, stdout, stderr, err)
 	}
@@ -345,8 +508,8 @@ func (r *RedisCLI) DBSIZE(nodeIP string, opt ...string) (int64, string, error) {
 // https://redis.io/commands/ping
 func (r *RedisCLI) Ping(nodeIP string, message ...string) (string, error) {
 	args := []string{"-h", nodeIP, "ping"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, message...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, message...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return stdout, errors.Errorf("Failed to execute INFO (%s): %s | %s | %v", nodeIP, stdout, stderr, err)
 	}
@@ -356,8 +519,8 @@ func (r *RedisCLI) Ping(nodeIP string, message ...string) (string, error) {
 // https://redis.io/commands/cluster-nodes
 func (r *RedisCLI) ClusterNodes(nodeIP string, opt ...string) (*RedisClusterNodes, string, error) {
 	args := []string{"-h", nodeIP, "cluster", "nodes"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return nil, "", errors.Errorf("Failed to execute CLUSTER NODES(%s): %s | %s | %v", nodeIP, stdout, stderr, err)
 	}
@@ -367,8 +530,8 @@ func (r *RedisCLI) ClusterNodes(nodeIP string, opt ...string) (*RedisClusterNode
 // https://redis.io/commands/cluster-myid
 func (r *RedisCLI) MyClusterID(nodeIP string, opt ...string) (string, error) {
 	args := []string{"-h", nodeIP, "cluster", "myid"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return stdout, errors.Errorf("Failed to execute MYID(%s): %s | %s | %v", nodeIP, stdout, stderr, err)
 	}
@@ -379,9 +542,12 @@ func (r *RedisCLI) MyClusterID(nodeIP string, opt ...string) (string, error) {
 // In other words the specified node is removed from the nodes table of the node receiving the command.
 // https://redis.io/commands/cluster-forget
 func (r *RedisCLI) ClusterForget(nodeIP string, forgetNodeID string, opt ...string) (string, error) {
+	if r.skipMutation("ForgetNode", nodeIP, "forget node [%s] on [%s]", forgetNodeID, nodeIP) {
+		return "", r.skippedError()
+	}
 	args := []string{"-h", nodeIP, "cluster", "forget", forgetNodeID}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || strings.TrimSpace(stdout) != "OK" {
 		return stdout, errors.Errorf("Failed to execute CLUSTER FORGET (%s, %s): %s | %s | %v", nodeIP, forgetNodeID, stdout, stderr, err)
 	}
@@ -392,8 +558,8 @@ func (r *RedisCLI) ClusterForget(nodeIP string, forgetNodeID string, opt ...stri
 // https://redis.io/commands/cluster-replicas
 func (r *RedisCLI) ClusterReplicas(nodeIP string, leaderNodeID string, opt ...string) (*RedisClusterNodes, string, error) {
 	args := []string{"-h", nodeIP, "cluster", "replicas", leaderNodeID}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return nil, "", errors.Errorf("Failed to execute CLUSTER REPLICAS (%s, %s): %s | %s | %v", nodeIP, leaderNodeID, stdout, stderr, err)
 	}
@@ -402,9 +568,12 @@ func (r *RedisCLI) ClusterReplicas(nodeIP string, leaderNodeID string, opt ...st
 
 // https://redis.io/commands/cluster-failover
 func (r *RedisCLI) ClusterFailover(nodeIP string, opt ...string) (string, error) {
+	if r.skipMutation("Failover", nodeIP, "failover on [%s]", nodeIP) {
+		return "", r.skippedError()
+	}
 	args := []string{"-h", nodeIP, "cluster", "failover"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false, 5)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false, 5)
 	if err != nil || strings.TrimSpace(stderr) != "" || strings.TrimSpace(stdout) != "OK" {
 		return stdout, errors.Errorf("Failed to execute CLUSTER FAILOVER (%s, %v): %s | %s | %v", nodeIP, opt, stdout, stderr, err)
 	}
@@ -413,9 +582,12 @@ func (r *RedisCLI) ClusterFailover(nodeIP string, opt ...string) (string, error)
 
 // https://redis.io/commands/cluster-meet
 func (r *RedisCLI) ClusterMeet(nodeIP string, newNodeIP string, newNodePort string, opt ...string) (string, error) {
-	args := []string{"-h", nodeIP, "cluster", "meet", newNodeIP, newNodePort}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	if r.skipMutation("MeetNode", newNodeIP, "cluster meet of [%s] on [%s]", newNodeIP, nodeIP) {
+		return "", r.skippedError()
+	}
+	args := []string{"-h", nodeIP, "cluster", "meet", resolveHost(newNodeIP), newNodePort}
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || strings.TrimSpace(stdout) != "OK" {
 		return stdout, errors.Errorf("Failed to execute CLUSTER MEET (%s, %s, %s, %v): %s | %s | %v", nodeIP, newNodeIP, newNodePort, opt, stdout, stderr, err)
 	}
@@ -424,9 +596,12 @@ func (r *RedisCLI) ClusterMeet(nodeIP string, newNodeIP string, newNodePort stri
 
 // https://redis.io/commands/cluster-reset
 func (r *RedisCLI) ClusterReset(nodeIP string, opt ...string) (string, error) {
+	if r.skipMutation("ResetNode", nodeIP, "cluster reset on [%s]", nodeIP) {
+		return "", r.skippedError()
+	}
 	args := []string{"-h", nodeIP, "cluster", "reset"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || strings.TrimSpace(stdout) != "OK" {
 		return stdout, errors.Errorf("Failed to execute CLUSTER RESET (%s, %v): %s | %s | %v", nodeIP, opt, stdout, stderr, err)
 	}
@@ -434,13 +609,33 @@ func (r *RedisCLI) ClusterReset(nodeIP string, opt ...string) (string, error) {
 }
 
 func (r *RedisCLI) ClusterRebalance(nodeIP string, useEmptyMasters bool, opt ...string) (bool, string, error) {
+	return r.ClusterRebalanceWeighted(nodeIP, useEmptyMasters, nil, opt...)
+}
+
+// ClusterRebalanceWeighted rebalances the slots in proportion to the weights of the leaders, by their node IDs.
+// The leaders that have no weight weigh 1.
+func (r *RedisCLI) ClusterRebalanceWeighted(nodeIP string, useEmptyMasters bool, weights map[string]int64, opt ...string) (bool, string, error) {
+	if r.skipMutation("Rebalance", nodeIP, "cluster rebalance by [%s]", nodeIP) {
+		return r.planner != nil, "", r.skippedError()
+	}
 	args := []string{"--cluster", "rebalance", addressPortDecider(nodeIP, r.Port)}
+	if len(weights) > 0 {
+		ids := make([]string, 0, len(weights))
+		for id := range weights {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+		args = append(args, "--cluster-weight")
+		for _, id := range ids {
+			args = append(args, fmt.Sprintf("%s=%d", id, weights[id]))
+		}
+	}
 	if useEmptyMasters {
 		args = append(args, "--cluster-use-empty-masters")
 	}
 	args = append(args, "--cluster-yes")
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false, 50)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false, 50)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return false, stdout, errors.Errorf("Failed to execute cluster rebalance (%v): %s | %s | %v", nodeIP, stdout, stderr, err)
 	}
@@ -448,6 +643,9 @@ func (r *RedisCLI) ClusterRebalance(nodeIP string, useEmptyMasters bool, opt ...
 }
 
 func (r *RedisCLI) ClusterReshard(nodeIP string, sourceId string, targetId string, slots int, opt ...string) (bool, string, error) {
+	if r.skipMutation("Reshard", nodeIP, "reshard of %d slots [%s]->[%s] by [%s]", slots, sourceId, targetId, nodeIP) {
+		return r.planner != nil, "", r.skippedError()
+	}
 	args := []string{
 		"--cluster reshard", addressPortDecider(nodeIP, r.Port),
 		"--cluster-from", sourceId,
@@ -456,8 +654,8 @@ func (r *RedisCLI) ClusterReshard(nodeIP string, sourceId string, targetId strin
 		"--cluster-yes",
 	}
 	useBash := true
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, useBash, 50)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, useBash, 50)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return false, stdout, errors.Errorf("Failed to execute cluster reshard (%v): from [%s] to [%s] stdout: %s | stderr : %s | err: %v", nodeIP, sourceId, targetId, stdout, stderr, err)
 	}
@@ -466,21 +664,38 @@ func (r *RedisCLI) ClusterReshard(nodeIP string, sourceId string, targetId strin
 
 // https://redis.io/commands/flushall
 func (r *RedisCLI) Flushall(nodeIP string, opt ...string) (string, error) {
+	if r.skipMutation("FlushAll", nodeIP, "flushall on [%s]", nodeIP) {
+		return "", r.skippedError()
+	}
 
 	args := []string{"-h", nodeIP, "flushall"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return stdout, errors.Errorf("Failed to execute FLUSHALL (%s, %v): %s | %s | %v", nodeIP, opt, stdout, stderr, err)
 	}
 	return stdout, nil
 }
 
+// https://redis.io/commands/save
+func (r *RedisCLI) Save(nodeIP string, opt ...string) (string, error) {
+	args := []string{"-h", nodeIP, "save"}
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false, 15)
+	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
+		return stdout, errors.Errorf("Failed to execute SAVE (%s, %v): %s | %s | %v", nodeIP, opt, stdout, stderr, err)
+	}
+	return stdout, nil
+}
+
 // https://redis.io/commands/cluster-replicate
 func (r *RedisCLI) ClusterReplicate(nodeIP string, leaderID string, opt ...string) (string, error) {
+	if r.skipMutation("Replicate", nodeIP, "replicate [%s] on [%s]", leaderID, nodeIP) {
+		return "", r.skippedError()
+	}
 	args := []string{"-h", nodeIP, "cluster", "replicate", leaderID}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || strings.TrimSpace(stdout) != "OK" {
 		return stdout, errors.Errorf("Failed to execute CLUSTER REPLICATE (%s, %s): %s | %s | %v", nodeIP, leaderID, stdout, stderr, err)
 	}
@@ -489,10 +704,13 @@ func (r *RedisCLI) ClusterReplicate(nodeIP string, leaderID string, opt ...strin
 
 // https://redis.io/commands/acl-load
 func (r *RedisCLI) ACLLoad(nodeIP string, opt ...string) (string, error) {
+	if r.skipMutation("LoadACL", nodeIP, "acl load on [%s]", nodeIP) {
+		return "", r.skippedError()
+	}
 
 	args := []string{"-h", nodeIP, "acl", "load"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || strings.TrimSpace(stdout) != "OK" {
 		return stdout, errors.Errorf("Failed to execute ACL LOAD (%s): %s | %s | %v", nodeIP, stdout, stderr, err)
 	}
@@ -503,8 +721,8 @@ func (r *RedisCLI) ACLLoad(nodeIP string, opt ...string) (string, error) {
 func (r *RedisCLI) ACLList(nodeIP string, opt ...string) (*RedisACL, string, error) {
 
 	args := []string{"-h", nodeIP, "acl", "list"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return nil, "", errors.Errorf("Failed to execute ACL LIST (%s): %s | %s | %v", nodeIP, stdout, stderr, err)
 	}
@@ -516,9 +734,12 @@ func (r *RedisCLI) ACLList(nodeIP string, opt ...string) (*RedisACL, string, err
 }
 
 func (r *RedisCLI) ClusterFix(nodeIP string, opt ...string) (bool, string, error) {
+	if r.skipMutation("Fix", nodeIP, "cluster fix by [%s]", nodeIP) {
+		return r.planner != nil, "", r.skippedError()
+	}
 	args := []string{"--cluster", "fix", addressPortDecider(nodeIP, r.Port), "--cluster-fix-with-unreachable-masters", "--cluster-yes"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, opt...)
-	stdout, stderr, err := r.Handler.executeCommand([]string{"yes", "yes"}, args, true, 50)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS, opt...)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{"yes", "yes"}, args, true, 50)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return false, stdout, errors.Errorf("Failed to execute cluster fix (%v): %s | %s | %v", addressPortDecider(nodeIP, r.Port), stdout, stderr, err)
 	}
@@ -527,8 +748,8 @@ func (r *RedisCLI) ClusterFix(nodeIP string, opt ...string) (bool, string, error
 
 func (r *RedisCLI) Role(nodeIP string) (string, error) {
 	args := []string{"-h", nodeIP, "role"}
-	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth)
-	stdout, stderr, err := r.Handler.executeCommand([]string{}, args, false)
+	args, _ = r.Handler.buildCommand(r.Port, args, r.Auth, r.TLS)
+	stdout, stderr, err := r.Handler.executeCommand(r.context(), r.Auth, []string{}, args, false)
 	if err != nil || strings.TrimSpace(stderr) != "" || IsError(strings.TrimSpace(stdout)) {
 		return stdout, errors.Errorf("Failed to execute Role (%s): %s | %s | %v", nodeIP, stdout, stderr, err)
 	}
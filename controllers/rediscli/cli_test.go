@@ -1,9 +1,13 @@
 package rediscli
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"strings"
 	"testing"
+
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
 type TestCommandHandler struct{}
@@ -16,7 +20,7 @@ func (h *TestCommandHandler) buildRedisClusterInfoModel(stdoutInfo string) (*Red
 	return &RedisClusterInfo{}, nil
 }
 
-func (h *TestCommandHandler) buildCommand(routingPort string, args []string, auth *RedisAuth, opt ...string) ([]string, map[string]string) {
+func (h *TestCommandHandler) buildCommand(routingPort string, args []string, auth *RedisAuth, tls *RedisTLS, opt ...string) ([]string, map[string]string) {
 	if auth != nil {
 		args = append([]string{"--user", auth.User}, args...)
 	}
@@ -28,7 +32,7 @@ func (h *TestCommandHandler) buildCommand(routingPort string, args []string, aut
 	return args, argListToArgMap(args)
 }
 
-func (h *TestCommandHandler) executeCommand(pipedArgs []string, args []string, useBash bool, multipFactorForTimeout ...float64) (string, string, error) {
+func (h *TestCommandHandler) executeCommand(ctx context.Context, auth *RedisAuth, pipedArgs []string, args []string, useBash bool, multipFactorForTimeout ...float64) (string, string, error) {
 	executedCommand := ""
 	for _, arg := range args {
 		executedCommand += arg + " "
@@ -61,8 +65,8 @@ var r *RedisCLI
 var t *testing.T
 
 func TestRedisCLI(test *testing.T) {
-	auth := &RedisAuth{"test_user"}
-	r = &RedisCLI{nil, auth, "6380", nil}
+	auth := &RedisAuth{User: "test_user"}
+	r = &RedisCLI{nil, auth, nil, "6380", nil, false, nil, nil}
 	r.Handler = &TestCommandHandler{}
 	t = test
 
@@ -84,6 +88,7 @@ func TestRedisCLI(test *testing.T) {
 	testClusterRebalance()
 	testClusterReshard()
 	testFlushAll()
+	testSave()
 	testClusterReplicate()
 	testACLLoad()
 	testACLList()
@@ -367,6 +372,16 @@ func testFlushAll() {
 	execFlushAllTest("5", nodeIP, "-p 8363", "-optArg1 optVal1")
 }
 
+func testSave() {
+	nodeIP := "128.0.1.1"
+	// Test 1 : Routing port is not provided, no optional arguments
+	execSaveTest("1", nodeIP)
+	// Test 2 : Routing port is provided, no optional arguments
+	execSaveTest("2", nodeIP, "-p 6373")
+	// Test 3 : Routing port is provided, optional arguments are provided as parametrized arg list
+	execSaveTest("3", nodeIP, "-p 8363", "-optArg1 optVal1")
+}
+
 func testClusterReplicate() {
 	nodeIP := "127.0.0.1"
 	leaderID := "abcdefg123456"
@@ -433,8 +448,8 @@ func execClusterCreateTest(testCaseId string, addresses []string, opt ...string)
 	updatedAddresses := addressesPortDecider(addresses, r.Port)
 	expectedArgList := append([]string{"--cluster", "create"}, updatedAddresses...)
 	expectedArgList = append(expectedArgList, "--cluster-yes")
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster Create "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -443,8 +458,8 @@ func execClusterCheckTest(testCaseId string, address string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"--cluster", "check", addressPortDecider(address, r.Port)}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster Check "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -458,8 +473,8 @@ func execAddFollowerTest(testCaseId string, newNodeAddr string, existingNodeAddr
 	leaderIdFlag := "--cluster-master-id"
 	expectedArgList := []string{"--cluster", "add-node"}
 	expectedArgList = append(expectedArgList, newNodeAddr, existingNodeAddr, leadershipType, leaderIdFlag, leaderID)
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Add follower "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -468,8 +483,8 @@ func execAddLeaderTest(testCaseId string, newNodeAddr string, existingNodeAddr s
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"--cluster", "add-node", addressPortDecider(newNodeAddr, r.Port), addressPortDecider(existingNodeAddr, r.Port)}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Add Leader "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -478,8 +493,8 @@ func execDelNodeTest(testCaseId string, nodeIP string, nodeID string, opt ...str
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"--cluster", "del-node", addressPortDecider(nodeIP, r.Port), nodeID}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Delete Node "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -488,8 +503,8 @@ func execClusterInfoTest(testCaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "cluster", "info"}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster Info "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -498,8 +513,8 @@ func execInfoTest(testCaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "info"}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Info "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -508,8 +523,8 @@ func execPingTest(testCaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "ping"}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Ping "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -518,8 +533,8 @@ func execClusterNodesTest(testCaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "cluster", "nodes"}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster Nodes "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -528,8 +543,8 @@ func execMyClusterIDTest(testCaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgLine := []string{"-h", nodeIP, "cluster", "myid"}
-	expectedArgLine, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgLine, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgLine, false)
+	expectedArgLine, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgLine, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgLine, false)
 	resultHandler(expectedResult, result, "My Cluster ID "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -538,8 +553,8 @@ func execClusterForgetTest(testCaseId string, nodeIP string, forgetNodeID string
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "cluster", "forget", forgetNodeID}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster Forget "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -548,8 +563,8 @@ func execClusterReplicasTest(testCaseId string, nodeIP string, leaderNodeID stri
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "cluster", "replicas", leaderNodeID}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster Replicas "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -558,8 +573,8 @@ func execClusterFailOverTest(testCaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "cluster", "failover"}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster Failover "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -568,8 +583,8 @@ func execClusterMeetTest(testCaseId string, nodeIP string, newNodeIP string, new
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "cluster", "meet", newNodeIP, newNodePort}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster Meet "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -578,8 +593,8 @@ func execClusterResetTest(testCaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "cluster", "reset"}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster Reset "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -592,8 +607,8 @@ func execClusterRebalanceTest(testCaseId string, nodeIP string, useEmptyMasters
 		expectedArgList = append(expectedArgList, "--cluster-use-empty-masters")
 	}
 	expectedArgList = append(expectedArgList, "--cluster-yes")
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, fmt.Sprint(result), "Cluster Rebalance "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -604,8 +619,8 @@ func execClusterReshardTest(testCaseId string, nodeIP string, sourceId string, t
 	expectedArgList := []string{"--cluster reshard", addressPortDecider(nodeIP, r.Port)}
 	expectedArgList = append(expectedArgList, "--cluster-from", sourceId, "--cluster-to", targetId)
 	expectedArgList = append(expectedArgList, "--cluster-slots", fmt.Sprint(slots), "--cluster-yes")
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, fmt.Sprint(result), "Cluster Reshard "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -614,18 +629,28 @@ func execFlushAllTest(testCaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "flushall"}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Flush All "+testCaseId, argMap, expectedArgMap)
 }
 
+func execSaveTest(testCaseId string, nodeIP string, opt ...string) {
+	result, _ := r.Save(nodeIP, opt...)
+	argMap := make(map[string]string)
+	argLineToArgMap(result, argMap)
+	expectedArgList := []string{"-h", nodeIP, "save"}
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
+	resultHandler(expectedResult, result, "Save "+testCaseId, argMap, expectedArgMap)
+}
+
 func execClusterReplicateTest(testCaseId string, nodeIP string, leaderID string, opt ...string) {
 	result, _ := r.ClusterReplicate(nodeIP, leaderID, opt...)
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "cluster", "replicate", leaderID}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster replicate "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -634,8 +659,8 @@ func execACLLoadTest(testcaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "acl", "load"}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "ACLLoad "+testcaseId, argMap, expectedArgMap)
 }
 
@@ -644,8 +669,8 @@ func execACLListTest(testCaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"-h", nodeIP, "acl", "list"}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "ACLList "+testCaseId, argMap, expectedArgMap)
 }
 
@@ -654,8 +679,67 @@ func execClusterFixTest(testCaseId string, nodeIP string, opt ...string) {
 	argMap := make(map[string]string)
 	argLineToArgMap(result, argMap)
 	expectedArgList := []string{"--cluster", "fix", addressPortDecider(nodeIP, r.Port), "--cluster-fix-with-unreachable-masters", "--cluster-yes"}
-	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, opt...)
+	expectedArgList, expectedArgMap := r.Handler.buildCommand(r.Port, expectedArgList, r.Auth, r.TLS, opt...)
 	//pipeArgs := []string{"yes", "yes"}
-	expectedResult, _, _ := r.Handler.executeCommand([]string{}, expectedArgList, false)
+	expectedResult, _, _ := r.Handler.executeCommand(r.context(), r.Auth, []string{}, expectedArgList, false)
 	resultHandler(expectedResult, result, "Cluster fix "+testCaseId, argMap, expectedArgMap)
 }
+
+type countingCommandHandler struct {
+	TestCommandHandler
+	executed int
+}
+
+func (h *countingCommandHandler) executeCommand(ctx context.Context, auth *RedisAuth, pipedArgs []string, args []string, useBash bool, multipFactorForTimeout ...float64) (string, string, error) {
+	h.executed++
+	return h.TestCommandHandler.executeCommand(ctx, auth, pipedArgs, args, useBash, multipFactorForTimeout...)
+}
+
+func TestObserveOnly(test *testing.T) {
+	handler := &countingCommandHandler{}
+	cli := &RedisCLI{Log: zap.New(), Port: "6379", Handler: handler}
+	observing := cli.WithObserveOnly(true)
+	if cli.ObserveOnly {
+		test.Error("Expected WithObserveOnly to return a copy")
+	}
+	if _, err := observing.ClusterForget("10.0.0.1", "id"); err != ErrObserveOnly {
+		test.Errorf("Expected cluster forget to be skipped, got %v", err)
+	}
+	if _, err := observing.ClusterFailover("10.0.0.1"); err != ErrObserveOnly {
+		test.Errorf("Expected cluster failover to be skipped, got %v", err)
+	}
+	if ok, _, err := observing.ClusterReshard("10.0.0.1", "source", "target", 10); ok || err != ErrObserveOnly {
+		test.Errorf("Expected cluster reshard to be skipped, got %v", err)
+	}
+	if handler.executed != 0 {
+		test.Errorf("Expected no command to be executed, %d were executed", handler.executed)
+	}
+	if _, _, err := observing.ClusterNodes("10.0.0.1"); err == ErrObserveOnly || handler.executed != 1 {
+		test.Errorf("Expected cluster nodes to be executed in observe-only mode, got %v", err)
+	}
+}
+
+func TestPlanner(test *testing.T) {
+	handler := &countingCommandHandler{}
+	var planned []string
+	cli := &RedisCLI{Log: zap.New(), Port: "6379", Handler: handler}
+	planning := cli.WithObserveOnly(true).WithPlanner(func(command string, nodeAddress string, description string) {
+		planned = append(planned, command+" "+nodeAddress)
+	})
+	if _, err := planning.ClusterForget("10.0.0.1", "id"); err != nil {
+		test.Errorf("Expected a planned cluster forget to succeed, got %v", err)
+	}
+	if ok, _, err := planning.ClusterFix("10.0.0.2"); !ok || err != nil {
+		test.Errorf("Expected a planned cluster fix to succeed, got %v", err)
+	}
+	if _, err := planning.AddFollower("10.0.0.3", "10.0.0.1", "id"); err != nil {
+		test.Errorf("Expected a planned add node to succeed, got %v", err)
+	}
+	expected := []string{"ForgetNode 10.0.0.1", "Fix 10.0.0.2", "AddNode 10.0.0.3"}
+	if !reflect.DeepEqual(planned, expected) {
+		test.Errorf("Expected the planned commands %v, got %v", expected, planned)
+	}
+	if _, _, err := planning.ClusterNodes("10.0.0.1"); err != nil || handler.executed != 1 {
+		test.Errorf("Expected only cluster nodes to be executed while planning, %d commands were executed: %v", handler.executed, err)
+	}
+}
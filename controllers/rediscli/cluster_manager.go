@@ -0,0 +1,1172 @@
+package rediscli
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+/*
+	The cluster manager implements the '--cluster' subcommands of redis-cli for the native command handler:
+	create, check, add-node, del-node, reshard, rebalance and fix.
+
+	The algorithms follow the ones of redis-cli (slots allocation, reshard table, rebalance balances), and the
+	output keeps the lines that are parsed by the operator, for example:
+	M: <id> <ip>:<port>
+	   slots: (0 slots) master
+	[OK] All nodes agree about slots configuration.
+	[OK] All 16384 slots covered.
+*/
+
+const (
+	clusterManagerPollInterval       = time.Second
+	clusterManagerMigratePipeline    = 10
+	clusterManagerMigrateTimeout     = 60000
+	clusterManagerRebalanceThreshold = 2.0
+)
+
+// Flags of the '--cluster' subcommands that are followed by a value
+var clusterManagerValueFlags = map[string]bool{
+	"--cluster-master-id": true,
+	"--cluster-from":      true,
+	"--cluster-to":        true,
+	"--cluster-slots":     true,
+	"--cluster-timeout":   true,
+	"--cluster-pipeline":  true,
+	"--cluster-threshold": true,
+	"--cluster-replicas":  true,
+}
+
+type clusterManager struct {
+	ctx                context.Context
+	handler            *NativeCommandHandler
+	user               string
+	password           string
+	tls                *RedisTLS
+	port               string
+	out                strings.Builder
+	nodes              []*clusterManagerNode
+	unreachableLeaders int
+	// The number of keys moved by a single MIGRATE command, and the limit of the keys moved per second
+	pipeline  int
+	throttle  *keysThrottle
+	movedKeys int64
+}
+
+type clusterManagerNode struct {
+	id        string
+	addr      string
+	flags     string
+	leaderID  string
+	slots     []int
+	migrating map[int]string
+	importing map[int]string
+	replicas  int
+	config    string
+}
+
+type clusterManagerArgs struct {
+	addrs   []string
+	flags   map[string]string
+	weights map[string]float64
+}
+
+func newClusterManager(ctx context.Context, handler *NativeCommandHandler, user string, password string, tls *RedisTLS, port string) *clusterManager {
+	return &clusterManager{
+		ctx:      ctx,
+		handler:  handler,
+		user:     user,
+		password: password,
+		tls:      tls,
+		port:     port,
+		pipeline: clusterManagerMigratePipeline,
+	}
+}
+
+func (cm *clusterManager) run(subcommand string, args []string) error {
+	a := parseClusterManagerArgs(args, cm.port)
+	switch subcommand {
+	case "create":
+		return cm.create(a)
+	case "check":
+		return cm.check(a)
+	case "add-node":
+		return cm.addNode(a)
+	case "del-node":
+		return cm.delNode(a)
+	case "reshard":
+		return cm.reshard(a)
+	case "rebalance":
+		return cm.rebalance(a)
+	case "fix":
+		return cm.fix(a)
+	}
+	return cm.fail(subcommand, "unknown subcommand")
+}
+
+func (cm *clusterManager) output() string {
+	return cm.out.String()
+}
+
+func (cm *clusterManager) printf(format string, args ...interface{}) {
+	cm.out.WriteString(fmt.Sprintf(format, args...) + "\n")
+}
+
+func (cm *clusterManager) fail(subcommand string, format string, args ...interface{}) error {
+	reason := fmt.Sprintf(format, args...)
+	cm.printf("[ERR] %s", reason)
+	return &ClusterManagerError{Subcommand: subcommand, Reason: reason}
+}
+
+func (cm *clusterManager) do(addr string, args ...interface{}) (interface{}, error) {
+	return cm.handler.do(cm.ctx, addr, cm.user, cm.password, cm.tls, args...)
+}
+
+func (cm *clusterManager) doString(addr string, args ...interface{}) (string, error) {
+	reply, err := cm.do(addr, args...)
+	if err != nil {
+		return "", err
+	}
+	return renderRawReply(reply), nil
+}
+
+func (cm *clusterManager) doInt(addr string, args ...interface{}) (int64, error) {
+	reply, err := cm.do(addr, args...)
+	if err != nil {
+		return 0, err
+	}
+	if i, ok := reply.(int64); ok {
+		return i, nil
+	}
+	return strconv.ParseInt(renderRawReply(reply), 10, 64)
+}
+
+// Waits until the condition is met or the deadline of the command is reached
+func (cm *clusterManager) poll(condition func() bool) bool {
+	for {
+		if condition() {
+			return true
+		}
+		select {
+		case <-cm.ctx.Done():
+			return false
+		case <-time.After(clusterManagerPollInterval):
+		}
+	}
+}
+
+// create <addr>... : assigns the slots evenly between the given nodes and joins them into a new cluster
+func (cm *clusterManager) create(a clusterManagerArgs) error {
+	if len(a.addrs) == 0 {
+		return cm.fail("create", "no nodes were given")
+	}
+	for _, addr := range a.addrs {
+		if err := cm.checkNodeIsEmpty(addr); err != nil {
+			return cm.fail("create", "%v", err)
+		}
+	}
+	cm.printf(">>> Performing hash slots allocation on %d nodes...", len(a.addrs))
+	allocation := allocateSlots(len(a.addrs))
+	for i, addr := range a.addrs {
+		id, err := cm.doString(addr, "cluster", "myid")
+		if err != nil {
+			return cm.fail("create", "%v", err)
+		}
+		cm.printf("M: %s %s", id, addr)
+		cm.printf("   slots:%s (%d slots) master", formatSlotRanges(allocation[i]), len(allocation[i]))
+	}
+	cm.printf(">>> Assigning the slots to the nodes")
+	for i, addr := range a.addrs {
+		args := []interface{}{"cluster", "addslots"}
+		for _, slot := range allocation[i] {
+			args = append(args, slot)
+		}
+		if _, err := cm.do(addr, args...); err != nil {
+			return cm.fail("create", "failed to assign the slots to %s: %v", addr, err)
+		}
+	}
+	cm.printf(">>> Assign a different config epoch to each node")
+	for i, addr := range a.addrs {
+		// The epoch can not be set on a node that already knows other nodes, the nodes get unique epochs anyway in that case
+		cm.do(addr, "cluster", "set-config-epoch", i+1)
+	}
+	cm.printf(">>> Sending CLUSTER MEET messages to join the cluster")
+	for _, addr := range a.addrs[1:] {
+		ip, port := splitAddr(addr)
+		if _, err := cm.do(a.addrs[0], "cluster", "meet", resolveHost(ip), port); err != nil {
+			return cm.fail("create", "failed to meet node %s: %v", addr, err)
+		}
+	}
+	cm.printf("Waiting for the cluster to join")
+	joined := cm.poll(func() bool {
+		signature := ""
+		for i, addr := range a.addrs {
+			stdout, err := cm.doString(addr, "cluster", "nodes")
+			if err != nil {
+				return false
+			}
+			table := NewRedisClusterNodes(stdout)
+			if len(*table) < len(a.addrs) || strings.Contains(stdout, "handshake") {
+				return false
+			}
+			if i == 0 {
+				signature = clusterConfigSignature(stdout)
+			} else if clusterConfigSignature(stdout) != signature {
+				return false
+			}
+		}
+		return true
+	})
+	if !joined {
+		return cm.fail("create", "the nodes did not join the cluster in time")
+	}
+	if err := cm.loadCluster(a.addrs[0]); err != nil {
+		return cm.fail("create", "%v", err)
+	}
+	if !cm.checkCluster(a.addrs[0]) {
+		return cm.fail("create", "cluster check failed after the creation")
+	}
+	return nil
+}
+
+// check <addr> : checks that all the nodes agree about the slots configuration and that all the slots are covered
+func (cm *clusterManager) check(a clusterManagerArgs) error {
+	if len(a.addrs) == 0 {
+		return cm.fail("check", "no node was given")
+	}
+	if err := cm.loadCluster(a.addrs[0]); err != nil {
+		return cm.fail("check", "%v", err)
+	}
+	if !cm.checkCluster(a.addrs[0]) {
+		return &ClusterManagerError{Subcommand: "check", Reason: "the cluster check found errors"}
+	}
+	return nil
+}
+
+// add-node <new addr> <existing addr> [--cluster-slave [--cluster-master-id <id>]] : joins an empty node to the cluster
+func (cm *clusterManager) addNode(a clusterManagerArgs) error {
+	if len(a.addrs) < 2 {
+		return cm.fail("add-node", "the new node and an existing node of the cluster are required")
+	}
+	newAddr, existingAddr := a.addrs[0], a.addrs[1]
+	cm.printf(">>> Adding node %s to cluster %s", newAddr, existingAddr)
+	if err := cm.loadCluster(existingAddr); err != nil {
+		return cm.fail("add-node", "%v", err)
+	}
+	cm.checkCluster(existingAddr)
+
+	var leader *clusterManagerNode
+	if a.has("--cluster-slave") {
+		if leaderID, exists := a.flags["--cluster-master-id"]; exists {
+			leader = cm.nodeByID(leaderID)
+			if leader == nil || !leader.isLeader() {
+				return cm.fail("add-node", "No such master ID %s", leaderID)
+			}
+		} else {
+			leader = cm.leaderWithFewestReplicas()
+			if leader == nil {
+				return cm.fail("add-node", "no master was found to replicate")
+			}
+			cm.printf("Automatically selected master %s", leader.addr)
+		}
+	}
+	if err := cm.checkNodeIsEmpty(newAddr); err != nil {
+		return cm.fail("add-node", "%v", err)
+	}
+	cm.printf(">>> Send CLUSTER MEET to node %s to make it join the cluster.", newAddr)
+	ip, port := splitAddr(existingAddr)
+	if _, err := cm.do(newAddr, "cluster", "meet", resolveHost(ip), port); err != nil {
+		return cm.fail("add-node", "%v", err)
+	}
+	if leader != nil {
+		known := cm.poll(func() bool {
+			stdout, err := cm.doString(newAddr, "cluster", "nodes")
+			return err == nil && strings.Contains(stdout, leader.id)
+		})
+		if !known {
+			return cm.fail("add-node", "node %s did not learn about master %s in time", newAddr, leader.id)
+		}
+		cm.printf(">>> Configure node as replica of %s.", leader.addr)
+		if _, err := cm.do(newAddr, "cluster", "replicate", leader.id); err != nil {
+			return cm.fail("add-node", "%v", err)
+		}
+	}
+	cm.printf("[OK] New node added correctly.")
+	return nil
+}
+
+// del-node <addr> <id> : removes an empty node from the tables of all the nodes and resets it
+func (cm *clusterManager) delNode(a clusterManagerArgs) error {
+	if len(a.addrs) < 2 {
+		return cm.fail("del-node", "a node of the cluster and the id of the deleted node are required")
+	}
+	entryAddr, id := a.addrs[0], a.addrs[1]
+	cm.printf(">>> Removing node %s from cluster %s", id, entryAddr)
+	if err := cm.loadCluster(entryAddr); err != nil {
+		return cm.fail("del-node", "%v", err)
+	}
+	deleted := cm.nodeByID(id)
+	if deleted == nil {
+		// A failing node is not loaded, it still has to be forgotten by the rest of the nodes
+		if stdout, _ := cm.doString(entryAddr, "cluster", "nodes"); !strings.Contains(stdout, id) {
+			return cm.fail("del-node", "No such node ID %s", id)
+		}
+	} else if len(deleted.slots) > 0 {
+		return cm.fail("del-node", "Node %s is not empty! Reshard data away and try again.", deleted.addr)
+	}
+	cm.printf(">>> Sending CLUSTER FORGET messages to the cluster...")
+	for _, n := range cm.nodes {
+		if n.id == id {
+			continue
+		}
+		if n.leaderID == id {
+			leader := cm.leaderWithFewestReplicas(id)
+			if leader != nil {
+				cm.printf(">>> %s as replica of %s", n.addr, leader.addr)
+				if _, err := cm.do(n.addr, "cluster", "replicate", leader.id); err != nil {
+					return cm.fail("del-node", "%v", err)
+				}
+				leader.replicas++
+			}
+		}
+		if _, err := cm.do(n.addr, "cluster", "forget", id); err != nil {
+			return cm.fail("del-node", "%v", err)
+		}
+	}
+	if deleted != nil {
+		cm.printf(">>> Sending CLUSTER RESET SOFT to the deleted node.")
+		cm.do(deleted.addr, "cluster", "reset", "soft")
+	}
+	return nil
+}
+
+// reshard <addr> --cluster-from <id,...|all> --cluster-to <id> --cluster-slots <n> : moves slots to the target leader
+func (cm *clusterManager) reshard(a clusterManagerArgs) error {
+	if len(a.addrs) == 0 {
+		return cm.fail("reshard", "no node was given")
+	}
+	if err := cm.loadCluster(a.addrs[0]); err != nil {
+		return cm.fail("reshard", "%v", err)
+	}
+	if !cm.checkCluster(a.addrs[0]) {
+		return cm.fail("reshard", "Please fix your cluster problems before resharding")
+	}
+	numSlots, err := strconv.Atoi(a.flags["--cluster-slots"])
+	if err != nil || numSlots <= 0 {
+		return cm.fail("reshard", "invalid number of slots [%s]", a.flags["--cluster-slots"])
+	}
+	target := cm.nodeByID(a.flags["--cluster-to"])
+	if target == nil || !target.isLeader() {
+		return cm.fail("reshard", "The specified node (%s) is not known or not a master, please retry.", a.flags["--cluster-to"])
+	}
+	var sources []*clusterManagerNode
+	if from := a.flags["--cluster-from"]; from == "all" {
+		for _, n := range cm.leaders() {
+			if n.id != target.id && len(n.slots) > 0 {
+				sources = append(sources, n)
+			}
+		}
+	} else {
+		for _, id := range strings.Split(from, ",") {
+			source := cm.nodeByID(id)
+			if source == nil || !source.isLeader() {
+				return cm.fail("reshard", "The specified node (%s) is not known or is not a master, please retry.", id)
+			}
+			if source.id == target.id {
+				return cm.fail("reshard", "It is not possible to use the target node as source node.")
+			}
+			sources = append(sources, source)
+		}
+	}
+	if len(sources) == 0 {
+		return cm.fail("reshard", "No source nodes given.")
+	}
+	fix := a.has("--cluster-replace")
+	for _, move := range computeReshardTable(sources, numSlots) {
+		if err := cm.moveSlot(move.source, target, move.slot, fix); err != nil {
+			return cm.fail("reshard", "%v", err)
+		}
+	}
+	return nil
+}
+
+// rebalance <addr> [--cluster-weight <id>=<weight> ...] [--cluster-use-empty-masters] [--cluster-threshold <percent>] : moves slots until
+// every leader holds a number of slots in proportion to its weight
+func (cm *clusterManager) rebalance(a clusterManagerArgs) error {
+	if len(a.addrs) == 0 {
+		return cm.fail("rebalance", "no node was given")
+	}
+	if err := cm.loadCluster(a.addrs[0]); err != nil {
+		return cm.fail("rebalance", "%v", err)
+	}
+	if !cm.checkCluster(a.addrs[0]) {
+		return cm.fail("rebalance", "Please fix your cluster problems before rebalancing")
+	}
+	threshold := clusterManagerRebalanceThreshold
+	if t, exists := a.flags["--cluster-threshold"]; exists {
+		if parsed, err := strconv.ParseFloat(t, 64); err == nil {
+			threshold = parsed
+		}
+	}
+	var leaders []*clusterManagerNode
+	for _, n := range cm.leaders() {
+		if len(n.slots) > 0 || a.has("--cluster-use-empty-masters") {
+			leaders = append(leaders, n)
+		}
+	}
+	if len(leaders) == 0 {
+		return cm.fail("rebalance", "no masters to rebalance")
+	}
+	slots := make([]int, len(leaders))
+	weights := make([]float64, len(leaders))
+	totalWeight := 0.0
+	for i, n := range leaders {
+		slots[i] = len(n.slots)
+		weights[i] = a.weight(n.id)
+		totalWeight += weights[i]
+	}
+	if totalWeight <= 0 {
+		return cm.fail("rebalance", "the total weight of the masters should be positive")
+	}
+	balances, needed := computeRebalanceBalances(slots, weights, threshold)
+	if !needed {
+		cm.printf("*** No rebalancing needed! All nodes are within the %.2f%% threshold.", threshold)
+		return nil
+	}
+	cm.printf(">>> Rebalancing across %d nodes. Total weight = %.2f", len(leaders), totalWeight)
+	order := make([]int, len(leaders))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool { return balances[order[i]] < balances[order[j]] })
+	dst, src := 0, len(order)-1
+	for dst < src {
+		d, s := order[dst], order[src]
+		numSlots := int(math.Min(math.Abs(float64(balances[d])), float64(balances[s])))
+		if numSlots > 0 {
+			cm.printf("Moving %d slots from %s to %s", numSlots, leaders[s].addr, leaders[d].addr)
+			for _, move := range computeReshardTable([]*clusterManagerNode{leaders[s]}, numSlots) {
+				if err := cm.moveSlot(move.source, leaders[d], move.slot, false); err != nil {
+					return cm.fail("rebalance", "%v", err)
+				}
+			}
+		}
+		balances[d] += numSlots
+		balances[s] -= numSlots
+		if balances[d] == 0 {
+			dst++
+		}
+		if balances[s] == 0 {
+			src--
+		}
+	}
+	return nil
+}
+
+// fix <addr> [--cluster-fix-with-unreachable-masters] : closes the open slots and covers the slots that have no owner
+func (cm *clusterManager) fix(a clusterManagerArgs) error {
+	if len(a.addrs) == 0 {
+		return cm.fail("fix", "no node was given")
+	}
+	if err := cm.loadCluster(a.addrs[0]); err != nil {
+		return cm.fail("fix", "%v", err)
+	}
+	if cm.checkCluster(a.addrs[0]) {
+		return nil
+	}
+	for _, slot := range cm.openSlots() {
+		if err := cm.fixOpenSlot(slot); err != nil {
+			return cm.fail("fix", "%v", err)
+		}
+	}
+	covered := cm.coveredSlots()
+	if len(covered) < MAX_SLOTS_PER_LEADER {
+		if cm.unreachableLeaders > 0 && !a.has("--cluster-fix-with-unreachable-masters") {
+			return cm.fail("fix", "Fixing slots coverage with %d unreachable masters is dangerous: the slots of the unreachable masters are assumed not covered. If you want to proceed anyway use --cluster-fix-with-unreachable-masters", cm.unreachableLeaders)
+		}
+		if err := cm.fixSlotsCoverage(covered); err != nil {
+			return cm.fail("fix", "%v", err)
+		}
+	}
+	if err := cm.loadCluster(a.addrs[0]); err != nil {
+		return cm.fail("fix", "%v", err)
+	}
+	if !cm.checkCluster(a.addrs[0]) {
+		return &ClusterManagerError{Subcommand: "fix", Reason: "the cluster check found errors after the fix"}
+	}
+	return nil
+}
+
+// Loads the cluster nodes as seen by the entry node, and the slots of each node as seen by the node itself
+func (cm *clusterManager) loadCluster(entryAddr string) error {
+	stdout, err := cm.doString(entryAddr, "cluster", "nodes")
+	if err != nil {
+		return err
+	}
+	cm.nodes = nil
+	cm.unreachableLeaders = 0
+	for _, entry := range *NewRedisClusterNodes(stdout) {
+		if hasNodeFlag(entry.Flags, "noaddr") || hasNodeFlag(entry.Flags, "handshake") {
+			continue
+		}
+		if hasNodeFlag(entry.Flags, "fail") {
+			if hasNodeFlag(entry.Flags, "master") {
+				cm.unreachableLeaders++
+			}
+			continue
+		}
+		node := &clusterManagerNode{id: entry.ID, addr: nodeAddr(entry.Addr), flags: entry.Flags, leaderID: entry.Leader}
+		own, err := cm.doString(node.addr, "cluster", "nodes")
+		if err != nil {
+			return fmt.Errorf("Node %s is not reachable: %v", node.addr, err)
+		}
+		for _, n := range *NewRedisClusterNodes(own) {
+			if hasNodeFlag(n.Flags, "myself") {
+				node.flags = n.Flags
+				node.leaderID = n.Leader
+				node.slots, node.migrating, node.importing = parseNodeSlots(n.Slots)
+			}
+		}
+		node.config = clusterConfigSignature(own)
+		cm.nodes = append(cm.nodes, node)
+	}
+	for _, n := range cm.nodes {
+		if leader := cm.nodeByID(n.leaderID); leader != nil && !n.isLeader() {
+			leader.replicas++
+		}
+	}
+	return nil
+}
+
+func (cm *clusterManager) checkCluster(entryAddr string) bool {
+	ok := true
+	cm.printf(">>> Performing Cluster Check (using node %s)", entryAddr)
+	cm.showNodes()
+	if cm.unreachableLeaders > 0 {
+		cm.printf("[WARNING] %d unreachable masters", cm.unreachableLeaders)
+	}
+	if cm.isConfigConsistent() {
+		cm.printf("[OK] All nodes agree about slots configuration.")
+	} else {
+		cm.printf("[ERR] Nodes don't agree about configuration!")
+		ok = false
+	}
+	cm.printf(">>> Check for open slots...")
+	if openSlots := cm.openSlots(); len(openSlots) > 0 {
+		for _, n := range cm.nodes {
+			if len(n.migrating) > 0 {
+				cm.printf("[WARNING] Node %s has slots in migrating state %s.", n.addr, joinSlots(n.migrating))
+			}
+			if len(n.importing) > 0 {
+				cm.printf("[WARNING] Node %s has slots in importing state %s.", n.addr, joinSlots(n.importing))
+			}
+		}
+		cm.printf("[WARNING] The following slots are open: %s.", formatSlotList(openSlots))
+		ok = false
+	}
+	cm.printf(">>> Check slots coverage...")
+	if len(cm.coveredSlots()) == MAX_SLOTS_PER_LEADER {
+		cm.printf("[OK] All %d slots covered.", MAX_SLOTS_PER_LEADER)
+	} else {
+		cm.printf("[ERR] Not all %d slots are covered by nodes.", MAX_SLOTS_PER_LEADER)
+		ok = false
+	}
+	return ok
+}
+
+func (cm *clusterManager) showNodes() {
+	for _, n := range cm.leaders() {
+		cm.printf("M: %s %s", n.id, n.addr)
+		cm.printf("   slots:%s (%d slots) master", formatSlotRanges(n.slots), len(n.slots))
+		if n.replicas > 0 {
+			cm.printf("   %d additional replica(s)", n.replicas)
+		}
+	}
+	for _, n := range cm.nodes {
+		if !n.isLeader() {
+			cm.printf("S: %s %s", n.id, n.addr)
+			cm.printf("   slots: (0 slots) slave")
+			cm.printf("   replicates %s", n.leaderID)
+		}
+	}
+}
+
+func (cm *clusterManager) isConfigConsistent() bool {
+	for _, n := range cm.nodes {
+		if n.config != cm.nodes[0].config {
+			return false
+		}
+	}
+	return true
+}
+
+func (cm *clusterManager) openSlots() []int {
+	open := map[int]bool{}
+	for _, n := range cm.nodes {
+		for slot := range n.migrating {
+			open[slot] = true
+		}
+		for slot := range n.importing {
+			open[slot] = true
+		}
+	}
+	var slots []int
+	for slot := range open {
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+	return slots
+}
+
+func (cm *clusterManager) coveredSlots() map[int]*clusterManagerNode {
+	covered := map[int]*clusterManagerNode{}
+	for _, n := range cm.leaders() {
+		for _, slot := range n.slots {
+			covered[slot] = n
+		}
+	}
+	return covered
+}
+
+func (cm *clusterManager) leaders() []*clusterManagerNode {
+	var leaders []*clusterManagerNode
+	for _, n := range cm.nodes {
+		if n.isLeader() {
+			leaders = append(leaders, n)
+		}
+	}
+	return leaders
+}
+
+func (cm *clusterManager) nodeByID(id string) *clusterManagerNode {
+	for _, n := range cm.nodes {
+		if n.id == id {
+			return n
+		}
+	}
+	return nil
+}
+
+func (cm *clusterManager) leaderWithFewestReplicas(excludeIDs ...string) *clusterManagerNode {
+	var selected *clusterManagerNode
+	for _, n := range cm.leaders() {
+		excluded := false
+		for _, id := range excludeIDs {
+			excluded = excluded || n.id == id
+		}
+		if !excluded && (selected == nil || n.replicas < selected.replicas) {
+			selected = n
+		}
+	}
+	return selected
+}
+
+func (cm *clusterManager) checkNodeIsEmpty(addr string) error {
+	stdout, err := cm.doString(addr, "cluster", "info")
+	if err != nil {
+		return err
+	}
+	info, err := NewRedisClusterInfo(stdout)
+	if err != nil {
+		return err
+	}
+	dbsize, err := cm.doInt(addr, "dbsize")
+	if err != nil {
+		return err
+	}
+	if (*info)["cluster_known_nodes"] != "1" || dbsize != 0 {
+		return fmt.Errorf("Node %s is not empty. Either the node already knows other nodes (check with CLUSTER NODES) or contains some key in database 0.", addr)
+	}
+	return nil
+}
+
+// Moves the keys of the slot from the source to the target and assigns the slot to the target on all the leaders
+func (cm *clusterManager) moveSlot(source *clusterManagerNode, target *clusterManagerNode, slot int, replace bool) error {
+	cm.out.WriteString(fmt.Sprintf("Moving slot %d from %s to %s: ", slot, source.addr, target.addr))
+	if _, err := cm.do(target.addr, "cluster", "setslot", slot, "importing", source.id); err != nil {
+		return err
+	}
+	if _, err := cm.do(source.addr, "cluster", "setslot", slot, "migrating", target.id); err != nil {
+		return err
+	}
+	if err := cm.migrateKeys(source, target, slot, replace); err != nil {
+		return err
+	}
+	// The target is set first so the slot can not be lost if the source fails in between
+	for _, n := range append([]*clusterManagerNode{target, source}, cm.leaders()...) {
+		if _, err := cm.do(n.addr, "cluster", "setslot", slot, "node", target.id); err != nil && (n == target || n == source) {
+			return err
+		}
+	}
+	source.slots = removeSlot(source.slots, slot)
+	target.slots = append(target.slots, slot)
+	cm.printf("OK")
+	return nil
+}
+
+func (cm *clusterManager) migrateKeys(source *clusterManagerNode, target *clusterManagerNode, slot int, replace bool) error {
+	ip, port := splitAddr(target.addr)
+	for {
+		reply, err := cm.do(source.addr, "cluster", "getkeysinslot", slot, cm.pipeline)
+		if err != nil {
+			return err
+		}
+		keys, _ := reply.([]interface{})
+		if len(keys) == 0 {
+			return nil
+		}
+		args := []interface{}{"migrate", ip, port, "", 0, clusterManagerMigrateTimeout}
+		if replace {
+			args = append(args, "replace")
+		}
+		args = append(args, cm.migrateAuthArgs()...)
+		args = append(args, "keys")
+		args = append(args, keys...)
+		if _, err = cm.do(source.addr, args...); err != nil {
+			if !replace && errorStringMatch(err, "BUSYKEY") {
+				cm.printf("*** Target key exists, replacing it for FIX.")
+				replace = true
+				continue
+			}
+			return err
+		}
+		cm.out.WriteString(".")
+		cm.movedKeys += int64(len(keys))
+		if !cm.throttle.wait(cm.ctx, len(keys)) {
+			return cm.ctx.Err()
+		}
+	}
+}
+
+func (cm *clusterManager) migrateAuthArgs() []interface{} {
+	if cm.password == "" {
+		return nil
+	}
+	if cm.user != "" {
+		return []interface{}{"auth2", cm.user, cm.password}
+	}
+	return []interface{}{"auth", cm.password}
+}
+
+func (cm *clusterManager) fixOpenSlot(slot int) error {
+	cm.printf(">>> Fixing open slot %d", slot)
+	var owner *clusterManagerNode
+	for _, n := range cm.leaders() {
+		if containsSlot(n.slots, slot) {
+			owner = n
+		}
+	}
+	keysPerNode := map[*clusterManagerNode]int64{}
+	for _, n := range cm.leaders() {
+		count, err := cm.doInt(n.addr, "cluster", "countkeysinslot", slot)
+		if err != nil {
+			return err
+		}
+		keysPerNode[n] = count
+		if owner == nil || (!containsSlot(owner.slots, slot) && count > keysPerNode[owner]) {
+			owner = n
+		}
+	}
+	if owner == nil {
+		return fmt.Errorf("no master was found to own slot %d", slot)
+	}
+	cm.printf("Set as owner for slot %d: %s", slot, owner.addr)
+	if !containsSlot(owner.slots, slot) {
+		if _, err := cm.do(owner.addr, "cluster", "addslots", slot); err != nil {
+			return err
+		}
+		cm.do(owner.addr, "cluster", "bumpepoch")
+		owner.slots = append(owner.slots, slot)
+	}
+	for n, count := range keysPerNode {
+		if n == owner || count == 0 {
+			continue
+		}
+		cm.printf("Moving the keys of slot %d from %s to the owner", slot, n.addr)
+		if _, err := cm.do(owner.addr, "cluster", "setslot", slot, "importing", n.id); err != nil {
+			return err
+		}
+		if _, err := cm.do(n.addr, "cluster", "setslot", slot, "migrating", owner.id); err != nil {
+			return err
+		}
+		if err := cm.migrateKeys(n, owner, slot, true); err != nil {
+			return err
+		}
+	}
+	for _, n := range cm.nodes {
+		if _, open := n.migrating[slot]; open {
+			cm.do(n.addr, "cluster", "setslot", slot, "stable")
+		}
+		if _, open := n.importing[slot]; open {
+			cm.do(n.addr, "cluster", "setslot", slot, "stable")
+		}
+	}
+	for _, n := range append([]*clusterManagerNode{owner}, cm.leaders()...) {
+		cm.do(n.addr, "cluster", "setslot", slot, "node", owner.id)
+	}
+	return nil
+}
+
+func (cm *clusterManager) fixSlotsCoverage(covered map[int]*clusterManagerNode) error {
+	leaders := cm.leaders()
+	if len(leaders) == 0 {
+		return fmt.Errorf("no reachable master was found to cover the slots")
+	}
+	cm.printf(">>> Fixing slots coverage...")
+	sort.SliceStable(leaders, func(i, j int) bool { return len(leaders[i].slots) < len(leaders[j].slots) })
+	next := 0
+	for slot := 0; slot < MAX_SLOTS_PER_LEADER; slot++ {
+		if _, exists := covered[slot]; exists {
+			continue
+		}
+		var owner *clusterManagerNode
+		var ownerKeys int64
+		var withKeys []*clusterManagerNode
+		for _, n := range leaders {
+			count, err := cm.doInt(n.addr, "cluster", "countkeysinslot", slot)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				withKeys = append(withKeys, n)
+			}
+			if count > ownerKeys {
+				owner, ownerKeys = n, count
+			}
+		}
+		if owner == nil {
+			// Slots without keys are spread between the leaders, starting with the leaders that hold the fewest slots
+			owner = leaders[next%len(leaders)]
+			next++
+		}
+		if _, err := cm.do(owner.addr, "cluster", "addslots", slot); err != nil {
+			return err
+		}
+		owner.slots = append(owner.slots, slot)
+		for _, n := range withKeys {
+			if n == owner {
+				continue
+			}
+			if _, err := cm.do(owner.addr, "cluster", "setslot", slot, "importing", n.id); err != nil {
+				return err
+			}
+			if _, err := cm.do(n.addr, "cluster", "setslot", slot, "migrating", owner.id); err != nil {
+				return err
+			}
+			if err := cm.migrateKeys(n, owner, slot, true); err != nil {
+				return err
+			}
+			cm.do(n.addr, "cluster", "setslot", slot, "stable")
+			cm.do(owner.addr, "cluster", "setslot", slot, "stable")
+		}
+	}
+	for _, n := range leaders {
+		cm.printf("Covered %d slots with %s", len(n.slots), n.addr)
+		cm.do(n.addr, "cluster", "bumpepoch")
+	}
+	return nil
+}
+
+func (n *clusterManagerNode) isLeader() bool {
+	return hasNodeFlag(n.flags, "master")
+}
+
+type slotMove struct {
+	source *clusterManagerNode
+	slot   int
+}
+
+// Splits the slots to move between the sources in proportion to the number of slots each of them holds,
+// the same way redis-cli does
+func computeReshardTable(sources []*clusterManagerNode, numSlots int) []slotMove {
+	sorted := append([]*clusterManagerNode{}, sources...)
+	sort.SliceStable(sorted, func(i, j int) bool { return len(sorted[i].slots) > len(sorted[j].slots) })
+	totalSlots := 0
+	for _, source := range sorted {
+		totalSlots += len(source.slots)
+	}
+	var moves []slotMove
+	if totalSlots == 0 {
+		return moves
+	}
+	for i, source := range sorted {
+		n := float64(numSlots) / float64(totalSlots) * float64(len(source.slots))
+		if i == 0 {
+			n = math.Ceil(n)
+		} else {
+			n = math.Floor(n)
+		}
+		slots := append([]int{}, source.slots...)
+		sort.Ints(slots)
+		for j := 0; j < int(n) && j < len(slots); j++ {
+			moves = append(moves, slotMove{source: source, slot: slots[j]})
+		}
+	}
+	return moves
+}
+
+// Returns for each leader the number of slots it should give (positive) or receive (negative) so all the leaders
+// hold a number of slots in proportion to their weight, and whether one of the leaders is off by more than the threshold percentage
+func computeRebalanceBalances(slots []int, weights []float64, threshold float64) ([]int, bool) {
+	totalWeight := 0.0
+	for _, w := range weights {
+		totalWeight += w
+	}
+	balances := make([]int, len(slots))
+	totalBalance := 0
+	thresholdReached := false
+	for i, s := range slots {
+		expected := float64(MAX_SLOTS_PER_LEADER) / totalWeight * weights[i]
+		balances[i] = s - int(expected)
+		totalBalance += balances[i]
+		if expected == 0 && s > 0 || expected > 0 && math.Abs(1-float64(s)/expected)*100 > threshold {
+			thresholdReached = true
+		}
+	}
+	// The rounding of the expected slots leaves a positive total balance, it is taken from the leaders that do not give slots
+	for totalBalance > 0 {
+		for i := range balances {
+			if balances[i] <= 0 && totalBalance > 0 {
+				balances[i]--
+				totalBalance--
+			}
+		}
+	}
+	return balances, thresholdReached
+}
+
+// WeightedSlotTargets returns the number of slots every leader holds after a rebalance by the given weights,
+// from the number of slots the leaders hold now
+func WeightedSlotTargets(slots []int, weights []float64) []int {
+	balances, _ := computeRebalanceBalances(slots, weights, 0)
+	targets := make([]int, len(slots))
+	for i := range slots {
+		targets[i] = slots[i] - balances[i]
+	}
+	return targets
+}
+
+// Splits the slots evenly between the leaders, the same way redis-cli does
+func allocateSlots(leaders int) [][]int {
+	allocation := make([][]int, leaders)
+	slotsPerLeader := float64(MAX_SLOTS_PER_LEADER) / float64(leaders)
+	first := 0
+	cursor := 0.0
+	for i := 0; i < leaders; i++ {
+		last := int(math.Round(cursor + slotsPerLeader - 1))
+		if last > MAX_SLOTS_PER_LEADER-1 || i == leaders-1 {
+			last = MAX_SLOTS_PER_LEADER - 1
+		}
+		if last < first {
+			last = first
+		}
+		for slot := first; slot <= last; slot++ {
+			allocation[i] = append(allocation[i], slot)
+		}
+		first = last + 1
+		cursor += slotsPerLeader
+	}
+	return allocation
+}
+
+// Parses the slots column of a CLUSTER NODES line, open slots are reported in the format [<slot>->-<id>] and [<slot>-<-<id>]
+func parseNodeSlots(slotsField string) ([]int, map[int]string, map[int]string) {
+	var slots []int
+	migrating := map[int]string{}
+	importing := map[int]string{}
+	for _, token := range strings.Fields(slotsField) {
+		if strings.HasPrefix(token, "[") {
+			token = strings.Trim(token, "[]")
+			if parts := strings.SplitN(token, "->-", 2); len(parts) == 2 {
+				if slot, err := strconv.Atoi(parts[0]); err == nil {
+					migrating[slot] = parts[1]
+				}
+			} else if parts := strings.SplitN(token, "-<-", 2); len(parts) == 2 {
+				if slot, err := strconv.Atoi(parts[0]); err == nil {
+					importing[slot] = parts[1]
+				}
+			}
+			continue
+		}
+		bounds := strings.SplitN(token, "-", 2)
+		start, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			continue
+		}
+		end := start
+		if len(bounds) == 2 {
+			if end, err = strconv.Atoi(bounds[1]); err != nil {
+				continue
+			}
+		}
+		for slot := start; slot <= end; slot++ {
+			slots = append(slots, slot)
+		}
+	}
+	return slots, migrating, importing
+}
+
+// Builds a signature of the slots owned by each leader in a CLUSTER NODES output, nodes that agree about
+// the slots configuration have the same signature
+func clusterConfigSignature(clusterNodes string) string {
+	var entries []string
+	for _, n := range *NewRedisClusterNodes(clusterNodes) {
+		slots, _, _ := parseNodeSlots(n.Slots)
+		if hasNodeFlag(n.Flags, "master") && len(slots) > 0 {
+			entries = append(entries, n.ID+":"+formatSlotRanges(slots))
+		}
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, "|")
+}
+
+func formatSlotRanges(slots []int) string {
+	sorted := append([]int{}, slots...)
+	sort.Ints(sorted)
+	var ranges []string
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprintf("[%d]", sorted[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("[%d-%d]", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(ranges, ",")
+}
+
+func formatSlotList(slots []int) string {
+	list := make([]string, len(slots))
+	for i, slot := range slots {
+		list[i] = strconv.Itoa(slot)
+	}
+	return strings.Join(list, ",")
+}
+
+func joinSlots(openSlots map[int]string) string {
+	var slots []int
+	for slot := range openSlots {
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+	return formatSlotList(slots)
+}
+
+func containsSlot(slots []int, slot int) bool {
+	for _, s := range slots {
+		if s == slot {
+			return true
+		}
+	}
+	return false
+}
+
+func removeSlot(slots []int, slot int) []int {
+	var remaining []int
+	for _, s := range slots {
+		if s != slot {
+			remaining = append(remaining, s)
+		}
+	}
+	return remaining
+}
+
+func hasNodeFlag(flags string, flag string) bool {
+	for _, f := range strings.Split(flags, ",") {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// Strips the cluster bus port and the hostname from an address of the CLUSTER NODES output, IPv6 addresses
+// are enclosed in brackets
+func nodeAddr(addr string) string {
+	ip, port, _ := splitNodeAddr(addr)
+	return net.JoinHostPort(ip, port)
+}
+
+// Splits an address of the CLUSTER NODES output, <ip>:<port>@<cluster bus port>[,<hostname>], into its IP, port
+// and announced host name. The IPv6 addresses are not enclosed in brackets in this output.
+func splitNodeAddr(addr string) (string, string, string) {
+	hostname := ""
+	if i := strings.Index(addr, ","); i >= 0 {
+		addr, hostname = addr[:i], addr[i+1:]
+	}
+	if i := strings.Index(addr, "@"); i >= 0 {
+		addr = addr[:i]
+	}
+	i := strings.LastIndex(addr, ":")
+	if i < 0 {
+		return addr, REDIS_DEFAULT_PORT, hostname
+	}
+	return strings.Trim(addr[:i], "[]"), addr[i+1:], hostname
+}
+
+func splitAddr(addr string) (string, string) {
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		return host, port
+	}
+	i := strings.LastIndex(addr, ":")
+	if i < 0 || net.ParseIP(addr) != nil {
+		return strings.Trim(addr, "[]"), REDIS_DEFAULT_PORT
+	}
+	return addr[:i], addr[i+1:]
+}
+
+func parseClusterManagerArgs(args []string, defaultPort string) clusterManagerArgs {
+	a := clusterManagerArgs{flags: map[string]string{}, weights: map[string]float64{}}
+	for i := 0; i < len(args); i++ {
+		if args[i] == "--cluster-weight" {
+			// The weights follow the flag as <node id prefix>=<weight> arguments, as redis-cli takes them
+			for i+1 < len(args) && !strings.HasPrefix(args[i+1], "--") && strings.Contains(args[i+1], "=") {
+				i++
+				pair := strings.SplitN(args[i], "=", 2)
+				if weight, err := strconv.ParseFloat(pair[1], 64); err == nil {
+					a.weights[pair[0]] = weight
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(args[i], "--") {
+			if clusterManagerValueFlags[args[i]] && i+1 < len(args) {
+				a.flags[args[i]] = args[i+1]
+				i++
+			} else {
+				a.flags[args[i]] = ""
+			}
+			continue
+		}
+		if strings.Contains(args[i], ".") || strings.Contains(args[i], ":") {
+			a.addrs = append(a.addrs, addressPortDecider(args[i], defaultPort))
+		} else {
+			a.addrs = append(a.addrs, args[i])
+		}
+	}
+	return a
+}
+
+func (a clusterManagerArgs) has(flag string) bool {
+	_, exists := a.flags[flag]
+	return exists
+}
+
+// Returns the weight of the node by the first weight whose node ID prefix it matches, 1 when none does
+func (a clusterManagerArgs) weight(id string) float64 {
+	for prefix, weight := range a.weights {
+		if strings.HasPrefix(id, prefix) {
+			return weight
+		}
+	}
+	return 1
+}
@@ -6,6 +6,7 @@ https://github.com/redis/redis/blob/unstable/src/cluster.c
 package rediscli
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/pkg/errors"
@@ -83,3 +84,29 @@ func IsError(message string) bool {
 	}
 	return IsGenericError(errors.Errorf(message))
 }
+
+// CommandError is returned by the native command handler when a node replies with an error or can not be reached
+type CommandError struct {
+	Addr    string
+	Command []string
+	Err     error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("%s [%s]: %v", strings.Join(e.Command, " "), e.Addr, e.Err)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// ClusterManagerError is returned by the native command handler when a '--cluster' subcommand fails,
+// the progress that was made before the failure is kept in the output
+type ClusterManagerError struct {
+	Subcommand string
+	Reason     string
+}
+
+func (e *ClusterManagerError) Error() string {
+	return fmt.Sprintf("[ERR] cluster %s: %s", e.Subcommand, e.Reason)
+}
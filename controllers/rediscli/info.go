@@ -161,7 +161,7 @@ func NewRedisClusterNodes(rawData string) *RedisClusterNodes {
 				PingRecv:    nodeInfo[5],
 				ConfigEpoch: nodeInfo[6],
 				LinkState:   nodeInfo[7],
-				Slots:       nodeInfo[8],
+				Slots:       strings.Join(nodeInfo[8:], " "),
 			})
 		} else if len(nodeInfo) >= 8 {
 			nodes = append(nodes, RedisClusterNode{
@@ -226,19 +226,19 @@ func (r *RedisInfo) GetLoadETA() string {
 func (r *RedisClusterNodes) GetIPForID(id string) (string, string) {
 	for _, info := range *r {
 		if info.ID == id {
-			ipPort := strings.Split(info.Addr, ":")
-			return ipPort[0], ipPort[1]
+			ip, port, _ := splitNodeAddr(info.Addr)
+			return ip, port
 		}
 	}
 	return "", ""
 }
 
-// Returns the Redis node ID for a specified IP or empty string if IP not found
-// Supports the IP and IP:port format
+// Returns the Redis node ID for a specified IP or announced host name, or empty string if it is not found
+// Supports the <host> and <host>:<port> formats
 func (r *RedisClusterNodes) GetIDForIP(ip string) string {
-	ipPort := strings.Split(ip, ":")
+	host, _ := splitAddr(ip)
 	for _, info := range *r {
-		if strings.Split(info.Addr, ":")[0] == ipPort[0] {
+		if nodeIP, _, hostname := splitNodeAddr(info.Addr); nodeIP == host || (hostname != "" && hostname == host) {
 			return info.ID
 		}
 	}
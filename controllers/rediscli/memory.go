@@ -0,0 +1,187 @@
+package rediscli
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+/*
+	The memory analyzer estimates how the memory of a leader is spread over its slots, since the same number of slots
+	does not hold the same memory when some hash tags are hot or large. The keys of every slot are counted by
+	CLUSTER COUNTKEYSINSLOT, the first keys of the slot are taken by CLUSTER GETKEYSINSLOT as a sample and sized by
+	MEMORY USAGE, and the memory of the slot is estimated by the average size of the sample. Like the resharding
+	engine it runs over the native go client whatever the handler of the RedisCLI is.
+	The planner evens out the used memory of the leaders: it moves slots from the leader furthest above the mean to
+	the leader furthest below it, the largest slot that narrows the gap between them first, until every leader is
+	within the tolerance of the mean or no slot narrows the gap.
+*/
+
+// SlotMemory is the estimated memory of the keys of a slot
+type SlotMemory struct {
+	Slot  int
+	Keys  int64
+	Bytes int64
+}
+
+// LeaderMemory is the used memory of a leader and the estimated memory of its slots
+type LeaderMemory struct {
+	ID         string
+	UsedMemory int64
+	Slots      []SlotMemory
+}
+
+// MemoryMove is a move of slots between two leaders that evens out their used memory
+type MemoryMove struct {
+	SourceID string
+	TargetID string
+	Slots    []int
+	Keys     int64
+	Bytes    int64
+}
+
+// SampleSlotsMemory estimates the memory of every slot the node holds, by the given number of sampled keys per slot
+func (r *RedisCLI) SampleSlotsMemory(nodeIP string, samplesPerSlot int) ([]SlotMemory, error) {
+	user := ""
+	if r.Auth != nil {
+		user = r.Auth.User
+	}
+	cm := newClusterManager(r.context(), r.nativeHandler(), user, r.Auth.password(), r.TLS, r.Port)
+	addr := addressPortDecider(nodeIP, r.Port)
+
+	ctx, cancel := context.WithTimeout(r.context(), defaultRedisCliTimeout)
+	cm.ctx = ctx
+	nodes, err := cm.doString(addr, "cluster", "nodes")
+	cancel()
+	if err != nil {
+		return nil, errors.Wrapf(err, "Failed to read the nodes table of [%s]", nodeIP)
+	}
+	var slots []int
+	for _, line := range strings.Split(nodes, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 8 && hasNodeFlag(fields[2], "myself") {
+			slots, _, _ = parseNodeSlots(strings.Join(fields[8:], " "))
+			break
+		}
+	}
+	sort.Ints(slots)
+
+	usage := make([]SlotMemory, 0, len(slots))
+	for _, slot := range slots {
+		ctx, cancel := context.WithTimeout(r.context(), defaultRedisCliTimeout)
+		cm.ctx = ctx
+		slotMemory, err := cm.sampleSlotMemory(addr, slot, samplesPerSlot)
+		cancel()
+		if err != nil {
+			return usage, errors.Wrapf(err, "Failed to sample slot %d of [%s]", slot, nodeIP)
+		}
+		usage = append(usage, slotMemory)
+	}
+	return usage, nil
+}
+
+func (cm *clusterManager) sampleSlotMemory(addr string, slot int, samples int) (SlotMemory, error) {
+	slotMemory := SlotMemory{Slot: slot}
+	keys, err := cm.doInt(addr, "cluster", "countkeysinslot", slot)
+	if err != nil || keys == 0 || samples <= 0 {
+		return slotMemory, err
+	}
+	slotMemory.Keys = keys
+	reply, err := cm.do(addr, "cluster", "getkeysinslot", slot, samples)
+	if err != nil {
+		return slotMemory, err
+	}
+	sampled, sampledBytes := int64(0), int64(0)
+	sample, _ := reply.([]interface{})
+	for _, key := range sample {
+		size, err := cm.do(addr, "memory", "usage", key)
+		if err != nil {
+			return slotMemory, err
+		}
+		// The key may have expired since it was sampled
+		if bytes, isInt := size.(int64); isInt {
+			sampled++
+			sampledBytes += bytes
+		}
+	}
+	if sampled > 0 {
+		slotMemory.Bytes = sampledBytes * keys / sampled
+	}
+	return slotMemory, nil
+}
+
+// PlanMemoryRebalance returns the moves of slots that bring the used memory of every leader within the tolerance
+// percentage of the mean
+func PlanMemoryRebalance(leaders []LeaderMemory, tolerancePercent float64) []MemoryMove {
+	if len(leaders) < 2 {
+		return nil
+	}
+	total := int64(0)
+	for _, leader := range leaders {
+		total += leader.UsedMemory
+	}
+	mean := total / int64(len(leaders))
+	tolerance := int64(float64(mean) * tolerancePercent / 100)
+	excess := make([]int64, len(leaders))
+	movable := make([][]SlotMemory, len(leaders))
+	for i, leader := range leaders {
+		excess[i] = leader.UsedMemory - mean
+		for _, slot := range leader.Slots {
+			if slot.Bytes > 0 {
+				movable[i] = append(movable[i], slot)
+			}
+		}
+		sort.SliceStable(movable[i], func(a, b int) bool { return movable[i][a].Bytes > movable[i][b].Bytes })
+	}
+
+	var moves []*MemoryMove
+	movesByPair := map[[2]int]*MemoryMove{}
+	for {
+		source, target := 0, 0
+		for i := range excess {
+			if excess[i] > excess[source] {
+				source = i
+			}
+			if excess[i] < excess[target] {
+				target = i
+			}
+		}
+		if excess[source] <= tolerance && -excess[target] <= tolerance {
+			break
+		}
+		// A slot smaller than the gap narrows it, the largest one first
+		gap := excess[source] - excess[target]
+		picked := -1
+		for i, slot := range movable[source] {
+			if slot.Bytes < gap {
+				picked = i
+				break
+			}
+		}
+		if picked < 0 {
+			break
+		}
+		slot := movable[source][picked]
+		movable[source] = append(movable[source][:picked], movable[source][picked+1:]...)
+		excess[source] -= slot.Bytes
+		excess[target] += slot.Bytes
+		move, exists := movesByPair[[2]int{source, target}]
+		if !exists {
+			move = &MemoryMove{SourceID: leaders[source].ID, TargetID: leaders[target].ID}
+			movesByPair[[2]int{source, target}] = move
+			moves = append(moves, move)
+		}
+		move.Slots = append(move.Slots, slot.Slot)
+		move.Keys += slot.Keys
+		move.Bytes += slot.Bytes
+	}
+
+	plan := make([]MemoryMove, len(moves))
+	for i, move := range moves {
+		sort.Ints(move.Slots)
+		plan[i] = *move
+	}
+	return plan
+}
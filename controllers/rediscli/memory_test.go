@@ -0,0 +1,31 @@
+package rediscli
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanMemoryRebalance(test *testing.T) {
+	leaders := []LeaderMemory{
+		{ID: "a", UsedMemory: 1000, Slots: []SlotMemory{{Slot: 0, Keys: 10, Bytes: 400}, {Slot: 1, Keys: 5, Bytes: 200}, {Slot: 2, Keys: 1, Bytes: 50}}},
+		{ID: "b", UsedMemory: 500, Slots: []SlotMemory{{Slot: 3, Keys: 2, Bytes: 100}}},
+		{ID: "c", UsedMemory: 300},
+	}
+	moves := PlanMemoryRebalance(leaders, 5)
+	// The slot of 400 bytes narrows the gap of 700 between a and c to 100, c has no slot to move to b afterwards
+	expected := []MemoryMove{
+		{SourceID: "a", TargetID: "c", Slots: []int{0}, Keys: 10, Bytes: 400},
+	}
+	if !reflect.DeepEqual(moves, expected) {
+		test.Errorf("Expected the moves %+v, got %+v", expected, moves)
+	}
+
+	if moves := PlanMemoryRebalance([]LeaderMemory{{ID: "a", UsedMemory: 500}, {ID: "b", UsedMemory: 510}}, 5); len(moves) != 0 {
+		test.Errorf("Expected no moves for leaders within the tolerance, got %+v", moves)
+	}
+
+	// The slot of 300 bytes is larger than the gap of 200, moving it would widen the gap
+	if moves := PlanMemoryRebalance([]LeaderMemory{{ID: "a", UsedMemory: 600, Slots: []SlotMemory{{Slot: 0, Keys: 1, Bytes: 300}}}, {ID: "b", UsedMemory: 400}}, 5); len(moves) != 0 {
+		test.Errorf("Expected no moves of slots larger than the gap, got %+v", moves)
+	}
+}
@@ -0,0 +1,272 @@
+package rediscli
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+/*
+	NativeCommandHandler executes the commands built for redis-cli over the Redis protocol by go-redis, without
+	starting a redis-cli process per command. The connections are pooled per node address and user, and every
+	command is bounded by the same timeout that is used for the redis-cli process, within the context of the RedisCLI.
+
+	The replies are received typed, and are rendered in the redis-cli raw output format so the existing
+	parsers of the RedisCLI methods keep working on both handlers. Error replies and connection failures are
+	returned as *CommandError, the '--cluster' subcommands are implemented in Go by the cluster manager and
+	their failures are returned as *ClusterManagerError.
+
+	The password is taken from the credentials of the RedisCLI, or from the REDISCLI_AUTH environment variable
+	same as redis-cli does. The '--tls' flags added by buildCommand turn on TLS for the connections, the
+	certificate files are loaded once per pooled connection.
+*/
+
+const (
+	nativeClientDialTimeout = 5 * time.Second
+	nativeClientPoolSize    = 4
+	defaultRedisHost        = "127.0.0.1"
+)
+
+type NativeCommandHandler struct {
+	mutex   sync.Mutex
+	clients map[string]*redis.Client
+}
+
+// The arguments of a command that was built for redis-cli
+type nativeCommand struct {
+	host    string
+	port    string
+	user    string
+	tls     *RedisTLS
+	cluster string
+	args    []string
+}
+
+func NewNativeCommandHandler() *NativeCommandHandler {
+	return &NativeCommandHandler{
+		clients: map[string]*redis.Client{},
+	}
+}
+
+func (h *NativeCommandHandler) buildRedisInfoModel(stdoutInfo string) (*RedisInfo, error) {
+	return NewRedisInfo(stdoutInfo)
+}
+
+func (h *NativeCommandHandler) buildRedisClusterInfoModel(stdoutInfo string) (*RedisClusterInfo, error) {
+	return NewRedisClusterInfo(stdoutInfo)
+}
+
+// The commands are built the same way for both handlers, the native handler parses back the redis-cli flags on execution
+func (h *NativeCommandHandler) buildCommand(routingPort string, args []string, auth *RedisAuth, tls *RedisTLS, opt ...string) ([]string, map[string]string) {
+	return (&RunTimeCommandHandler{}).buildCommand(routingPort, args, auth, tls, opt...)
+}
+
+// The piped arguments and the bash indicator are only needed to run redis-cli interactively, the native
+// handler answers the confirmations of the '--cluster' subcommands by itself
+func (h *NativeCommandHandler) executeCommand(ctx context.Context, auth *RedisAuth, pipedArgs []string, args []string, useBash bool, multipFactorForTimeout ...float64) (string, string, error) {
+	multipFactor := 1.0
+	if len(multipFactorForTimeout) > 0 {
+		multipFactor = multipFactorForTimeout[0]
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(multipFactor)*defaultRedisCliTimeout)
+	defer cancel()
+
+	cmd := parseNativeCommand(args)
+	password := auth.password()
+	if cmd.cluster != "" {
+		cm := newClusterManager(ctx, h, cmd.user, password, cmd.tls, cmd.port)
+		err := cm.run(cmd.cluster, cmd.args)
+		stdout := strings.TrimSpace(cm.output())
+		if err != nil {
+			return stdout, "", err
+		}
+		return stdout, "", nil
+	}
+	if len(cmd.args) == 0 {
+		return "", "", &CommandError{Addr: net.JoinHostPort(cmd.host, cmd.port), Err: fmt.Errorf("no command to execute")}
+	}
+	reply, err := h.do(ctx, net.JoinHostPort(cmd.host, cmd.port), cmd.user, password, cmd.tls, stringsToArgs(cmd.args)...)
+	if err != nil {
+		return strings.TrimSpace(err.Error()), "", err
+	}
+	return strings.TrimSpace(renderRawReply(reply)), "", nil
+}
+
+// Sends a command to the node and returns its typed reply
+func (h *NativeCommandHandler) do(ctx context.Context, addr string, user string, password string, tls *RedisTLS, args ...interface{}) (interface{}, error) {
+	client, err := h.client(addr, user, password, tls)
+	if err != nil {
+		return nil, &CommandError{Addr: addr, Command: argsToStrings(args), Err: err}
+	}
+	reply, err := client.Do(ctx, args...).Result()
+	if err == redis.Nil {
+		return nil, nil
+	}
+	if err != nil {
+		if _, isReplyError := err.(redis.Error); !isReplyError {
+			// The node might have been deleted, the connections to it are not kept in the pool
+			h.removeClient(addr, user, tls)
+		}
+		return nil, &CommandError{Addr: addr, Command: argsToStrings(args), Err: err}
+	}
+	return reply, nil
+}
+
+func (h *NativeCommandHandler) client(addr string, user string, password string, tls *RedisTLS) (*redis.Client, error) {
+	key := nativeClientKey(addr, user, tls)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if c, exists := h.clients[key]; exists {
+		if c.Options().Password == password {
+			return c, nil
+		}
+		c.Close()
+		delete(h.clients, key)
+	}
+	tlsConfig, err := tls.Config()
+	if err != nil {
+		return nil, err
+	}
+	c := redis.NewClient(&redis.Options{
+		Addr:        addr,
+		Username:    user,
+		Password:    password,
+		TLSConfig:   tlsConfig,
+		DialTimeout: nativeClientDialTimeout,
+		ReadTimeout: -1, // bounded by the context deadline of the command
+		PoolSize:    nativeClientPoolSize,
+		MaxRetries:  -1, // commands such as CLUSTER FAILOVER should not be sent twice
+	})
+	h.clients[key] = c
+	return c, nil
+}
+
+func (h *NativeCommandHandler) removeClient(addr string, user string, tls *RedisTLS) {
+	key := nativeClientKey(addr, user, tls)
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	if c, exists := h.clients[key]; exists {
+		c.Close()
+		delete(h.clients, key)
+	}
+}
+
+// The certificate files are part of the key, renewed certificates are written by the operator to new files
+func nativeClientKey(addr string, user string, tls *RedisTLS) string {
+	key := user + "@" + addr
+	if tls != nil {
+		key += "#" + tls.CACertFile + "," + tls.CertFile + "," + tls.KeyFile
+	}
+	return key
+}
+
+// Parses back the flags added by buildCommand. The arguments are taken as they are given, only a flag that was given
+// together with its value in a single argument (such as "--cluster reshard") is split into words
+func parseNativeCommand(args []string) nativeCommand {
+	cmd := nativeCommand{host: defaultRedisHost, port: REDIS_DEFAULT_PORT}
+	fields := make([]string, 0, len(args))
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") && strings.ContainsAny(arg, " \t") {
+			fields = append(fields, strings.Fields(arg)...)
+		} else {
+			fields = append(fields, arg)
+		}
+	}
+	for i := 0; i < len(fields); i++ {
+		hasValue := i+1 < len(fields)
+		switch {
+		case fields[i] == "-p" && hasValue:
+			i++
+			cmd.port = fields[i]
+		case fields[i] == "-h" && hasValue:
+			i++
+			cmd.host = fields[i]
+		case fields[i] == "--user" && hasValue:
+			i++
+			cmd.user = fields[i]
+		case fields[i] == "--tls":
+			cmd.tlsFiles()
+		case fields[i] == "--cacert" && hasValue:
+			i++
+			cmd.tlsFiles().CACertFile = fields[i]
+		case fields[i] == "--cert" && hasValue:
+			i++
+			cmd.tlsFiles().CertFile = fields[i]
+		case fields[i] == "--key" && hasValue:
+			i++
+			cmd.tlsFiles().KeyFile = fields[i]
+		case fields[i] == "--cluster" && hasValue:
+			cmd.cluster = strings.ToLower(fields[i+1])
+			cmd.args = fields[i+2:]
+			return cmd
+		default:
+			cmd.args = fields[i:]
+			return cmd
+		}
+	}
+	return cmd
+}
+
+func (cmd *nativeCommand) tlsFiles() *RedisTLS {
+	if cmd.tls == nil {
+		cmd.tls = &RedisTLS{}
+	}
+	return cmd.tls
+}
+
+// Renders a reply the way redis-cli prints it when its output is not a terminal
+func renderRawReply(reply interface{}) string {
+	switch r := reply.(type) {
+	case nil:
+		return ""
+	case string:
+		return r
+	case int64:
+		return strconv.FormatInt(r, 10)
+	case []interface{}:
+		lines := make([]string, 0, len(r))
+		for _, element := range r {
+			lines = append(lines, renderRawReply(element))
+		}
+		return strings.Join(lines, "\n")
+	case error:
+		return r.Error()
+	default:
+		return fmt.Sprint(r)
+	}
+}
+
+func stringsToArgs(args []string) []interface{} {
+	converted := make([]interface{}, len(args))
+	for i, arg := range args {
+		converted[i] = arg
+	}
+	return converted
+}
+
+// The passwords of the AUTH and AUTH2 options of MIGRATE are masked, the arguments are reported by the errors
+func argsToStrings(args []interface{}) []string {
+	converted := make([]string, len(args))
+	for i, arg := range args {
+		converted[i] = fmt.Sprint(arg)
+	}
+	for i := range converted {
+		switch strings.ToLower(converted[i]) {
+		case "auth":
+			if i+1 < len(converted) {
+				converted[i+1] = "***"
+			}
+		case "auth2":
+			if i+2 < len(converted) {
+				converted[i+2] = "***"
+			}
+		}
+	}
+	return converted
+}
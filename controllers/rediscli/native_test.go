@@ -0,0 +1,198 @@
+package rediscli
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseNativeCommand(test *testing.T) {
+	handler := NewNativeCommandHandler()
+	args, _ := handler.buildCommand("6379", []string{"-h", "10.0.0.1", "cluster", "failover"}, &RedisAuth{User: "admin"}, nil, "takeover")
+	cmd := parseNativeCommand(args)
+	expected := nativeCommand{host: "10.0.0.1", port: "6379", user: "admin", args: []string{"cluster", "failover", "takeover"}}
+	if !reflect.DeepEqual(cmd, expected) {
+		test.Errorf("Expected command %+v, got %+v", expected, cmd)
+	}
+
+	args, _ = handler.buildCommand("6379", []string{"--cluster reshard", "10.0.0.1:6379", "--cluster-from", "a", "--cluster-to", "b", "--cluster-slots", "16384", "--cluster-yes"}, nil, nil, "-p 6380")
+	cmd = parseNativeCommand(args)
+	if cmd.cluster != "reshard" || cmd.port != "6380" || cmd.user != "" {
+		test.Errorf("Expected a reshard on port 6380 without user, got %+v", cmd)
+	}
+
+	tls := &RedisTLS{CACertFile: "/tls/ca.crt", CertFile: "/tls/tls.crt", KeyFile: "/tls/tls.key"}
+	args, _ = handler.buildCommand("6379", []string{"-h", "10.0.0.1", "ping"}, nil, tls)
+	if cmd := parseNativeCommand(args); !reflect.DeepEqual(cmd.tls, tls) || !reflect.DeepEqual(cmd.args, []string{"ping"}) {
+		test.Errorf("Expected a ping over TLS with %+v, got %+v", tls, cmd)
+	}
+	a := parseClusterManagerArgs(cmd.args, cmd.port)
+	if !reflect.DeepEqual(a.addrs, []string{"10.0.0.1:6379"}) || a.flags["--cluster-from"] != "a" || a.flags["--cluster-to"] != "b" || a.flags["--cluster-slots"] != "16384" || !a.has("--cluster-yes") {
+		test.Errorf("Unexpected reshard arguments %+v", a)
+	}
+}
+
+func TestParseNativeCommandPasswordOnly(test *testing.T) {
+	handler := NewNativeCommandHandler()
+	args, _ := handler.buildCommand("6379", []string{"-h", "10.0.0.1", "ping"}, &RedisAuth{Password: "secret"}, nil)
+	for _, arg := range args {
+		if arg == "--user" {
+			test.Errorf("Expected no user flag without a user, got %q", args)
+		}
+	}
+	expected := nativeCommand{host: "10.0.0.1", port: "6379", args: []string{"ping"}}
+	if cmd := parseNativeCommand(args); !reflect.DeepEqual(cmd, expected) {
+		test.Errorf("Expected command %+v, got %+v", expected, cmd)
+	}
+}
+
+func TestParseNativeCommandKeepsArguments(test *testing.T) {
+	handler := NewNativeCommandHandler()
+	args, _ := handler.buildCommand("6379", []string{"-h", "10.0.0.1", "set", "greeting", "hello  world"}, nil, nil)
+	if cmd := parseNativeCommand(args); !reflect.DeepEqual(cmd.args, []string{"set", "greeting", "hello  world"}) {
+		test.Errorf("Expected the value with spaces to be kept, got %q", cmd.args)
+	}
+}
+
+func TestNativeCommandContext(test *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	handler := NewNativeCommandHandler()
+	args, _ := handler.buildCommand("6379", []string{"-h", "127.0.0.1", "ping"}, nil, nil)
+	start := time.Now()
+	_, _, err := handler.executeCommand(ctx, nil, nil, args, false)
+	if !errors.Is(err, context.Canceled) {
+		test.Errorf("Expected the command to be cancelled by the context, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		test.Errorf("Expected the cancelled command to return at once, it took %v", elapsed)
+	}
+}
+
+func TestAddressPortDecider(test *testing.T) {
+	cases := map[string]string{
+		"10.0.0.1":                           "10.0.0.1:6379",
+		"10.0.0.1:":                          "10.0.0.1:6379",
+		"10.0.0.1:6380":                      "10.0.0.1:6380",
+		"fd00::1":                            "[fd00::1]:6379",
+		"[fd00::1]":                          "[fd00::1]:6379",
+		"[fd00::1]:6380":                     "[fd00::1]:6380",
+		"redis-node-0.rdc-nodes.default.svc": "redis-node-0.rdc-nodes.default.svc:6379",
+		"redis-node-0.rdc-nodes.default.svc:6380": "redis-node-0.rdc-nodes.default.svc:6380",
+	}
+	for address, expected := range cases {
+		if full := addressPortDecider(address, "6379"); full != expected {
+			test.Errorf("Expected address %s to be completed as %s, got %s", address, expected, full)
+		}
+	}
+}
+
+func TestSplitNodeAddr(test *testing.T) {
+	cases := map[string][3]string{
+		"10.0.0.1:6379@16379": {"10.0.0.1", "6379", ""},
+		"fd00::1:6379@16379":  {"fd00::1", "6379", ""},
+		"10.0.0.1:6379@16379,redis-node-0.rdc-nodes.default.svc": {"10.0.0.1", "6379", "redis-node-0.rdc-nodes.default.svc"},
+	}
+	for addr, expected := range cases {
+		if ip, port, hostname := splitNodeAddr(addr); [3]string{ip, port, hostname} != expected {
+			test.Errorf("Expected address %s to be split into %v, got %v", addr, expected, [3]string{ip, port, hostname})
+		}
+	}
+	if addr := nodeAddr("fd00::1:6379@16379"); addr != "[fd00::1]:6379" {
+		test.Errorf("Expected the IPv6 node address to be enclosed in brackets, got %s", addr)
+	}
+	nodes := NewRedisClusterNodes("abc fd00::1:6379@16379,redis-node-0.rdc-nodes.default.svc myself,master - 0 0 1 connected 0-16383")
+	if id := nodes.GetIDForIP("redis-node-0.rdc-nodes.default.svc:6379"); id != "abc" {
+		test.Errorf("Expected the node to be found by its host name, got [%s]", id)
+	}
+	if ip, port := nodes.GetIPForID("abc"); ip != "fd00::1" || port != "6379" {
+		test.Errorf("Expected the IPv6 address of the node, got %s %s", ip, port)
+	}
+}
+
+func TestRenderRawReply(test *testing.T) {
+	cases := map[string]interface{}{
+		"OK":                                 "OK",
+		"42":                                 int64(42),
+		"":                                   nil,
+		"master\n3129\n10.0.0.2\n6379\n3129": []interface{}{"master", int64(3129), []interface{}{[]interface{}{"10.0.0.2", "6379", "3129"}}},
+	}
+	for expected, reply := range cases {
+		if rendered := renderRawReply(reply); rendered != expected {
+			test.Errorf("Expected reply %v to be rendered as %q, got %q", reply, expected, rendered)
+		}
+	}
+}
+
+func TestAllocateSlots(test *testing.T) {
+	allocation := allocateSlots(3)
+	expected := [][2]int{{0, 5460}, {5461, 10922}, {10923, 16383}}
+	for i, slots := range allocation {
+		if slots[0] != expected[i][0] || slots[len(slots)-1] != expected[i][1] || len(slots) != expected[i][1]-expected[i][0]+1 {
+			test.Errorf("Expected leader %d to get slots %v, got %s", i, expected[i], formatSlotRanges(slots))
+		}
+	}
+}
+
+func TestParseNodeSlots(test *testing.T) {
+	slots, migrating, importing := parseNodeSlots("0-2 5 [6->-abc] [7-<-def]")
+	if !reflect.DeepEqual(slots, []int{0, 1, 2, 5}) {
+		test.Errorf("Unexpected slots %v", slots)
+	}
+	if migrating[6] != "abc" || importing[7] != "def" {
+		test.Errorf("Unexpected open slots, migrating: %v, importing: %v", migrating, importing)
+	}
+	if ranges := formatSlotRanges(slots); ranges != "[0-2],[5]" {
+		test.Errorf("Unexpected slot ranges %s", ranges)
+	}
+}
+
+func TestComputeReshardTable(test *testing.T) {
+	source := &clusterManagerNode{id: "a", slots: []int{3, 1, 2}}
+	moves := computeReshardTable([]*clusterManagerNode{source}, MAX_SLOTS_PER_LEADER)
+	if len(moves) != 3 || moves[0].slot != 1 || moves[2].slot != 3 {
+		test.Errorf("Expected all the slots of the source to be moved in order, got %v", moves)
+	}
+
+	big := &clusterManagerNode{id: "b", slots: []int{0, 1, 2, 3}}
+	small := &clusterManagerNode{id: "c", slots: []int{4, 5}}
+	moves = computeReshardTable([]*clusterManagerNode{small, big}, 3)
+	if len(moves) != 3 || moves[0].source != big || moves[1].source != big || moves[2].source != small {
+		test.Errorf("Expected 2 slots from the bigger source and 1 from the smaller one, got %v", moves)
+	}
+}
+
+func TestComputeRebalanceBalances(test *testing.T) {
+	balances, needed := computeRebalanceBalances([]int{8192, 8192, 0}, []float64{1, 1, 1}, clusterManagerRebalanceThreshold)
+	if !needed {
+		test.Errorf("Expected a rebalance to be needed with an empty leader")
+	}
+	total := 0
+	for _, b := range balances {
+		total += b
+	}
+	if total != 0 || balances[2] >= 0 {
+		test.Errorf("Expected the empty leader to receive all the moved slots, got %v", balances)
+	}
+
+	if _, needed = computeRebalanceBalances([]int{5461, 5462, 5461}, []float64{1, 1, 1}, clusterManagerRebalanceThreshold); needed {
+		test.Errorf("Expected no rebalance to be needed for evenly spread slots")
+	}
+	if _, needed = computeRebalanceBalances([]int{5461, 5462, 5461}, []float64{2, 1, 1}, clusterManagerRebalanceThreshold); !needed {
+		test.Errorf("Expected a rebalance to be needed for a leader of a double weight")
+	}
+}
+
+func TestWeightedSlotTargets(test *testing.T) {
+	targets := WeightedSlotTargets([]int{5461, 5462, 5461}, []float64{2, 1, 1})
+	if targets[0] < 8191 || targets[0] > 8193 || targets[0]+targets[1]+targets[2] != MAX_SLOTS_PER_LEADER {
+		test.Errorf("Expected about half of the slots on the leader of a double weight, got %v", targets)
+	}
+
+	a := parseClusterManagerArgs([]string{"10.0.0.1:6379", "--cluster-weight", "abc=2", "def=0.5", "--cluster-use-empty-masters"}, "6379")
+	if a.weight("abcdef") != 2 || a.weight("def123") != 0.5 || a.weight("123") != 1 || !a.has("--cluster-use-empty-masters") || len(a.addrs) != 1 {
+		test.Errorf("Unexpected rebalance arguments %+v", a)
+	}
+}
@@ -0,0 +1,219 @@
+package rediscli
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+/*
+	The resharding engine moves all the slots of a leader to another leader slot by slot, by the steps of the
+	cluster manager: CLUSTER SETSLOT IMPORTING on the target, CLUSTER SETSLOT MIGRATING on the source, MIGRATE of
+	the keys of the slot in batches of the pipeline size, and CLUSTER SETSLOT NODE on the leaders.
+	It runs over the native go client whatever the handler of the RedisCLI is, and reports its progress to a journal
+	before every slot, so the caller can keep it and resume an interrupted reshard: a slot that was left open between
+	the source and the target is completed first, then the slots the source still holds are moved.
+	The MIGRATE batches are throttled by a limit of keys per second, to keep the latency of the clients low.
+*/
+
+const reshardSlotTimeout = 60 * time.Second
+
+// Used by the RedisCLI instances that run redis-cli processes, the connections are pooled for all of them
+var reshardHandler = NewNativeCommandHandler()
+
+// ReshardOptions tune a run of the resharding engine
+type ReshardOptions struct {
+	// The number of keys moved by a single MIGRATE command
+	Pipeline int
+	// The maximum number of keys moved per second, zero for no limit
+	KeysPerSecond int
+	// Called before every slot is migrated and when the reshard completes, the reshard stops if it returns an error
+	Journal func(ReshardProgress) error
+	// The slots to move, all the slots of the source when empty. The slots left open between the source
+	// and the target are completed either way.
+	Slots []int
+}
+
+// ReshardProgress is the progress of a run of the resharding engine
+type ReshardProgress struct {
+	SourceID string
+	TargetID string
+	// The slot that is being migrated, -1 when none
+	MigratingSlot int
+	// The slots that are left to move, including the migrating slot
+	PendingSlots []int
+	MovedSlots   int
+	MovedKeys    int64
+}
+
+type keysThrottle struct {
+	keysPerSecond int
+	start         time.Time
+	keys          int64
+}
+
+func newKeysThrottle(keysPerSecond int) *keysThrottle {
+	if keysPerSecond <= 0 {
+		return nil
+	}
+	return &keysThrottle{keysPerSecond: keysPerSecond, start: time.Now()}
+}
+
+// Returns how long to wait after the keys were moved, so the rate since the start stays within the limit
+func (t *keysThrottle) delay(keys int, now time.Time) time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.keys += int64(keys)
+	expected := time.Duration(float64(t.keys) / float64(t.keysPerSecond) * float64(time.Second))
+	if elapsed := now.Sub(t.start); expected > elapsed {
+		return expected - elapsed
+	}
+	return 0
+}
+
+// Waits after the keys were moved, returns false if the context is done meanwhile
+func (t *keysThrottle) wait(ctx context.Context, keys int) bool {
+	d := t.delay(keys, time.Now())
+	if d == 0 {
+		return true
+	}
+	select {
+	case <-ctx.Done():
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// SlotRanges formats the slots as the ranges of CLUSTER NODES, '0-5460' or '5461'
+func SlotRanges(slots []int) []string {
+	sorted := append([]int{}, slots...)
+	sort.Ints(sorted)
+	ranges := []string{}
+	for i := 0; i < len(sorted); {
+		j := i
+		for j+1 < len(sorted) && sorted[j+1] == sorted[j]+1 {
+			j++
+		}
+		if i == j {
+			ranges = append(ranges, fmt.Sprintf("%d", sorted[i]))
+		} else {
+			ranges = append(ranges, fmt.Sprintf("%d-%d", sorted[i], sorted[j]))
+		}
+		i = j + 1
+	}
+	return ranges
+}
+
+func (r *RedisCLI) nativeHandler() *NativeCommandHandler {
+	if h, isNative := r.Handler.(*NativeCommandHandler); isNative {
+		return h
+	}
+	return reshardHandler
+}
+
+// Returns the slots to move from the source to the target, the slots left open between them first, then the slots
+// of the source that are selected, or all of them when none is. Fails if a slot of the source is open to another node,
+// it should be fixed first.
+func reshardSlotsOrder(source *clusterManagerNode, target *clusterManagerNode, selected []int) ([]int, error) {
+	open := map[int]bool{}
+	for slot, id := range source.migrating {
+		if id != target.id {
+			return nil, errors.Errorf("Slot %d of the source is migrating to %s", slot, id)
+		}
+		open[slot] = true
+	}
+	for slot, id := range target.importing {
+		if id == source.id {
+			open[slot] = true
+		}
+	}
+	slots := []int{}
+	for slot := range open {
+		slots = append(slots, slot)
+	}
+	sort.Ints(slots)
+	owned := append([]int{}, source.slots...)
+	sort.Ints(owned)
+	for _, slot := range owned {
+		if !open[slot] && (len(selected) == 0 || containsSlot(selected, slot)) {
+			slots = append(slots, slot)
+		}
+	}
+	return slots, nil
+}
+
+// ReshardSlots moves the slots of the source leader to the target leader, slot by slot
+func (r *RedisCLI) ReshardSlots(nodeIP string, sourceID string, targetID string, opts ReshardOptions) (ReshardProgress, error) {
+	progress := ReshardProgress{SourceID: sourceID, TargetID: targetID, MigratingSlot: -1}
+	if r.skipMutation("Reshard", nodeIP, "reshard of the slots [%s]->[%s] by [%s]", sourceID, targetID, nodeIP) {
+		return progress, r.skippedError()
+	}
+	journal := opts.Journal
+	if journal == nil {
+		journal = func(ReshardProgress) error { return nil }
+	}
+	user := ""
+	if r.Auth != nil {
+		user = r.Auth.User
+	}
+	cm := newClusterManager(r.context(), r.nativeHandler(), user, r.Auth.password(), r.TLS, r.Port)
+	if opts.Pipeline > 0 {
+		cm.pipeline = opts.Pipeline
+	}
+	cm.throttle = newKeysThrottle(opts.KeysPerSecond)
+
+	ctx, cancel := context.WithTimeout(r.context(), defaultRedisCliTimeout)
+	cm.ctx = ctx
+	err := cm.loadCluster(addressPortDecider(nodeIP, r.Port))
+	cancel()
+	if err != nil {
+		return progress, errors.Wrap(err, "Failed to load the cluster for the reshard")
+	}
+	source, target := cm.nodeByID(sourceID), cm.nodeByID(targetID)
+	if source == nil || !source.isLeader() {
+		return progress, errors.Errorf("The source node (%s) is not known or is not a master", sourceID)
+	}
+	if target == nil || !target.isLeader() {
+		return progress, errors.Errorf("The target node (%s) is not known or is not a master", targetID)
+	}
+	if source.id == target.id {
+		return progress, errors.New("It is not possible to use the target node as source node")
+	}
+	slots, err := reshardSlotsOrder(source, target, opts.Slots)
+	if err != nil {
+		return progress, err
+	}
+	for i, slot := range slots {
+		progress.MigratingSlot = slot
+		progress.PendingSlots = slots[i:]
+		if err := journal(progress); err != nil {
+			return progress, errors.Wrap(err, "Failed to write the reshard journal")
+		}
+		timeout := reshardSlotTimeout
+		if opts.KeysPerSecond > 0 {
+			countCtx, cancel := context.WithTimeout(r.context(), defaultRedisCliTimeout)
+			cm.ctx = countCtx
+			if keys, err := cm.doInt(source.addr, "cluster", "countkeysinslot", slot); err == nil {
+				timeout += time.Duration(keys/int64(opts.KeysPerSecond)) * time.Second
+			}
+			cancel()
+		}
+		ctx, cancel := context.WithTimeout(r.context(), timeout)
+		cm.ctx = ctx
+		err := cm.moveSlot(source, target, slot, false)
+		cancel()
+		progress.MovedKeys = cm.movedKeys
+		if err != nil {
+			return progress, errors.Wrapf(err, "Failed to move slot %d", slot)
+		}
+		progress.MovedSlots++
+	}
+	progress.MigratingSlot = -1
+	progress.PendingSlots = nil
+	return progress, journal(progress)
+}
@@ -0,0 +1,48 @@
+package rediscli
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestReshardSlotsOrder(test *testing.T) {
+	source := &clusterManagerNode{id: "a", slots: []int{7, 3, 5, 4}, migrating: map[int]string{5: "b"}}
+	target := &clusterManagerNode{id: "b", slots: []int{0, 1}, importing: map[int]string{5: "a", 2: "a"}}
+	slots, err := reshardSlotsOrder(source, target, nil)
+	if err != nil {
+		test.Fatalf("Unexpected error %v", err)
+	}
+	if expected := []int{2, 5, 3, 4, 7}; !reflect.DeepEqual(slots, expected) {
+		test.Errorf("Expected the open slots first %v, got %v", expected, slots)
+	}
+
+	if slots, _ = reshardSlotsOrder(source, target, []int{4, 9}); !reflect.DeepEqual(slots, []int{2, 5, 4}) {
+		test.Errorf("Expected the open slots and the selected slots of the source, got %v", slots)
+	}
+
+	source.migrating[6] = "c"
+	if _, err := reshardSlotsOrder(source, target, nil); err == nil {
+		test.Error("Expected an error for a slot that is migrating to another node")
+	}
+}
+
+func TestKeysThrottle(test *testing.T) {
+	if d := newKeysThrottle(0).delay(1000, time.Now()); d != 0 {
+		test.Errorf("Expected no delay without a limit, got %v", d)
+	}
+	t := newKeysThrottle(100)
+	start := t.start
+	if d := t.delay(50, start.Add(100*time.Millisecond)); d != 400*time.Millisecond {
+		test.Errorf("Expected a delay of 400ms for 50 keys after 100ms, got %v", d)
+	}
+	if d := t.delay(50, start.Add(2*time.Second)); d != 0 {
+		test.Errorf("Expected no delay for 100 keys after 2s, got %v", d)
+	}
+}
+
+func TestSlotRanges(test *testing.T) {
+	if ranges := SlotRanges([]int{9, 0, 1, 2, 5}); !reflect.DeepEqual(ranges, []string{"0-2", "5", "9"}) {
+		test.Errorf("Unexpected slot ranges %v", ranges)
+	}
+}
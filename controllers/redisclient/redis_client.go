@@ -2,9 +2,10 @@ package redisclient
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
-	"regexp"
+	"net"
 	"strings"
 	"sync"
 
@@ -21,10 +22,16 @@ var clusterClient *RedisClusterClient = nil
 
 var lookups int = 5
 
-var format string = "MOVED\\s*\\d+\\s*(\\d+\\.\\d+\\.\\d+\\.\\d+:\\d+)"
-var comp *regexp.Regexp = regexp.MustCompile(format)
-
-func GetRedisClusterClient(v *view.RedisClusterView, cli *rediscli.RedisCLI) *RedisClusterClient {
+// Returns the client of the cluster with a connection to every node that is part of the cluster, fails if the
+// TLS configuration of the RedisCLI can not be loaded
+func GetRedisClusterClient(v *view.RedisClusterView, cli *rediscli.RedisCLI) (*RedisClusterClient, error) {
+	var tlsConfig *tls.Config
+	if cli.TLS != nil {
+		var err error
+		if tlsConfig, err = cli.TLS.Config(); err != nil {
+			return nil, fmt.Errorf("Could not load the TLS configuration of the cluster client: %v", err)
+		}
+	}
 	mutex := &sync.Mutex{}
 	mutex.Lock()
 	if clusterClient == nil {
@@ -40,15 +47,19 @@ func GetRedisClusterClient(v *view.RedisClusterView, cli *rediscli.RedisCLI) *Re
 		if err != nil || nodes == nil || len(*nodes) <= 1 {
 			continue
 		}
-		addr := n.Ip + ":" + cli.Port
-		clusterClient.clients[addr] = redis.NewClient(&redis.Options{
-			Addr:     addr,
-			Username: "admin",
-			Password: "adminpass",
-		})
+		addr := net.JoinHostPort(n.Ip, cli.Port)
+		options := &redis.Options{
+			Addr:      addr,
+			TLSConfig: tlsConfig,
+		}
+		if cli.Auth != nil {
+			options.Username = cli.Auth.User
+			options.Password = cli.Auth.Password
+		}
+		clusterClient.clients[addr] = redis.NewClient(options)
 	}
 	mutex.Unlock()
-	return clusterClient
+	return clusterClient, nil
 }
 
 func (c *RedisClusterClient) Set(key string, val interface{}, retries int) error {
@@ -141,16 +152,23 @@ func (c *RedisClusterClient) get(ctx context.Context, key string, addr string, l
 	return value, err
 }
 
+// Extracts the address of a MOVED redirection, 'MOVED <slot> <endpoint>:<port>', where the endpoint is an IPv4 or
+// an IPv6 address, which is not enclosed in brackets, or the host name announced by the node
 func (c *RedisClusterClient) extractAddress(msg string) string {
-	matchingStrings := comp.FindAllStringSubmatch(msg, -1)
-	for _, match := range matchingStrings {
-		if len(match) > 1 {
-			if len(match[1]) > 0 {
-				return match[1]
-			}
-		}
+	moved := strings.Index(msg, "MOVED")
+	if moved < 0 {
+		return ""
+	}
+	fields := strings.Fields(msg[moved:])
+	if len(fields) < 3 {
+		return ""
+	}
+	endpoint := fields[2]
+	i := strings.LastIndex(endpoint, ":")
+	if i < 0 {
+		return ""
 	}
-	return ""
+	return net.JoinHostPort(strings.Trim(endpoint[:i], "[]"), endpoint[i+1:])
 }
 
 func (c *RedisClusterClient) FlushAllData() {
@@ -0,0 +1,25 @@
+package redisclient
+
+import (
+	"testing"
+)
+
+func TestExtractAddress(test *testing.T) {
+	cases := map[string]string{
+		"MOVED 3999 10.0.0.12:6379":                     "10.0.0.12:6379",
+		"MOVED 3999 fd00:10:244::12:6379":               "[fd00:10:244::12]:6379",
+		"MOVED 3999 [fd00:10:244::12]:6379":             "[fd00:10:244::12]:6379",
+		"MOVED 3999 redis-node-1.dev-rdc-headless:6379": "redis-node-1.dev-rdc-headless:6379",
+		"ERR MOVED 3999 10.0.0.12:6379":                 "10.0.0.12:6379",
+		"MOVED 3999":                                    "",
+		"MOVED 3999 10.0.0.12":                          "",
+		"CLUSTERDOWN The cluster is down":               "",
+		"":                                              "",
+	}
+	c := &RedisClusterClient{}
+	for msg, expected := range cases {
+		if address := c.extractAddress(msg); address != expected {
+			test.Errorf("Expected the address of [%s] to be [%s], got [%s]", msg, expected, address)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"math"
 	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -16,7 +17,6 @@ import (
 	"github.com/pkg/errors"
 
 	dbv1 "github.com/PayU/redis-operator/api/v1"
-	rediscli "github.com/PayU/redis-operator/controllers/rediscli"
 	view "github.com/PayU/redis-operator/controllers/view"
 )
 
@@ -38,6 +38,11 @@ func (s ScaleType) String() string {
 	return [...]string{"ScaleUpLeaders", "ScaleUpFollowers", "ScaleDownLeaders", "ScaleDownFollowers"}[s]
 }
 
+// The leader scaling moves slots between the leaders, it runs in the maintenance windows only
+func (s ScaleType) reshardsLeaders() bool {
+	return s == ScaleUpLeaders || s == ScaleDownLeaders
+}
+
 func (r *RedisClusterReconciler) NewRedisClusterView(redisCluster *dbv1.RedisCluster) (*view.RedisClusterView, bool) {
 	r.Log.Info("Getting cluster view...")
 	v := &view.RedisClusterView{}
@@ -65,6 +70,10 @@ func (r *RedisClusterReconciler) createNewRedisCluster(redisCluster *dbv1.RedisC
 		return err
 	}
 
+	if err := r.createRedisHeadlessService(redisCluster); err != nil {
+		return err
+	}
+
 	if err := r.initializeLeaders(redisCluster); err != nil {
 		return err
 	}
@@ -90,9 +99,9 @@ func (r *RedisClusterReconciler) initializeLeaders(redisCluster *dbv1.RedisClust
 
 	var nodeIPs []string
 	for _, leaderPod := range newLeaderPods {
-		r.RedisCLI.Flushall(leaderPod.Status.PodIP)
-		r.RedisCLI.ClusterReset(leaderPod.Status.PodIP)
-		nodeIPs = append(nodeIPs, leaderPod.Status.PodIP)
+		r.RedisCLI.Flushall(view.NodeAddress(leaderPod))
+		r.RedisCLI.ClusterReset(view.NodeAddress(leaderPod))
+		nodeIPs = append(nodeIPs, view.NodeAddress(leaderPod))
 	}
 
 	if _, err := r.waitForPodReady(newLeaderPods...); err != nil {
@@ -128,10 +137,12 @@ func (r *RedisClusterReconciler) failOverToReplica(leaderName string, v *view.Re
 				if err != nil {
 					continue
 				}
+				r.recordEvent(corev1.EventTypeNormal, eventFailedOver, "Follower [%s] was promoted to replace leader [%s]", n.Name, leaderName)
 				return n
 			}
 		}
 	}
+	r.recordEvent(corev1.EventTypeWarning, eventFailoverFailed, "None of the followers of leader [%s] could be promoted", leaderName)
 	return nil
 }
 
@@ -158,10 +169,15 @@ func (r *RedisClusterReconciler) doFailover(promotedNodeIp string, opt ...string
 	if err != nil {
 		return err
 	}
-	return r.waitForManualFailover(promotedNodeIp)
+	if err = r.waitForManualFailover(promotedNodeIp); err != nil {
+		return err
+	}
+	r.countFailover()
+	return nil
 }
 
 func (r *RedisClusterReconciler) cleanMapFromNodesToRemove(redisCluster *dbv1.RedisCluster, v *view.RedisClusterView) {
+	r.planReason("The nodes marked for removal in the state map are removed")
 	r.Log.Info("Cleaning state map from nodes that shuold be removed...")
 	healthyLeaderName, found := r.findHealthyLeader(v)
 	if !found {
@@ -233,7 +249,7 @@ func (r *RedisClusterReconciler) cleanMapFromNodesToRemove(redisCluster *dbv1.Re
 					return
 				}
 				r.waitForAllNodesAgreeAboutSlotsConfiguration(v, redisCluster)
-				r.scaleDownSingleUnit(node.Name, map[string]bool{node.Name: true}, v)
+				r.scaleDownSingleUnit(redisCluster, node.Name, map[string]bool{node.Name: true}, v)
 				r.RedisClusterStateView.ClusterState = view.ClusterRebalance
 			}
 		}
@@ -254,7 +270,7 @@ func (r *RedisClusterReconciler) removeNode(healthyServerIp string, n *view.Node
 	}
 	r.Log.Info(fmt.Sprintf("Removing node [%s] from all tables...", n.Id))
 	_, err = r.RedisCLI.DelNode(healthyServerIp, n.Id)
-	if err != nil {
+	if err != nil || r.planning() {
 		return err
 	}
 	r.Log.Info(fmt.Sprintf("Waiting for node [%s:%s] removal to be completed...", n.Ip, n.Id))
@@ -277,6 +293,9 @@ func (r *RedisClusterReconciler) removeNode(healthyServerIp string, n *view.Node
 }
 
 func (r *RedisClusterReconciler) waitForAllNodesAgreeAboutSlotsConfiguration(v *view.RedisClusterView, redisCluster *dbv1.RedisCluster) {
+	if r.planning() {
+		return
+	}
 	r.Log.Info("Waiting for all cluster nodes to agree about slots configuration...")
 	if redisCluster != nil {
 		newView, ok := r.NewRedisClusterView(redisCluster)
@@ -348,7 +367,7 @@ func (r *RedisClusterReconciler) addLeaderNodes(redisCluster *dbv1.RedisCluster,
 	}
 	leaders, e := r.createRedisLeaderPods(redisCluster, leaderNames...)
 	if e != nil || len(leaders) == 0 {
-		r.deletePods(leaders)
+		r.deletePodsKeepVolume(leaders)
 		r.Log.Error(e, "Could not add new leaders")
 		return e
 	}
@@ -383,26 +402,26 @@ func (r *RedisClusterReconciler) preperNewRedisNode(pod corev1.Pod, mutex *sync.
 		return false
 	}
 	leaderPod := newLeader[0]
-	e = r.waitForRedis(leaderPod.Status.PodIP)
+	e = r.waitForRedis(view.NodeAddress(leaderPod))
 	if e != nil {
 		message := fmt.Sprintf("Error while waiting for pod [%s] to be ready", leaderPod.Name)
 		r.handleCreateErrByDeleteGracefully(pod.Name, leaderPod, mutex, message, e)
 		return false
 	}
-	r.RedisCLI.Flushall(leaderPod.Status.PodIP)
-	r.RedisCLI.ClusterReset(leaderPod.Status.PodIP)
+	r.RedisCLI.Flushall(view.NodeAddress(leaderPod))
+	r.RedisCLI.ClusterReset(view.NodeAddress(leaderPod))
 	return true
 }
 
 func (r *RedisClusterReconciler) joindNewLeaderToCluster(pod corev1.Pod, healthyServerIp string, mutex *sync.Mutex) {
 	r.Log.Info(fmt.Sprintf("Adding new leader: [%s]", pod.Name))
-	_, e := r.RedisCLI.AddLeader(pod.Status.PodIP, healthyServerIp)
+	_, e := r.RedisCLI.AddLeader(view.NodeAddress(pod), healthyServerIp)
 	if e != nil {
 		message := fmt.Sprintf("Error while adding pod [%s] to redis cluster, healthy node ip: [%s]", pod.Name, healthyServerIp)
 		r.handleCreateErrByDeleteGracefully(pod.Name, pod, mutex, message, e)
 		return
 	}
-	e = r.waitForRedisMeet(pod.Status.PodIP)
+	e = r.waitForRedisMeet(view.NodeAddress(pod))
 	if e != nil {
 		message := fmt.Sprintf("Error while adding pod [%s] to redis cluster, healthy node ip: [%s]", pod.Name, healthyServerIp)
 		r.handleCreateErrByDeleteGracefully(pod.Name, pod, mutex, message, e)
@@ -422,6 +441,7 @@ func (r *RedisClusterReconciler) forgetLostNodes(redisCluster *dbv1.RedisCluster
 	r.Log.Info("Forgetting lost nodes...")
 	healthyNodes := map[string]string{}
 	lostIds := map[string]bool{}
+	returningIds := r.returningNodeIDs(redisCluster, v)
 	for _, node := range v.Nodes {
 		if node == nil {
 			continue
@@ -436,13 +456,14 @@ func (r *RedisClusterReconciler) forgetLostNodes(redisCluster *dbv1.RedisCluster
 		}
 		healthyNodes[node.Name] = node.Ip
 		for _, tableNode := range *nodesTable {
-			if strings.Contains(tableNode.Flags, "fail") {
+			if strings.Contains(tableNode.Flags, "fail") && !returningIds[tableNode.ID] {
 				lostIds[tableNode.ID] = true
 			}
 		}
 	}
 	r.waitForAllNodesAgreeAboutSlotsConfiguration(v, nil)
 	if len(lostIds) > 0 {
+		r.planReason("The nodes are flagged as failing in the cluster tables, they are forgotten by the healthy nodes")
 		r.Log.Info(fmt.Sprintf("List of healthy nodes: %v", healthyNodes))
 		r.Log.Info(fmt.Sprintf("List of lost nodes ids: %v", lostIds))
 		failingForgets := r.runForget(lostIds, healthyNodes, map[string]string{})
@@ -468,7 +489,7 @@ func (r *RedisClusterReconciler) forgetLostNodes(redisCluster *dbv1.RedisCluster
 							for name, _ := range healthyNodes {
 								if _, failing := failingForgets[name]; !failing {
 									if h, exists := v.Nodes[name]; exists {
-										r.reshardAndKeepInMap(node.Name, node.LeaderName, h.Ip, h.Id, v)
+										r.reshardAndKeepInMap(redisCluster, node.Name, node.LeaderName, h.Ip, h.Id, v)
 										break
 									}
 								}
@@ -487,28 +508,48 @@ func (r *RedisClusterReconciler) forgetLostNodes(redisCluster *dbv1.RedisCluster
 
 func (r *RedisClusterReconciler) runForget(lostIds map[string]bool, healthyNodes map[string]string, ignore map[string]string) map[string]string {
 	podsToDelete := map[string]string{}
+	// The healthy nodes that failed to forget each lost node, and the last error they failed with
+	failedForgets := map[string][]string{}
+	forgetErrors := map[string]error{}
 	var wg sync.WaitGroup
 	waitIfFails := 20 * time.Second
 	mutex := &sync.Mutex{}
 	wg.Add(len(lostIds) * len(healthyNodes))
 	for id, _ := range lostIds {
 		for name, ip := range healthyNodes {
-			go func(ip string, id string) {
+			go func(name string, ip string, id string) {
 				defer wg.Done()
 				if _, toIgnore := ignore[name]; toIgnore {
 					return
 				}
 				_, err := r.RedisCLI.ClusterForget(ip, id)
-				if err != nil && strings.Contains(err.Error(), "Can't forget my master") {
-					mutex.Lock()
+				// A node that does not know the lost node anymore has already forgotten it
+				if err == nil || strings.Contains(err.Error(), "Unknown node") {
+					return
+				}
+				mutex.Lock()
+				defer mutex.Unlock()
+				failedForgets[id] = append(failedForgets[id], name)
+				forgetErrors[id] = err
+				if strings.Contains(err.Error(), "Can't forget my master") {
 					r.Log.Info(fmt.Sprintf("[Warn] node [%v:%v] is not able to forget [%v] properly, additional attempt to forget will be performed within [%v], additional failure to forget [%v] will lead to node [%v:%v] deletion", name, ip, id, waitIfFails, id, name, ip))
 					podsToDelete[name] = id
-					mutex.Unlock()
 				}
-			}(ip, id)
+			}(name, ip, id)
 		}
 	}
 	wg.Wait()
+	forgotten := 0
+	for id := range lostIds {
+		if names, failed := failedForgets[id]; failed {
+			sort.Strings(names)
+			r.recordEvent(corev1.EventTypeWarning, eventForgetFailed, "Lost node [%s] could not be forgotten by nodes %v: %v", id, names, forgetErrors[id])
+			continue
+		}
+		forgotten++
+		r.recordEvent(corev1.EventTypeNormal, eventForgotLostNode, "Lost node [%s] was forgotten by the healthy nodes", id)
+	}
+	r.countForgottenNodes(forgotten)
 	return podsToDelete
 }
 
@@ -690,6 +731,7 @@ func (r *RedisClusterReconciler) handleLossOfLeaderWithAllReplicas(redisCluster
 	r.Log.Info("Checking for potential data loss..")
 	missingLeadersWithLossOfReplicas := r.detectLossOfLeadersWithAllReplicas(v)
 	if len(missingLeadersWithLossOfReplicas) > 0 {
+		r.planReason("Leaders %v were lost with all their followers, their slots are fixed and they are created again", missingLeadersWithLossOfReplicas)
 		r.removeSoloLeaders(v)
 		r.Log.Info("[Warn] Loss of leader with all of his replica detected, mitigating with CLUSTER FIX...")
 		r.RedisClusterStateView.ClusterState = view.ClusterFix
@@ -707,7 +749,7 @@ func (r *RedisClusterReconciler) handleLossOfLeaderWithAllReplicas(redisCluster
 		}
 		r.RedisClusterStateView.ClusterState = view.ClusterRebalance
 		r.waitForAllNodesAgreeAboutSlotsConfiguration(v, redisCluster)
-		rebalanced, _, e := r.RedisCLI.ClusterRebalance(healthyLeader.Ip, true)
+		rebalanced, _, e := r.rebalanceSlots(redisCluster, v, healthyLeader.Ip)
 		if !rebalanced || e != nil {
 			r.RedisClusterStateView.ClusterState = view.ClusterFix
 			return true
@@ -797,6 +839,7 @@ func (r *RedisClusterReconciler) recoverNodes(redisCluster *dbv1.RedisCluster, v
 	}
 	actionRequired := false
 	mutex := &sync.Mutex{}
+	r.planReason("The missing nodes of the state map are created")
 	r.Log.Info("Detecting missing nodes in cluster...")
 	pods := r.createMissingRedisPods(redisCluster, v)
 	if len(pods) == 0 {
@@ -804,6 +847,7 @@ func (r *RedisClusterReconciler) recoverNodes(redisCluster *dbv1.RedisCluster, v
 	}
 	var wg sync.WaitGroup
 	// can be asynchrounous
+	r.planReason("The interrupted recovery flows of the nodes are resumed")
 	r.Log.Info("Mitigating failures and interrupted flows...")
 	for _, n := range r.RedisClusterStateView.Nodes {
 		if n.NodeState == view.NodeOK {
@@ -844,11 +888,13 @@ func (r *RedisClusterReconciler) handleInterruptedScaleFlows(redisCluster *dbv1.
 		switch n.NodeState {
 		case view.ReshardNode:
 			actionRequired = true
-			r.scaleDownLeader(n.Name, n.LeaderName, map[string]bool{n.Name: true}, v)
+			r.planReason("The reshard of node [%s] was interrupted (%s)", n.Name, n.NodeState)
+			r.scaleDownLeader(redisCluster, n.Name, n.LeaderName, map[string]bool{n.Name: true}, v)
 			break
 		case view.ReshardNodeKeepInMap:
 			actionRequired = true
-			r.scaleDownLeaderKeepInMap(n.Name, n.LeaderName, map[string]bool{n.Name: true}, v)
+			r.planReason("The reshard of node [%s] was interrupted (%s)", n.Name, n.NodeState)
+			r.scaleDownLeaderKeepInMap(redisCluster, n.Name, n.LeaderName, map[string]bool{n.Name: true}, v)
 			break
 		case view.NewEmptyNode:
 			actionRequired = true
@@ -895,15 +941,15 @@ func (r *RedisClusterReconciler) handleInterruptedClusterHealthFlow(redisCluster
 		break
 	case view.ReplicateNode:
 		actionRequired = true
-		err = r.recoverFromReplicateNode(pod.Status.PodIP, m, mutex)
+		err = r.recoverFromReplicateNode(view.NodeAddress(pod), m, mutex)
 		break
 	case view.SyncNode:
 		actionRequired = true
-		err = r.recoverFromSyncNode(pod.Status.PodIP, m, mutex)
+		err = r.recoverFromSyncNode(view.NodeAddress(pod), m, mutex)
 		break
 	case view.FailoverNode:
 		actionRequired = true
-		err = r.recoverFromFailOver(pod.Status.PodIP, m, mutex)
+		err = r.recoverFromFailOver(view.NodeAddress(pod), m, mutex)
 		break
 	}
 
@@ -937,7 +983,7 @@ func (r *RedisClusterReconciler) recoverFromNewEmptyNode(name string, v *view.Re
 func (r *RedisClusterReconciler) recoverFromAddNode(p corev1.Pod, m *view.MissingNodeView, mutex *sync.Mutex) error {
 	masterIp := m.CurrentMasterIp
 	masterId := m.CurrentMasterId
-	newPodIp := p.Status.PodIP
+	newPodIp := view.NodeAddress(p)
 
 	ipsToNodesTables, err := r.ClusterNodesWaitForRedisLoadDataSetInMemory(newPodIp)
 	nodesTable, exists := ipsToNodesTables[newPodIp]
@@ -965,7 +1011,7 @@ func (r *RedisClusterReconciler) recoverFromAddNode(p corev1.Pod, m *view.Missin
 			return err
 		}
 	}
-	return r.recoverFromReplicateNode(p.Status.PodIP, m, mutex)
+	return r.recoverFromReplicateNode(view.NodeAddress(p), m, mutex)
 }
 
 func (r *RedisClusterReconciler) recoverFromReplicateNode(podIp string, m *view.MissingNodeView, mutex *sync.Mutex) error {
@@ -1029,7 +1075,7 @@ func (r *RedisClusterReconciler) retryFailover(name string, ip string, attempts
 	return true
 }
 
-func (r *RedisClusterReconciler) detectNodeTableMissalignments(v *view.RedisClusterView) bool {
+func (r *RedisClusterReconciler) detectNodeTableMissalignments(redisCluster *dbv1.RedisCluster, v *view.RedisClusterView) bool {
 	r.Log.Info("Detecting nodes table missalignments...")
 	missalignments := []string{}
 	for _, node := range v.Nodes {
@@ -1078,7 +1124,8 @@ func (r *RedisClusterReconciler) detectNodeTableMissalignments(v *view.RedisClus
 	}
 
 	for _, missAlignedNode := range missalignments {
-		r.scaleDownSingleUnit(missAlignedNode, map[string]bool{missAlignedNode: true}, v)
+		r.planReason("Follower [%s] serves as a leader besides its own leader, its slots are moved and it is removed", missAlignedNode)
+		r.scaleDownSingleUnit(redisCluster, missAlignedNode, map[string]bool{missAlignedNode: true}, v)
 	}
 	return len(missalignments) > 0
 }
@@ -1088,8 +1135,9 @@ func (r *RedisClusterReconciler) recoverRedisCluster(redisCluster *dbv1.RedisClu
 	r.Log.Info(fmt.Sprintf("Cluster state: %v", s))
 	switch s {
 	case view.ClusterOK:
-		return r.detectNodeTableMissalignments(v), nil
+		return r.detectNodeTableMissalignments(redisCluster, v), nil
 	case view.ClusterFix:
+		r.planReason("The cluster state is %s", view.ClusterFix)
 		healthyLeaderName, found := r.findHealthyLeader(v)
 		if !found {
 			return true, errors.New("Could not find healthy reachable leader to serve cluster fix request")
@@ -1102,6 +1150,10 @@ func (r *RedisClusterReconciler) recoverRedisCluster(redisCluster *dbv1.RedisClu
 		r.RedisClusterStateView.ClusterState = view.ClusterRebalance
 		return true, nil
 	case view.ClusterRebalance:
+		if r.deferDisruption(redisCluster, "Cluster rebalance") {
+			return false, nil
+		}
+		r.planReason("The cluster state is %s", view.ClusterRebalance)
 		r.removeSoloLeaders(v)
 		r.waitForAllNodesAgreeAboutSlotsConfiguration(v, nil)
 		healthyLeaderName, found := r.findHealthyLeader(v)
@@ -1109,7 +1161,7 @@ func (r *RedisClusterReconciler) recoverRedisCluster(redisCluster *dbv1.RedisClu
 			return true, errors.New("Could not find healthy reachable leader to serve cluster rebalance request")
 		}
 		healthyLeaderIp := v.Nodes[healthyLeaderName].Ip
-		rebalanced, _, e := r.RedisCLI.ClusterRebalance(healthyLeaderIp, true)
+		rebalanced, _, e := r.rebalanceSlots(redisCluster, v, healthyLeaderIp)
 		if !rebalanced || e != nil {
 			r.RedisClusterStateView.ClusterState = view.ClusterFix
 			return true, e
@@ -1132,15 +1184,16 @@ func (r *RedisClusterReconciler) waitForNonReachablePodsTermination(redisCluster
 			terminatingPods = append(terminatingPods, pod)
 			continue
 		}
-		clusterInfo, _, e := r.RedisCLI.ClusterInfo(pod.Status.PodIP)
+		clusterInfo, _, e := r.RedisCLI.ClusterInfo(view.NodeAddress(pod))
 		if e != nil || (*clusterInfo) == nil {
 			nonReachablePods = append(nonReachablePods, pod)
 			continue
 		}
 	}
 	if len(nonReachablePods) > 0 {
+		r.planReason("Redis is not reachable on the pods")
 		r.Log.Info(fmt.Sprintf("Removing non reachable pods...number of non reachable pods: %d", len(nonReachablePods)))
-		deletedPods, _ := r.deletePods(nonReachablePods)
+		deletedPods, _ := r.deletePodsKeepVolume(nonReachablePods)
 		if len(deletedPods) > 0 {
 			terminatingPods = append(terminatingPods, deletedPods...)
 		}
@@ -1167,7 +1220,7 @@ func (r *RedisClusterReconciler) getMaxUpdatedPodsPerUpdateBtach(v *view.RedisCl
 }
 
 func (r *RedisClusterReconciler) updateCluster(redisCluster *dbv1.RedisCluster) error {
-	requestUpgrade = false
+	r.requestUpgrade = false
 	r.Log.Info("Updating Cluster Pods...")
 	v, ok := r.NewRedisClusterView(redisCluster)
 	if !ok {
@@ -1221,8 +1274,7 @@ func (r *RedisClusterReconciler) updateCluster(redisCluster *dbv1.RedisCluster)
 				}
 			} else {
 				r.Log.Info("[Warn] Update master with no defined followers will lead to repeated resharding and rebalancing attempts during leaders recreation")
-				success, _, err := r.RedisCLI.ClusterReshard(healthyLeader.Ip, n.Id, healthyLeader.Id, rediscli.MAX_SLOTS_PER_LEADER)
-				if err != nil || !success {
+				if err := r.reshardLeader(redisCluster, healthyLeader.Ip, n.Id, healthyLeader.Id); err != nil {
 					continue
 				}
 				r.RedisClusterStateView.ClusterState = view.ClusterRebalance
@@ -1269,13 +1321,16 @@ func (r *RedisClusterReconciler) updateCluster(redisCluster *dbv1.RedisCluster)
 			updatedPodsCounter++
 		}
 	}
-	requestUpgrade = false
+	r.requestUpgrade = false
 	r.waitForPodDelete(deletedPods...)
 	return nil
 }
 
 // TODO replace with a readyness probe on the redis container
 func (r *RedisClusterReconciler) waitForRedis(nodeIPs ...string) error {
+	if r.planning() {
+		return nil
+	}
 	for _, nodeIP := range nodeIPs {
 		if nodeIP == "" {
 			return errors.Errorf("Missing IP")
@@ -1297,6 +1352,9 @@ func (r *RedisClusterReconciler) waitForRedis(nodeIPs ...string) error {
 }
 
 func (r *RedisClusterReconciler) waitForClusterCreate(leaderIPs []string) error {
+	if r.planning() {
+		return nil
+	}
 	r.Log.Info("Waiting for cluster create execution to complete...")
 	return wait.Poll(r.Config.Times.ClusterCreateInterval, r.Config.Times.ClusterCreateTimeout, func() (bool, error) {
 		for _, leaderIP := range leaderIPs {
@@ -1324,6 +1382,9 @@ func (r *RedisClusterReconciler) waitForClusterCreate(leaderIPs []string) error
 
 // Safe to be called with both followers and leaders, the call on a leader will be ignored
 func (r *RedisClusterReconciler) waitForRedisSync(m *view.MissingNodeView, nodeIP string) error {
+	if r.planning() {
+		return nil
+	}
 	r.Log.Info(fmt.Sprintf("Waiting for SYNC to start on [%s:%s]", m.Name, nodeIP))
 	return wait.PollImmediate(r.Config.Times.SyncCheckInterval, r.Config.Times.SyncCheckTimeout, func() (bool, error) {
 		stdoutF, err := r.RedisCLI.Role(nodeIP)
@@ -1343,14 +1404,14 @@ func (r *RedisClusterReconciler) waitForRedisSync(m *view.MissingNodeView, nodeI
 		if !strings.Contains(stdoutF, m.CurrentMasterIp) || !strings.Contains(stdoutL, nodeIP) {
 			return false, nil
 		}
-		infoF, std, err := reconciler.RedisCLI.Info(nodeIP)
+		infoF, std, err := r.RedisCLI.Info(nodeIP)
 		if err != nil {
 			if strings.Contains(err.Error(), "Redis is loading the dataset in memory") || strings.Contains(std, "Redis is loading the dataset in memory") {
 				return false, nil
 			}
 			return false, err
 		}
-		infoL, std, err := reconciler.RedisCLI.Info(m.CurrentMasterIp)
+		infoL, std, err := r.RedisCLI.Info(m.CurrentMasterIp)
 		if err != nil {
 			if strings.Contains(err.Error(), "Redis is loading the dataset in memory") || strings.Contains(std, "Redis is loading the dataset in memory") {
 				return false, nil
@@ -1404,6 +1465,9 @@ func (r *RedisClusterReconciler) waitForRedisSync(m *view.MissingNodeView, nodeI
 }
 
 func (r *RedisClusterReconciler) waitForRedisReplication(leaderName string, leaderIP string, leaderID string, followerName string, followerID string) error {
+	if r.planning() {
+		return nil
+	}
 	r.Log.Info(fmt.Sprintf("Waiting for CLUSTER REPLICATION [%s:%s]->[%s:%s]", leaderName, leaderID, followerName, followerID))
 	return wait.PollImmediate(r.Config.Times.RedisClusterReplicationCheckInterval, r.Config.Times.RedisClusterReplicationCheckTimeout, func() (bool, error) {
 		replicas, _, err := r.RedisCLI.ClusterReplicas(leaderIP, leaderID)
@@ -1420,6 +1484,9 @@ func (r *RedisClusterReconciler) waitForRedisReplication(leaderName string, lead
 }
 
 func (r *RedisClusterReconciler) waitForRedisMeet(newNodeIP string) error {
+	if r.planning() {
+		return nil
+	}
 	return wait.PollImmediate(r.Config.Times.RedisClusterMeetCheckInterval, r.Config.Times.RedisClusterMeetCheckTimeout, func() (bool, error) {
 		clusterNodes, _, err := r.RedisCLI.ClusterNodes(newNodeIP)
 		if err != nil {
@@ -1437,6 +1504,9 @@ func (r *RedisClusterReconciler) waitForRedisMeet(newNodeIP string) error {
 
 // Waits for a specified pod to be marked as master
 func (r *RedisClusterReconciler) waitForManualFailover(podIP string) error {
+	if r.planning() {
+		return nil
+	}
 	r.Log.Info(fmt.Sprintf("Waiting for [%s] to become leader", podIP))
 	return wait.PollImmediate(r.Config.Times.RedisManualFailoverCheckInterval, r.Config.Times.RedisManualFailoverCheckTimeout, func() (bool, error) {
 		isMaster, err := r.checkIfMaster(podIP)
@@ -1455,7 +1525,7 @@ func (r *RedisClusterReconciler) waitForFailover(redisCluster *dbv1.RedisCluster
 
 	return promotedFollower, wait.PollImmediate(r.Config.Times.RedisAutoFailoverCheckInterval, r.Config.Times.RedisAutoFailoverCheckTimeout, func() (bool, error) {
 		for _, follower := range reachableFollowers {
-			isMaster, err := r.checkIfMaster(follower.Status.PodIP)
+			isMaster, err := r.checkIfMaster(view.NodeAddress(follower))
 			if err != nil {
 				continue
 			}
@@ -1473,16 +1543,25 @@ func (r *RedisClusterReconciler) isPodUpToDate(redisCluster *dbv1.RedisCluster,
 	if existsInMap && node != nil && !node.IsUpToDate {
 		return false, nil
 	}
+	return podMatchesSpec(redisCluster, pod), nil
+}
+
+// Checks if the images and the resources of the pod containers are the ones declared by the custom resource,
+// and if the pod has a persistent volume when spec.persistence is set
+func podMatchesSpec(redisCluster *dbv1.RedisCluster, pod corev1.Pod) bool {
+	if hasPersistentVolume(pod) != (redisCluster.Spec.Persistence != nil) {
+		return false
+	}
 	for _, container := range pod.Spec.Containers {
 		for _, crContainer := range redisCluster.Spec.RedisPodSpec.Containers {
 			if crContainer.Name == container.Name {
 				if !reflect.DeepEqual(container.Resources, crContainer.Resources) || crContainer.Image != container.Image {
-					return false, nil
+					return false
 				}
 			}
 		}
 	}
-	return true, nil
+	return true
 }
 
 // Checks if the image declared by the custom resource is the same as the image in the pods
@@ -1505,6 +1584,11 @@ func (r *RedisClusterReconciler) isClusterUpToDate(redisCluster *dbv1.RedisClust
 
 func (r *RedisClusterReconciler) isClusterHealthy(redisCluster *dbv1.RedisCluster, v *view.RedisClusterView) (bool, error) {
 	if len(v.Nodes) == 0 {
+		if nodes := r.restorableNodes(redisCluster); len(nodes) > 0 {
+			r.Log.Info("[WARN] Could not find redis cluster nodes, creating them again on their volume claims...")
+			r.planReason("All the pods of the cluster were lost, the nodes are created again on their volume claims")
+			return false, r.restoreNodesFromVolumes(redisCluster, nodes)
+		}
 		r.Log.Info("[WARN] Could not find redis cluster nodes, reseting cluster...")
 		redisCluster.Status.ClusterState = string(Reset)
 		return false, nil
@@ -1529,8 +1613,8 @@ func (r *RedisClusterReconciler) isClusterHealthy(redisCluster *dbv1.RedisCluste
 			r.Log.Info(fmt.Sprintf("[Warn] Non reported node detected in view: [%v]", node.Name))
 			return false, nil
 		}
-		if !requestUpgrade && len(node.Pod.Labels["leader-name"]) == 0 {
-			requestUpgrade = true
+		if !r.requestUpgrade && len(node.Pod.Labels["leader-name"]) == 0 {
+			r.requestUpgrade = true
 		}
 	}
 	isComplete := r.RedisClusterStateView.ClusterState == view.ClusterOK && len(nonHealthyNodes) == 0
@@ -1596,15 +1680,7 @@ func (r *RedisClusterReconciler) findHealthyLeader(v *view.RedisClusterView, exc
 }
 
 func (r *RedisClusterReconciler) isScaleRequired(redisCluster *dbv1.RedisCluster) (bool, ScaleType) {
-	leaders := 0
-	followers := 0
-	for _, n := range r.RedisClusterStateView.Nodes {
-		if n.Name == n.LeaderName {
-			leaders++
-		} else {
-			followers++
-		}
-	}
+	leaders, followers := countStateNodes(r.RedisClusterStateView.Nodes)
 	leadersBySpec := redisCluster.Spec.LeaderCount
 	followersBySpec := leadersBySpec * redisCluster.Spec.LeaderFollowersCount
 	isRequired := (leaders != leadersBySpec) || (followers != followersBySpec)
@@ -1621,6 +1697,17 @@ func (r *RedisClusterReconciler) isScaleRequired(redisCluster *dbv1.RedisCluster
 	return isRequired, scaleType
 }
 
+func countStateNodes(state map[string]*view.NodeStateView) (leaders int, followers int) {
+	for _, n := range state {
+		if n.Name == n.LeaderName {
+			leaders++
+		} else {
+			followers++
+		}
+	}
+	return leaders, followers
+}
+
 func (r *RedisClusterReconciler) scaleCluster(redisCluster *dbv1.RedisCluster) error {
 	v, ok := r.NewRedisClusterView(redisCluster)
 	if !ok {
@@ -1634,6 +1721,7 @@ func (r *RedisClusterReconciler) scaleCluster(redisCluster *dbv1.RedisCluster) e
 
 	var err error
 	_, scaleType := r.isScaleRequired(redisCluster)
+	r.planReason("Scale [%v] to %d leaders with %d followers each", scaleType.String(), redisCluster.Spec.LeaderCount, redisCluster.Spec.LeaderFollowersCount)
 	switch scaleType {
 	case ScaleUpLeaders:
 		err = r.scaleUpLeaders(redisCluster, v)
@@ -1710,13 +1798,13 @@ func (r *RedisClusterReconciler) scaleDownLeaders(redisCluster *dbv1.RedisCluste
 	}
 	r.RedisClusterStateView.ClusterState = view.ClusterRebalance
 	for leaderName, _ := range leadersToReshard {
-		r.scaleDownLeader(leaderName, leaderName, leadersToReshard, v)
+		r.scaleDownLeader(redisCluster, leaderName, leaderName, leadersToReshard, v)
 	}
 	r.cleanMapFromNodesToRemove(redisCluster, v)
 	return nil
 }
 
-func (r *RedisClusterReconciler) scaleDownLeader(name string, leaderName string, excludeList map[string]bool, v *view.RedisClusterView) {
+func (r *RedisClusterReconciler) scaleDownLeader(redisCluster *dbv1.RedisCluster, name string, leaderName string, excludeList map[string]bool, v *view.RedisClusterView) {
 	healthyLeaderName, found := r.findHealthyLeader(v, excludeList)
 	if !found {
 		return
@@ -1727,10 +1815,10 @@ func (r *RedisClusterReconciler) scaleDownLeader(name string, leaderName string,
 	}
 	healthyLeaderIp := v.Nodes[healthyLeaderName].Ip
 	targetLeaderId := v.Nodes[targetLeaderName].Id
-	r.reshardAndRemoveLeader(name, leaderName, healthyLeaderIp, targetLeaderId, v)
+	r.reshardAndRemoveLeader(redisCluster, name, leaderName, healthyLeaderIp, targetLeaderId, v)
 }
 
-func (r *RedisClusterReconciler) scaleDownLeaderKeepInMap(name string, leaderName string, excludeList map[string]bool, v *view.RedisClusterView) {
+func (r *RedisClusterReconciler) scaleDownLeaderKeepInMap(redisCluster *dbv1.RedisCluster, name string, leaderName string, excludeList map[string]bool, v *view.RedisClusterView) {
 	healthyLeaderName, found := r.findHealthyLeader(v, excludeList)
 	if !found {
 		return
@@ -1741,10 +1829,10 @@ func (r *RedisClusterReconciler) scaleDownLeaderKeepInMap(name string, leaderNam
 	}
 	healthyLeaderIp := v.Nodes[healthyLeaderName].Ip
 	targetLeaderId := v.Nodes[targetLeaderName].Id
-	r.reshardAndKeepInMap(name, leaderName, healthyLeaderIp, targetLeaderId, v)
+	r.reshardAndKeepInMap(redisCluster, name, leaderName, healthyLeaderIp, targetLeaderId, v)
 }
 
-func (r *RedisClusterReconciler) scaleDownSingleUnit(name string, excludeList map[string]bool, v *view.RedisClusterView) {
+func (r *RedisClusterReconciler) scaleDownSingleUnit(redisCluster *dbv1.RedisCluster, name string, excludeList map[string]bool, v *view.RedisClusterView) {
 	healthyLeaderName, found := r.findHealthyLeader(v, excludeList)
 	if !found {
 		return
@@ -1755,19 +1843,21 @@ func (r *RedisClusterReconciler) scaleDownSingleUnit(name string, excludeList ma
 	}
 	healthyLeaderIp := v.Nodes[healthyLeaderName].Ip
 	targetLeaderId := v.Nodes[targetLeaderName].Id
-	r.reshardAndRemoveSingleUnit(name, healthyLeaderIp, targetLeaderId, v)
+	r.reshardAndRemoveSingleUnit(redisCluster, name, healthyLeaderIp, targetLeaderId, v)
 }
 
-func (r *RedisClusterReconciler) reshardAndRemoveLeader(name string, leaderName string, healthyLeaderIp string, targetLeaderId string, v *view.RedisClusterView) {
+func (r *RedisClusterReconciler) reshardAndRemoveLeader(redisCluster *dbv1.RedisCluster, name string, leaderName string, healthyLeaderIp string, targetLeaderId string, v *view.RedisClusterView) {
 	if leaderToRemove, exists := v.Nodes[name]; exists && leaderToRemove != nil {
 		r.Log.Info(fmt.Sprintf("Resharding node: [%s]->all slots->[%s]", leaderToRemove.Id, targetLeaderId))
-		err := r.reshardLeaderCheckCoverage(healthyLeaderIp, targetLeaderId, leaderToRemove, false)
+		err := r.reshardLeaderCheckCoverage(redisCluster, healthyLeaderIp, targetLeaderId, leaderToRemove, false)
 		if err != nil {
 			r.RedisClusterStateView.ClusterState = view.ClusterFix
 			r.Log.Error(err, fmt.Sprintf("Error during attempt to reshard node [%s]", name))
+			r.recordEvent(corev1.EventTypeWarning, eventReshardFailed, "Could not reshard the slots of leader [%s] before its removal: %v", name, err)
 			return
 		}
 		r.Log.Info(fmt.Sprintf("Leader reshard successful between [%s]->[%s]", leaderToRemove.Id, targetLeaderId))
+		r.recordEvent(corev1.EventTypeNormal, eventResharded, "Slots of leader [%s] were moved to [%s] before its removal", name, targetLeaderId)
 	}
 
 	r.RedisClusterStateView.SetNodeState(name, leaderName, view.DeleteNode)
@@ -1780,31 +1870,35 @@ func (r *RedisClusterReconciler) reshardAndRemoveLeader(name string, leaderName
 	}
 }
 
-func (r *RedisClusterReconciler) reshardAndRemoveSingleUnit(name string, healthyLeaderIp string, targetLeaderId string, v *view.RedisClusterView) {
+func (r *RedisClusterReconciler) reshardAndRemoveSingleUnit(redisCluster *dbv1.RedisCluster, name string, healthyLeaderIp string, targetLeaderId string, v *view.RedisClusterView) {
 	if nodeToRemove, exists := v.Nodes[name]; exists && nodeToRemove != nil {
 		r.Log.Info(fmt.Sprintf("Resharding node: [%s]->all slots->[%s]", nodeToRemove.Id, targetLeaderId))
-		err := r.reshardLeaderCheckCoverage(healthyLeaderIp, targetLeaderId, nodeToRemove, true)
+		err := r.reshardLeaderCheckCoverage(redisCluster, healthyLeaderIp, targetLeaderId, nodeToRemove, true)
 		if err != nil {
 			r.RedisClusterStateView.ClusterState = view.ClusterFix
 			r.Log.Error(err, fmt.Sprintf("Error during attempt to reshard node [%s]", name))
+			r.recordEvent(corev1.EventTypeWarning, eventReshardFailed, "Could not reshard the slots of leader [%s] before its removal: %v", name, err)
 			return
 		}
 		r.waitForAllNodesAgreeAboutSlotsConfiguration(v, nil)
 		r.Log.Info(fmt.Sprintf("Leader reshard successful between [%s]->[%s]", nodeToRemove.Id, targetLeaderId))
+		r.recordEvent(corev1.EventTypeNormal, eventResharded, "Slots of leader [%s] were moved to [%s] before its removal", name, targetLeaderId)
 		r.deletePod(nodeToRemove.Pod)
 	}
 }
 
-func (r *RedisClusterReconciler) reshardAndKeepInMap(name string, leaderName, healthyLeaderIp string, targetLeaderId string, v *view.RedisClusterView) {
+func (r *RedisClusterReconciler) reshardAndKeepInMap(redisCluster *dbv1.RedisCluster, name string, leaderName, healthyLeaderIp string, targetLeaderId string, v *view.RedisClusterView) {
 	if leaderToRemove, exists := v.Nodes[name]; exists && leaderToRemove != nil {
 		r.Log.Info(fmt.Sprintf("Resharding node: [%s]->all slots->[%s]", leaderToRemove.Id, targetLeaderId))
-		err := r.reshardLeaderCheckCoverage(healthyLeaderIp, targetLeaderId, leaderToRemove, true)
+		err := r.reshardLeaderCheckCoverage(redisCluster, healthyLeaderIp, targetLeaderId, leaderToRemove, true)
 		if err != nil {
 			r.RedisClusterStateView.ClusterState = view.ClusterFix
 			r.Log.Error(err, fmt.Sprintf("Error during attempt to reshard node [%s]", leaderName))
+			r.recordEvent(corev1.EventTypeWarning, eventReshardFailed, "Could not reshard the slots of leader [%s]: %v", name, err)
 			return
 		}
 		r.Log.Info(fmt.Sprintf("Leader reshard successful between [%s]->[%s]", leaderToRemove.Id, targetLeaderId))
+		r.recordEvent(corev1.EventTypeNormal, eventResharded, "Slots of leader [%s] were moved to [%s]", name, targetLeaderId)
 	}
 
 	r.RedisClusterStateView.SetNodeState(name, leaderName, view.DeleteNodeKeepInMap)
@@ -1862,7 +1956,7 @@ func (r *RedisClusterReconciler) checkIfMaster(nodeIP string) (bool, error) {
 	return false, nil
 }
 
-func (r *RedisClusterReconciler) reshardLeaderCheckCoverage(healthyLeaderIp string, targetLeaderId string, leaderToRemove *view.NodeView, keepInMap bool) error {
+func (r *RedisClusterReconciler) reshardLeaderCheckCoverage(redisCluster *dbv1.RedisCluster, healthyLeaderIp string, targetLeaderId string, leaderToRemove *view.NodeView, keepInMap bool) error {
 	isMaster, e := r.checkIfMaster(leaderToRemove.Ip)
 	if e != nil {
 		return e
@@ -1877,10 +1971,14 @@ func (r *RedisClusterReconciler) reshardLeaderCheckCoverage(healthyLeaderIp stri
 
 	r.Log.Info(fmt.Sprintf("Performing resharding and coverage check on leader [%s]", leaderToRemove.Name))
 
-	success, _, e := r.RedisCLI.ClusterReshard(healthyLeaderIp, leaderToRemove.Id, targetLeaderId, rediscli.MAX_SLOTS_PER_LEADER)
-	if e != nil || !success {
+	if e := r.reshardLeader(redisCluster, healthyLeaderIp, leaderToRemove.Id, targetLeaderId); e != nil {
 		return e
 	}
+	r.countReshard()
+	// The planned reshard did not move the slots, their coverage is checked when it is carried out
+	if r.planning() {
+		return nil
+	}
 	emptyLeadersIds, fullCoverage, e := r.CheckClusterAndCoverage(healthyLeaderIp)
 	if !fullCoverage || e != nil {
 		r.RedisClusterStateView.ClusterState = view.ClusterFix
@@ -1900,7 +1998,7 @@ func (r *RedisClusterReconciler) CheckClusterAndCoverage(nodeIp string) (emptyLe
 	}
 	slotsConfigurationFormat := "[OK] All nodes agree about slots configuration"
 	allSlotsCoveredFormat := "[OK] All 16384 slots covered"
-	zeroSlotsPerMasterFormat := "M:\\s*(\\w*|\\d*)\\s*\\d+\\.\\d+\\.\\d+\\.\\d+:\\d+\\s*slots:\\s*\\(0 slots\\)\\s*master"
+	zeroSlotsPerMasterFormat := "M:\\s*(\\w*|\\d*)\\s*\\S+:\\d+\\s*slots:\\s*\\(0 slots\\)\\s*master"
 	c := regexp.MustCompile(zeroSlotsPerMasterFormat)
 	matchingSubstrings := c.FindAllStringSubmatch(clusterCheckResult, -1)
 	for _, match := range matchingSubstrings {
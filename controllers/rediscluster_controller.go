@@ -31,9 +31,14 @@ import (
 	"github.com/go-logr/logr"
 	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/client-go/util/retry"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	dbv1 "github.com/PayU/redis-operator/api/v1"
 	corev1 "k8s.io/api/core/v1"
@@ -70,84 +75,247 @@ type RedisClusterReconciler struct {
 	Scheme                *runtime.Scheme
 	RedisCLI              *rediscli.RedisCLI
 	Config                *OperatorConfig
+	Recorder              record.EventRecorder
 	State                 RedisClusterState
 	RedisClusterStateView *view.RedisClusterStateView
-}
 
-var reconciler *RedisClusterReconciler
-var cluster *dbv1.RedisCluster
+	requestUpgrade      bool
+	requestReset        bool
+	setChannelOnSigTerm bool
+
+	// The details the nodes reported on themselves in the current reconcile loop by node address, the status saves
+	// of the loop share them
+	observedNodes map[string]observedNode
+
+	// The NamespacedName of the RedisCluster handled by the reconciler, used to label its metrics
+	key types.NamespacedName
+
+	// The RedisCluster object that events are recorded on
+	eventObject *dbv1.RedisCluster
+
+	// Each managed RedisCluster is handled by a dedicated reconciler that holds its own
+	// state and state view, the manager registered reconciler dispatches to them by NamespacedName
+	clusterReconcilers      map[types.NamespacedName]*RedisClusterReconciler
+	clusterReconcilersMutex sync.Mutex
+
+	// The background jobs started by the entry points, shared by all the managed clusters
+	jobs *jobRegistry
+
+	// The last plan logged in observe-only mode, an event is recorded when it changes
+	lastObservedPlan []PlanStep
+
+	// The disruptive flows whose deferral was recorded, until the disruptions are allowed again
+	deferredFlows map[string]bool
+
+	// Records the actions of the handlers instead of carrying them out, set on the reconcilers that build a plan
+	planner *planRecorder
+
+	// The last time a follower was recreated to leave the zone of its leader
+	lastZoneRelocation time.Time
+
+	// The CPU seconds of the leaders by the previous reconcile loop, the autoscaling measures the CPU usage since then
+	cpuSamples map[string]cpuSample
 
-var requestUpgrade bool = false
-var setChannelOnSigTerm bool = true
+	// The cleanups of the deleted cluster in which some of the nodes could not save the final snapshot
+	failedSnapshotAttempts int
+
+	// Serializes the reconcile loops of the cluster with the entry points and the jobs that change it
+	clusterMutex sync.Mutex
+}
 
 // +kubebuilder:rbac:groups=db.payu.com,resources=redisclusters,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=db.payu.com,resources=redisclusters/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=*,resources=pods;services;configmaps,verbs=create;update;patch;get;list;watch;delete
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
 
 func (r *RedisClusterReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
-	reconciler = r
-	r.Status()
 	var redisCluster dbv1.RedisCluster
-	var err error
-
-	if err = r.Get(context.Background(), req.NamespacedName, &redisCluster); err != nil {
+	if err := r.Get(context.Background(), req.NamespacedName, &redisCluster); err != nil {
+		if apierrors.IsNotFound(err) {
+			r.Log.Info(fmt.Sprintf("RedisCluster [%s] not found, releasing its reconciler", req.NamespacedName))
+			r.jobRegistry().release(req.NamespacedName)
+			r.removeClusterReconciler(req.NamespacedName)
+			return ctrl.Result{}, nil
+		}
 		r.Log.Info("Unable to fetch RedisCluster resource")
-		return ctrl.Result{Requeue: true, RequeueAfter: 15 * time.Second}, client.IgnoreNotFound(err)
+		return ctrl.Result{Requeue: true, RequeueAfter: 15 * time.Second}, err
+	}
+	if !redisCluster.DeletionTimestamp.IsZero() {
+		return r.finalizeRedisCluster(req.NamespacedName, &redisCluster)
+	}
+	return r.getClusterReconciler(req.NamespacedName).reconcile(&redisCluster)
+}
+
+// Returns the reconciler dedicated to the RedisCluster with the given NamespacedName, creates it on first use
+func (r *RedisClusterReconciler) getClusterReconciler(key types.NamespacedName) *RedisClusterReconciler {
+	r.clusterReconcilersMutex.Lock()
+	defer r.clusterReconcilersMutex.Unlock()
+	if r.clusterReconcilers == nil {
+		r.clusterReconcilers = map[types.NamespacedName]*RedisClusterReconciler{}
+	}
+	cr, exists := r.clusterReconcilers[key]
+	if !exists {
+		cr = &RedisClusterReconciler{
+			Client:                r.Client,
+			Log:                   r.Log.WithValues("rediscluster", key.String()),
+			Scheme:                r.Scheme,
+			RedisCLI:              r.RedisCLI,
+			Config:                r.Config,
+			Recorder:              r.Recorder,
+			State:                 NotExists,
+			RedisClusterStateView: &view.RedisClusterStateView{Name: clusterStateMapName(key.Name)},
+			setChannelOnSigTerm:   true,
+			key:                   key,
+		}
+		r.clusterReconcilers[key] = cr
 	}
+	return cr
+}
+
+// Returns the reconciler dedicated to the RedisCluster with the given NamespacedName if it is already managed
+func (r *RedisClusterReconciler) lookupClusterReconciler(key types.NamespacedName) (*RedisClusterReconciler, bool) {
+	r.clusterReconcilersMutex.Lock()
+	defer r.clusterReconcilersMutex.Unlock()
+	cr, exists := r.clusterReconcilers[key]
+	return cr, exists
+}
 
+func (r *RedisClusterReconciler) removeClusterReconciler(key types.NamespacedName) {
+	r.clusterReconcilersMutex.Lock()
+	defer r.clusterReconcilersMutex.Unlock()
+	if cr, exists := r.clusterReconcilers[key]; exists && cr.RedisClusterStateView != nil {
+		var nodeNames []string
+		for name := range cr.RedisClusterStateView.Nodes {
+			nodeNames = append(nodeNames, name)
+		}
+		deleteClusterMetrics(key, nodeNames)
+	}
+	delete(r.clusterReconcilers, key)
+}
+
+func (r *RedisClusterReconciler) reconcile(redisCluster *dbv1.RedisCluster) (ctrl.Result, error) {
+	var err error
+	r.clusterMutex.Lock()
+	defer r.clusterMutex.Unlock()
+
+	r.observedNodes = nil
+	r.eventObject = redisCluster
+	if err = r.reloadRedisAuth(redisCluster); err != nil {
+		r.Log.Error(err, "Could not load the redis credentials")
+		return ctrl.Result{Requeue: true, RequeueAfter: 20 * time.Second}, nil
+	}
+	if err = r.reloadRedisTLS(redisCluster); err != nil {
+		r.Log.Error(err, "Could not load the TLS certificates")
+		return ctrl.Result{Requeue: true, RequeueAfter: 20 * time.Second}, nil
+	}
+	r.reloadObserveOnly(redisCluster)
+	if err = r.ensureFinalizer(redisCluster); err != nil {
+		r.Log.Error(err, "Could not add the finalizer")
+		return ctrl.Result{Requeue: true, RequeueAfter: 20 * time.Second}, nil
+	}
 	r.State = RedisClusterState(redisCluster.Status.ClusterState)
 	if len(redisCluster.Status.ClusterState) == 0 {
 		r.State = NotExists
 	}
 
-	cluster = &redisCluster
-
 	if r.State != NotExists && r.State != Reset {
-		err = r.setClusterStateView(&redisCluster)
+		err = r.setClusterStateView(redisCluster)
 		if err != nil {
 			r.Log.Error(err, "Could not perform reconcile loop")
-			r.deriveStateViewOutOfExistingCluster(&redisCluster)
+			r.deriveStateViewOutOfExistingCluster(redisCluster)
 			return ctrl.Result{Requeue: true, RequeueAfter: 20 * time.Second}, nil
 		}
 	}
 
-	if setChannelOnSigTerm {
-		r.saveClusterStateOnSigTerm(&redisCluster)
-		setChannelOnSigTerm = false
+	if r.setChannelOnSigTerm {
+		r.saveClusterStateOnSigTerm(redisCluster)
+		r.setChannelOnSigTerm = false
+	}
+
+	handleStateStart := time.Now()
+	if r.RedisCLI.ObserveOnly {
+		err = r.handleObserveOnly(redisCluster)
+	} else {
+		err = r.handleState(redisCluster)
 	}
+	r.observeHandleStateDuration(r.State, handleStateStart)
+	if err != nil {
+		r.Log.Error(err, "Handling error")
+		r.recordEvent(corev1.EventTypeWarning, eventReconcileFailed, "Handling [%s] state failed: %v", r.State, err)
+	}
+	r.recordStateTransition(r.State, RedisClusterState(redisCluster.Status.ClusterState))
+
+	r.saveClusterView(redisCluster)
+	return ctrl.Result{Requeue: true, RequeueAfter: 15 * time.Second}, err
+}
 
+// Runs the handler of the operator state, the reconcile loops and the plan of the cluster share it
+func (r *RedisClusterReconciler) handleState(redisCluster *dbv1.RedisCluster) error {
 	switch r.State {
 	case NotExists:
-		err = r.handleInitializingCluster(&redisCluster)
-		break
+		return r.handleInitializingCluster(redisCluster)
 	case Reset:
-		err = r.handleInitializingCluster(&redisCluster)
-		break
+		if r.deferDisruption(redisCluster, "Cluster reset") {
+			return nil
+		}
+		return r.handleInitializingCluster(redisCluster)
 	case Ready:
-		err = r.handleReadyState(&redisCluster)
-		break
+		return r.handleReadyState(redisCluster)
 	case Recovering:
-		err = r.handleRecoveringState(&redisCluster)
-		break
+		return r.handleRecoveringState(redisCluster)
 	case Updating:
-		err = r.handleUpdatingState(&redisCluster)
-		break
+		return r.handleUpdatingState(redisCluster)
 	case Scale:
-		err = r.handleScaleState(&redisCluster)
-	}
-	if err != nil {
-		r.Log.Error(err, "Handling error")
+		return r.handleScaleState(redisCluster)
 	}
-
-	r.saveClusterView(&redisCluster)
-	return ctrl.Result{Requeue: true, RequeueAfter: 15 * time.Second}, err
+	return nil
 }
 
-func (r *RedisClusterReconciler) saveOperatorState(redisCluster *dbv1.RedisCluster) {
-	r.Status().Update(context.Background(), redisCluster)
+func (r *RedisClusterReconciler) saveOperatorState(redisCluster *dbv1.RedisCluster) error {
+	status := redisCluster.Status.DeepCopy()
+	err := retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		err := r.Status().Update(context.Background(), redisCluster)
+		if err == nil || !apierrors.IsConflict(err) {
+			return err
+		}
+		// The status is written by the reconcile loop and by the jobs that patch their fields under the lock of the
+		// cluster, the ACLSynced condition is owned by the config map reconciler, keep its latest value
+		var latest dbv1.RedisCluster
+		if getErr := r.Get(context.Background(), client.ObjectKey{Namespace: redisCluster.Namespace, Name: redisCluster.Name}, &latest); getErr != nil {
+			return getErr
+		}
+		if aclSynced := latest.Status.GetCondition(dbv1.ConditionACLSynced); aclSynced != nil {
+			status.SetCondition(*aclSynced)
+		}
+		latest.Status = *status
+		latest.DeepCopyInto(redisCluster)
+		return err
+	})
+	if err != nil {
+		r.Log.Error(err, "Could not update the redis cluster status")
+		return err
+	}
 	operatorState := redisCluster.Status.ClusterState
-	r.Client.Status()
 	r.Log.Info(fmt.Sprintf("Operator state: [%s], Cluster state: [%s]", operatorState, r.RedisClusterStateView.ClusterState))
+	return nil
+}
+
+// Patches the fields of the status that are set by the update on the latest RedisCluster, the fields that were
+// changed meanwhile by the other writers of the status are kept. The update is applied to the given RedisCluster too.
+func (r *RedisClusterReconciler) patchStatus(redisCluster *dbv1.RedisCluster, update func(status *dbv1.RedisClusterStatus)) error {
+	var latest dbv1.RedisCluster
+	if err := r.Get(context.Background(), client.ObjectKey{Namespace: redisCluster.Namespace, Name: redisCluster.Name}, &latest); err != nil {
+		return err
+	}
+	patch := client.MergeFrom(latest.DeepCopy())
+	update(&latest.Status)
+	if err := r.Status().Patch(context.Background(), &latest, patch); err != nil {
+		return err
+	}
+	update(&redisCluster.Status)
+	redisCluster.ResourceVersion = latest.ResourceVersion
+	return nil
 }
 
 func (r *RedisClusterReconciler) saveClusterView(redisCluster *dbv1.RedisCluster) {
@@ -156,65 +324,77 @@ func (r *RedisClusterReconciler) saveClusterView(redisCluster *dbv1.RedisCluster
 	} else {
 		r.RedisClusterStateView.NumOfReconcileLoopsSinceHealthyCluster++
 	}
+	r.updateClusterConditions(redisCluster)
 	r.saveClusterStateView(redisCluster)
-	v, ok := r.NewRedisClusterView(redisCluster)
-	if !ok {
-		return
-	}
-	for _, n := range v.Nodes {
-		if n != nil {
-			n.Pod = corev1.Pod{}
-		}
-	}
-	r.saveOperatorState(redisCluster)
+	r.updateClusterMetrics(redisCluster)
 }
 
 func (r *RedisClusterReconciler) handleInitializingCluster(redisCluster *dbv1.RedisCluster) error {
+	r.planReason("The cluster is created from scratch with %d leaders and %d followers each", redisCluster.Spec.LeaderCount, redisCluster.Spec.LeaderFollowersCount)
 	r.Log.Info("Clear all cluster pods...")
-	e := r.deleteAllRedisClusterPods()
+	e := r.deleteAllRedisClusterPods(redisCluster)
 	if e != nil {
 		return e
 	}
+	// The claims are deleted by an explicit reset only, a cluster that lost its pods gets them back from the claims
+	if redisCluster.Spec.Persistence != nil && r.requestReset {
+		r.Log.Info("Clear all cluster volume claims...")
+		if e := r.deleteAllNodeVolumes(redisCluster); e != nil {
+			return e
+		}
+	}
+	r.requestReset = false
 	r.Log.Info("Clear cluster state map...")
 	r.deleteClusterStateView(redisCluster)
 	r.RedisClusterStateView.CreateStateView(redisCluster.Spec.LeaderCount, redisCluster.Spec.LeaderFollowersCount)
+	redisCluster.Status.Reshard = nil
 	r.Log.Info("Handling initializing cluster...")
 	if err := r.createNewRedisCluster(redisCluster); err != nil {
 		redisCluster.Status.ClusterState = string(Reset)
+		r.setUnavailable(redisCluster, reasonClusterCreateFailed, err.Error())
 		return err
 	}
 	redisCluster.Status.ClusterState = string(Ready)
-	r.postNewClusterStateView(redisCluster)
+	setCondition(redisCluster, dbv1.ConditionSlotsCovered, true, reasonClusterCreated, "Slots were assigned to the new cluster leaders")
 	r.saveClusterView(redisCluster)
 	return nil
 }
 
 func (r *RedisClusterReconciler) handleReadyState(redisCluster *dbv1.RedisCluster) error {
 	r.Log.Info("Handling ready state...")
+	if err := r.ensureRedisService(redisCluster); err != nil {
+		r.Log.Error(err, "Could not create the service of the cluster")
+	}
 	v, ok := r.NewRedisClusterView(redisCluster)
 	if !ok {
 		r.RedisClusterStateView.NumOfReconcileLoopsSinceHealthyCluster++
 		r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow = 0
 		redisCluster.Status.ClusterState = string(Recovering)
+		r.setUnavailable(redisCluster, reasonNonReachableNodes, "Some of the cluster nodes are not reachable")
 		return nil
 	}
 	lostNodesDetected := r.forgetLostNodes(redisCluster, v)
 	if lostNodesDetected {
 		r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow = 0
 		r.Log.Info("[Warn] Lost nodes detcted on some of the nodes tables...")
+		setCondition(redisCluster, dbv1.ConditionDegraded, true, reasonLostNodesDetected, "Lost nodes detected on some of the nodes tables")
 		return nil
 	}
 	healthy, err := r.isClusterHealthy(redisCluster, v)
 	if err != nil {
 		r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow = 0
 		r.Log.Info("Could not check if cluster is healthy")
+		setCondition(redisCluster, dbv1.ConditionDegraded, true, reasonHealthCheckFailed, err.Error())
 		return err
 	}
 	if !healthy {
 		r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow = 0
 		redisCluster.Status.ClusterState = string(Recovering)
+		r.setUnavailable(redisCluster, reasonUnhealthyNodes, "Some of the cluster nodes are not healthy")
 		return nil
 	}
+	r.setAvailable(redisCluster)
+	r.updateSlotsCoveredCondition(redisCluster, v)
 	r.logCurrentMastersList(v)
 	uptodate, err := r.isClusterUpToDate(redisCluster, v)
 	if err != nil {
@@ -223,24 +403,41 @@ func (r *RedisClusterReconciler) handleReadyState(redisCluster *dbv1.RedisCluste
 		redisCluster.Status.ClusterState = string(Recovering)
 		return err
 	}
-	if !uptodate {
+	if !uptodate && !r.deferDisruption(redisCluster, "Rolling update") {
 		r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow = 0
 		redisCluster.Status.ClusterState = string(Updating)
 		return nil
 	}
 	scale, scaleType := r.isScaleRequired(redisCluster)
+	if scale && scaleType.reshardsLeaders() && r.deferDisruption(redisCluster, fmt.Sprintf("Scale [%v]", scaleType.String())) {
+		scale = false
+	}
 	if scale {
 		r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow = 0
 		r.Log.Info(fmt.Sprintf("Scale is required, scale type: [%v]", scaleType.String()))
 		redisCluster.Status.ClusterState = string(Scale)
 	}
+	if redisCluster.Status.ClusterState == string(Ready) {
+		switchedBack, err := r.switchbackLeaders(redisCluster, v)
+		if err != nil {
+			r.Log.Error(err, "Could not switch back the designated leaders")
+		}
+		if !switchedBack {
+			if err := r.balanceZones(redisCluster, v); err != nil {
+				r.Log.Error(err, "Could not balance the zones of the cluster")
+			}
+		}
+		if err := r.autoscaleLeaders(redisCluster, v); err != nil {
+			r.Log.Error(err, "Could not autoscale the leaders of the cluster")
+		}
+	}
 	r.Log.Info("Cluster is healthy")
 	if r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow < 10 {
 		r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow++
 	} else {
 		r.Log.Info("[OK] Cluster is in finalized state")
 	}
-	if requestUpgrade {
+	if r.requestUpgrade {
 		r.Log.Info("[Warn] Cluster upgrade is required, upgrade can be triggered by using entry point /upgrade")
 	}
 	return nil
@@ -248,9 +445,14 @@ func (r *RedisClusterReconciler) handleReadyState(redisCluster *dbv1.RedisCluste
 
 func (r *RedisClusterReconciler) handleScaleState(redisCluster *dbv1.RedisCluster) error {
 	r.Log.Info("Handling cluster scale...")
+	if scale, scaleType := r.isScaleRequired(redisCluster); scale && scaleType.reshardsLeaders() && r.deferDisruption(redisCluster, fmt.Sprintf("Scale [%v]", scaleType.String())) {
+		redisCluster.Status.ClusterState = string(Ready)
+		return nil
+	}
 	e := r.scaleCluster(redisCluster)
 	if e != nil {
 		r.Log.Error(e, "Could not perform cluster scale")
+		setCondition(redisCluster, dbv1.ConditionDegraded, true, reasonScaleFailed, e.Error())
 	}
 	redisCluster.Status.ClusterState = string(Ready)
 	return nil
@@ -258,6 +460,7 @@ func (r *RedisClusterReconciler) handleScaleState(redisCluster *dbv1.RedisCluste
 
 func (r *RedisClusterReconciler) handleRecoveringState(redisCluster *dbv1.RedisCluster) error {
 	r.Log.Info("Handling cluster recovery...")
+	r.setUnavailable(redisCluster, reasonRecovering, "Non healthy nodes are being recovered")
 	v, ok := r.NewRedisClusterView(redisCluster)
 	if !ok {
 		return nil
@@ -273,9 +476,15 @@ func (r *RedisClusterReconciler) handleRecoveringState(redisCluster *dbv1.RedisC
 func (r *RedisClusterReconciler) handleUpdatingState(redisCluster *dbv1.RedisCluster) error {
 	var err error = nil
 	r.Log.Info("Handling rolling update...")
+	if r.deferDisruption(redisCluster, "Rolling update") {
+		redisCluster.Status.ClusterState = string(Ready)
+		return nil
+	}
+	r.planReason("Rolling update of the pods that do not match the spec")
 	r.updateCluster(redisCluster)
 	redisCluster.Status.ClusterState = string(Recovering)
-	reconciler.saveOperatorState(cluster)
+	r.updateClusterConditions(redisCluster)
+	r.saveOperatorState(redisCluster)
 	return err
 }
 
@@ -311,6 +520,9 @@ func (r *RedisClusterReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&dbv1.RedisCluster{}).
 		Owns(&corev1.Pod{}).
+		Watches(&source.Kind{Type: &corev1.Secret{}}, &handler.EnqueueRequestsFromMapFunc{
+			ToRequests: handler.ToRequestsFunc(r.redisClustersForSecret),
+		}).
 		WithOptions(controller.Options{MaxConcurrentReconciles: 1}).
 		Complete(r)
 }
@@ -341,12 +553,12 @@ func (r *RedisClusterReconciler) deriveStateViewOutOfExistingCluster(redisCluste
 				}
 			}
 		}
-		r.postNewClusterStateView(redisCluster)
+		r.saveClusterStateView(redisCluster)
 	}
 }
 
 func (r *RedisClusterReconciler) saveClusterStateOnSigTerm(redisCluster *dbv1.RedisCluster) {
-	if setChannelOnSigTerm && r.RedisClusterStateView != nil {
+	if r.setChannelOnSigTerm && r.RedisClusterStateView != nil {
 		mutex := &sync.Mutex{}
 		saveStatusOnQuit := make(chan os.Signal, 1)
 		signal.Notify(saveStatusOnQuit, syscall.SIGTERM, syscall.SIGQUIT, syscall.SIGKILL)
@@ -0,0 +1,117 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/rediscli"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/*
+	The slots of a leader that is removed, by a scale down or by the recovery of a misaligned node, are moved to another
+	leader by the resharding engine of rediscli, slot by slot. Its progress is kept in status.reshard: the source and the
+	target, the slot that is migrating, the pending slot ranges and the moved slots and keys. The journal is written at
+	most every few seconds, and when the reshard fails; it is removed when the reshard completes.
+	The flows of the interrupted scale downs move the slots of the same source again after a restart of the operator, the
+	journal keeps them on the target they were moved to, as long as it is still a leader, and the slot that was left open
+	between them is completed first.
+	The moves of the memory rebalance plan run by the same engine, for the slots the plan selected.
+	The MIGRATE batches and the rate of the moved keys are set by the ReshardMigratePipeline and ReshardKeysPerSecond
+	thresholds of the operator config.
+*/
+
+const reshardJournalInterval = 5 * time.Second
+
+// Moves all the slots of the source leader to the target leader, an interrupted reshard of the same source is resumed from its journal
+func (r *RedisClusterReconciler) reshardLeader(redisCluster *dbv1.RedisCluster, healthyLeaderIp string, sourceId string, targetId string) error {
+	return r.reshardSlots(redisCluster, healthyLeaderIp, sourceId, targetId, nil)
+}
+
+// Moves the given slots of the source leader to the target leader, or all of them when none is given
+func (r *RedisClusterReconciler) reshardSlots(redisCluster *dbv1.RedisCluster, healthyLeaderIp string, sourceId string, targetId string, slots []int) error {
+	startTime := metav1.Now()
+	movedSlots, movedKeys := 0, int64(0)
+	if journal := redisCluster.Status.Reshard; journal != nil && journal.SourceID == sourceId {
+		if len(slots) == 0 && journal.TargetID != targetId && r.isLeaderId(healthyLeaderIp, journal.TargetID) {
+			targetId = journal.TargetID
+		}
+		if journal.TargetID == targetId {
+			startTime, movedSlots, movedKeys = journal.StartTime, journal.MovedSlots, journal.MovedKeys
+			r.Log.Info(fmt.Sprintf("Resuming the reshard [%s]->[%s], %d slots were moved, pending [%s]", sourceId, targetId, movedSlots, strings.Join(journal.PendingSlots, ",")))
+		}
+	}
+
+	lastWrite := time.Time{}
+	writeJournal := func(progress rediscli.ReshardProgress, force bool) {
+		journal := &dbv1.ReshardJournal{
+			SourceID:       progress.SourceID,
+			TargetID:       progress.TargetID,
+			PendingSlots:   rediscli.SlotRanges(progress.PendingSlots),
+			MovedSlots:     movedSlots + progress.MovedSlots,
+			MovedKeys:      movedKeys + progress.MovedKeys,
+			StartTime:      startTime,
+			LastUpdateTime: metav1.Now(),
+		}
+		if progress.MigratingSlot >= 0 {
+			slot := progress.MigratingSlot
+			journal.MigratingSlot = &slot
+		}
+		redisCluster.Status.Reshard = journal
+		if !force && time.Since(lastWrite) < reshardJournalInterval {
+			return
+		}
+		lastWrite = time.Now()
+		if err := r.saveReshardJournal(redisCluster, journal); err != nil {
+			r.Log.Info(fmt.Sprintf("[Warn] Could not write the journal of the reshard [%s]->[%s]: %v", sourceId, targetId, err))
+		}
+	}
+
+	options := rediscli.ReshardOptions{
+		Pipeline:      r.Config.Thresholds.ReshardMigratePipeline,
+		KeysPerSecond: r.Config.Thresholds.ReshardKeysPerSecond,
+		Slots:         slots,
+		Journal: func(progress rediscli.ReshardProgress) error {
+			if progress.MigratingSlot >= 0 {
+				writeJournal(progress, false)
+			}
+			return nil
+		},
+	}
+	r.Log.Info(fmt.Sprintf("Resharding [%s]->[%s] by [%s]", sourceId, targetId, healthyLeaderIp))
+	progress, err := r.RedisCLI.ReshardSlots(healthyLeaderIp, sourceId, targetId, options)
+	if err != nil {
+		if progress.MigratingSlot >= 0 {
+			writeJournal(progress, true)
+		}
+		return err
+	}
+	if err := r.saveReshardJournal(redisCluster, nil); err != nil {
+		r.Log.Info(fmt.Sprintf("[Warn] Could not remove the journal of the reshard [%s]->[%s]: %v", sourceId, targetId, err))
+	}
+	r.Log.Info(fmt.Sprintf("Reshard [%s]->[%s] completed, %d slots and %d keys were moved", sourceId, targetId, movedSlots+progress.MovedSlots, movedKeys+progress.MovedKeys))
+	return nil
+}
+
+// Writes the journal to the status, the other fields of the status are left to the reconcile loop
+func (r *RedisClusterReconciler) saveReshardJournal(redisCluster *dbv1.RedisCluster, journal *dbv1.ReshardJournal) error {
+	return r.patchStatus(redisCluster, func(status *dbv1.RedisClusterStatus) {
+		status.Reshard = journal
+	})
+}
+
+// Checks if the node is a leader that is not failing, as seen by the given node
+func (r *RedisClusterReconciler) isLeaderId(nodeIp string, id string) bool {
+	clusterNodes, _, err := r.RedisCLI.ClusterNodes(nodeIp)
+	if err != nil || clusterNodes == nil {
+		return false
+	}
+	for _, n := range *clusterNodes {
+		if n.ID == id {
+			return strings.Contains(n.Flags, "master") && !strings.Contains(n.Flags, "fail")
+		}
+	}
+	return false
+}
@@ -0,0 +1,167 @@
+package controllers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/rediscli"
+	"github.com/PayU/redis-operator/controllers/view"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+)
+
+/*
+	The rebalances of the operator give every leader the same number of slots. With spec.slotWeights the slots are
+	given in proportion to the weight of every leader instead: the weight of its shard, by the name of the designated
+	leader, multiplied by the memory limit of its redis container in MiB when fromMemoryLimit is set. The memory limit
+	is read from the pod of the leader, so while the pods of a cluster move to a node pool of bigger nodes, by a
+	rolling update of their pod spec, the leaders that already moved are given more slots.
+	The weights are passed by node ID to the rebalance, which is run after the scale up of the leaders, the fix of
+	the cluster and by the rebalance entry point. The slots of every leader against the target of its weight are
+	reported on status.slotDistribution.
+*/
+
+const mebibyte = 1024 * 1024
+
+// Rebalances the slots of the cluster by the weights of its leaders, or evenly when the cluster has no slot weights
+func (r *RedisClusterReconciler) rebalanceSlots(redisCluster *dbv1.RedisCluster, v *view.RedisClusterView, healthyLeaderIp string) (bool, string, error) {
+	if redisCluster.Spec.SlotWeights == nil {
+		return r.RedisCLI.ClusterRebalance(healthyLeaderIp, true)
+	}
+	leaderPods, err := r.leaderPodsById(v, healthyLeaderIp)
+	if err != nil {
+		return false, "", err
+	}
+	weights := leaderSlotWeights(redisCluster.Spec.SlotWeights, leaderPods)
+	r.Log.Info(fmt.Sprintf("Rebalancing the slots by the weights of the leaders: %v", weights))
+	return r.RedisCLI.ClusterRebalanceWeighted(healthyLeaderIp, true, weights)
+}
+
+// Returns the pods of the leaders by their node ID, as they are seen by the given node.
+// A leader that is not a node of the view is returned with an empty pod.
+func (r *RedisClusterReconciler) leaderPodsById(v *view.RedisClusterView, nodeIp string) (map[string]corev1.Pod, error) {
+	clusterNodes, _, err := r.RedisCLI.ClusterNodes(nodeIp)
+	if err != nil || clusterNodes == nil {
+		return nil, errors.Errorf("Could not read the nodes table from [%s]: %v", nodeIp, err)
+	}
+	podsById := map[string]corev1.Pod{}
+	for _, n := range v.Nodes {
+		if n != nil {
+			podsById[n.Id] = n.Pod
+		}
+	}
+	leaderPods := map[string]corev1.Pod{}
+	for _, clusterNode := range *clusterNodes {
+		if strings.Contains(clusterNode.Flags, "master") && !strings.Contains(clusterNode.Flags, "fail") {
+			leaderPods[clusterNode.ID] = podsById[clusterNode.ID]
+		}
+	}
+	return leaderPods, nil
+}
+
+// Returns the weight of every leader by its node ID, from the pods of the leaders by their node ID
+func leaderSlotWeights(slotWeights *dbv1.SlotWeights, leaderPods map[string]corev1.Pod) map[string]int64 {
+	weights := map[string]int64{}
+	memoryLimits := map[string]int64{}
+	smallestLimit := int64(0)
+	for id, pod := range leaderPods {
+		weights[id] = 1
+		if slotWeights == nil {
+			continue
+		}
+		if weight, exists := slotWeights.Shards[pod.Labels["leader-name"]]; exists {
+			weights[id] = int64(weight)
+		}
+		if limit := redisMemoryLimit(pod) / mebibyte; slotWeights.FromMemoryLimit && limit > 0 {
+			memoryLimits[id] = limit
+			if smallestLimit == 0 || limit < smallestLimit {
+				smallestLimit = limit
+			}
+		}
+	}
+	if smallestLimit > 0 {
+		for id := range weights {
+			if limit, exists := memoryLimits[id]; exists {
+				weights[id] *= limit
+			} else {
+				weights[id] *= smallestLimit
+			}
+		}
+	}
+	return weights
+}
+
+// Returns the memory limit of the redis container of the pod in bytes, 0 when it is not set
+func redisMemoryLimit(pod corev1.Pod) int64 {
+	for _, container := range pod.Spec.Containers {
+		if container.Name == dbv1.RedisContainerName {
+			return container.Resources.Limits.Memory().Value()
+		}
+	}
+	return 0
+}
+
+// Counts the slots of the slot ranges of a CLUSTER NODES line, the open slots are not counted
+func countSlots(slotRanges []string) int {
+	count := 0
+	for _, slotRange := range slotRanges {
+		if strings.HasPrefix(slotRange, "[") {
+			continue
+		}
+		bounds := strings.SplitN(slotRange, "-", 2)
+		first, err := strconv.Atoi(bounds[0])
+		if err != nil {
+			continue
+		}
+		last := first
+		if len(bounds) == 2 {
+			if last, err = strconv.Atoi(bounds[1]); err != nil {
+				continue
+			}
+		}
+		count += last - first + 1
+	}
+	return count
+}
+
+// Reports the slots of every leader of the nodes status against the target of its weight
+func (r *RedisClusterReconciler) updateSlotDistribution(redisCluster *dbv1.RedisCluster, pods []corev1.Pod) {
+	podsByName := map[string]corev1.Pod{}
+	for _, pod := range pods {
+		podsByName[pod.Name] = pod
+	}
+	var leaders []dbv1.RedisNodeStatus
+	leaderPods := map[string]corev1.Pod{}
+	for _, n := range redisCluster.Status.Nodes {
+		if n.Role == "leader" && n.NodeID != "" {
+			leaders = append(leaders, n)
+			leaderPods[n.NodeID] = podsByName[n.Name]
+		}
+	}
+	if len(leaders) == 0 {
+		redisCluster.Status.SlotDistribution = nil
+		return
+	}
+	weights := leaderSlotWeights(redisCluster.Spec.SlotWeights, leaderPods)
+	slots := make([]int, len(leaders))
+	leaderWeights := make([]float64, len(leaders))
+	for i, n := range leaders {
+		slots[i] = countSlots(n.Slots)
+		leaderWeights[i] = float64(weights[n.NodeID])
+	}
+	targets := rediscli.WeightedSlotTargets(slots, leaderWeights)
+	distribution := make([]dbv1.LeaderSlots, len(leaders))
+	for i, n := range leaders {
+		distribution[i] = dbv1.LeaderSlots{
+			Name:        n.Name,
+			NodeID:      n.NodeID,
+			Weight:      weights[n.NodeID],
+			Slots:       slots[i],
+			TargetSlots: targets[i],
+			Gap:         targets[i] - slots[i],
+		}
+	}
+	redisCluster.Status.SlotDistribution = distribution
+}
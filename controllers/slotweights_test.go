@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newTestLeaderPod(leaderName string, memoryLimit string) corev1.Pod {
+	container := corev1.Container{Name: dbv1.RedisContainerName}
+	if memoryLimit != "" {
+		container.Resources.Limits = corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(memoryLimit)}
+	}
+	return corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: leaderName, Labels: map[string]string{"leader-name": leaderName}},
+		Spec:       corev1.PodSpec{Containers: []corev1.Container{container}},
+	}
+}
+
+func TestLeaderSlotWeights(test *testing.T) {
+	leaderPods := map[string]corev1.Pod{
+		"a": newTestLeaderPod("redis-node-0", "1Gi"),
+		"b": newTestLeaderPod("redis-node-1", "2Gi"),
+		"c": newTestLeaderPod("redis-node-2", ""),
+	}
+	cases := map[string]struct {
+		slotWeights *dbv1.SlotWeights
+		weights     map[string]int64
+	}{
+		"no weights":    {nil, map[string]int64{"a": 1, "b": 1, "c": 1}},
+		"shard weights": {&dbv1.SlotWeights{Shards: map[string]int{"redis-node-1": 3}}, map[string]int64{"a": 1, "b": 3, "c": 1}},
+		// A leader without a memory limit is weighed by the smallest limit
+		"memory limits": {&dbv1.SlotWeights{FromMemoryLimit: true}, map[string]int64{"a": 1024, "b": 2048, "c": 1024}},
+		"both weights": {&dbv1.SlotWeights{FromMemoryLimit: true, Shards: map[string]int{"redis-node-0": 2}},
+			map[string]int64{"a": 2048, "b": 2048, "c": 1024}},
+	}
+	for name, expected := range cases {
+		if weights := leaderSlotWeights(expected.slotWeights, leaderPods); !reflect.DeepEqual(weights, expected.weights) {
+			test.Errorf("Expected the weights by %s to be %v, got %v", name, expected.weights, weights)
+		}
+	}
+}
+
+func TestCountSlots(test *testing.T) {
+	cases := map[string]int{
+		"":                          0,
+		"0-5460":                    5461,
+		"42":                        1,
+		"0-99,100,200-299":          201,
+		"0-9,[10->-abc],[11-<-def]": 10,
+		"x-9,5-y":                   0,
+	}
+	for slots, expected := range cases {
+		var slotRanges []string
+		if slots != "" {
+			slotRanges = strings.Split(slots, ",")
+		}
+		if count := countSlots(slotRanges); count != expected {
+			test.Errorf("Expected the slot ranges [%s] to count %d slots, got %d", slots, expected, count)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package controllers
+
+import (
+	"fmt"
+	"sort"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/view"
+	corev1 "k8s.io/api/core/v1"
+)
+
+/*
+	Every shard of the state map has a designated leader, redis-node-N, and its followers, redis-node-N-M.
+	A follower that is promoted by a failover, of the operator or of redis, stays the leader of the shard, while
+	its pod name and its redis-node-role label still tell it is a follower.
+	With the SwitchbackLeaders setter, once the designated leader of such a shard is back as a follower, its pod is
+	ready, it is NodeOK in the state map and its data is synced with the current leader, as judged by waitForRedisSync,
+	the operator runs a graceful CLUSTER FAILOVER on it, so the roles match the names again. One shard is switched
+	back per reconcile loop, within the maintenance windows. With the BalanceZones setter, a switchback that would
+	leave the leaders skewed over the zones is skipped, the zone balancing takes precedence over the names.
+*/
+
+func isPodReady(pod corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// Switches back a single shard of a healthy cluster to its designated leader, it is enabled by the SwitchbackLeaders setter.
+// Returns true if a switchback was attempted.
+func (r *RedisClusterReconciler) switchbackLeaders(redisCluster *dbv1.RedisCluster, v *view.RedisClusterView) (bool, error) {
+	if !r.Config.Setters.SwitchbackLeaders || r.RedisClusterStateView.ClusterState != view.ClusterOK {
+		return false, nil
+	}
+	isLeader, leaderOf, err := r.currentRoles(v)
+	if err != nil {
+		return false, err
+	}
+	candidates := []string{}
+	for name, currentLeader := range leaderOf {
+		n, inMap := r.RedisClusterStateView.Nodes[name]
+		if !inMap || n.Name != n.LeaderName || n.NodeState != view.NodeOK {
+			continue
+		}
+		if leader, exists := v.Nodes[currentLeader]; !exists || leader == nil || leader.LeaderName != name {
+			continue
+		}
+		if !isPodReady(v.Nodes[name].Pod) {
+			continue
+		}
+		candidates = append(candidates, name)
+	}
+	sort.Strings(candidates)
+	for _, name := range candidates {
+		node, currentLeader := v.Nodes[name], v.Nodes[leaderOf[name]]
+		if r.Config.Setters.BalanceZones && r.switchbackSkewsZones(v, isLeader, name, currentLeader.Name) {
+			r.Log.Info(fmt.Sprintf("Switchback of leader [%s] is skipped, it would skew the leaders over the zones", name))
+			continue
+		}
+		// The sync is waited for only within the maintenance windows, the switchback would not follow it otherwise
+		if r.deferDisruption(redisCluster, "Leader switchback") {
+			return false, nil
+		}
+		m := &view.MissingNodeView{
+			Name:              name,
+			LeaderName:        name,
+			CurrentMasterName: currentLeader.Name,
+			CurrentMasterId:   currentLeader.Id,
+			CurrentMasterIp:   currentLeader.Ip,
+		}
+		if err := r.waitForRedisSync(m, node.Ip); err != nil {
+			r.Log.Info(fmt.Sprintf("Switchback of leader [%s] is postponed, it is not synced with [%s]: %v", name, currentLeader.Name, err))
+			continue
+		}
+		r.planReason("Leader [%s] takes over from follower [%s] after a failover", name, currentLeader.Name)
+		if err := r.attemptToFailOver(node.Ip); err != nil {
+			r.recordEvent(corev1.EventTypeWarning, eventFailoverFailed, "Leader [%s] could not take over from follower [%s]: %v", name, currentLeader.Name, err)
+			return true, err
+		}
+		r.recordEvent(corev1.EventTypeNormal, eventLeaderSwitchedBack, "Leader [%s] took over from follower [%s] after a failover", name, currentLeader.Name)
+		return true, nil
+	}
+	return false, nil
+}
+
+// Checks if moving the leadership of a shard from the current leader to the designated one would leave
+// the zones of the cluster more than one leader apart, and further apart than they are
+func (r *RedisClusterReconciler) switchbackSkewsZones(v *view.RedisClusterView, isLeader map[string]bool, designatedLeader string, currentLeader string) bool {
+	zones := r.podZones(clusterViewPods(v))
+	if _, known := zones[designatedLeader]; !known {
+		return false
+	}
+	leadersAfter := map[string]bool{designatedLeader: true}
+	for name := range isLeader {
+		if name != currentLeader {
+			leadersAfter[name] = true
+		}
+	}
+	minBefore, maxBefore := leadersSpread(countLeadersPerZone(zones, isLeader))
+	minAfter, maxAfter := leadersSpread(countLeadersPerZone(zones, leadersAfter))
+	return maxAfter-minAfter > 1 && maxAfter-minAfter > maxBefore-minBefore
+}
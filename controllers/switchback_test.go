@@ -0,0 +1,77 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/PayU/redis-operator/controllers/view"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func TestIsPodReady(test *testing.T) {
+	condition := func(conditionType corev1.PodConditionType, status corev1.ConditionStatus) corev1.PodCondition {
+		return corev1.PodCondition{Type: conditionType, Status: status}
+	}
+	cases := map[string]struct {
+		phase      corev1.PodPhase
+		conditions []corev1.PodCondition
+		ready      bool
+	}{
+		"ready":                 {corev1.PodRunning, []corev1.PodCondition{condition(corev1.PodScheduled, corev1.ConditionTrue), condition(corev1.PodReady, corev1.ConditionTrue)}, true},
+		"not ready":             {corev1.PodRunning, []corev1.PodCondition{condition(corev1.PodReady, corev1.ConditionFalse)}, false},
+		"no ready condition":    {corev1.PodRunning, []corev1.PodCondition{condition(corev1.PodScheduled, corev1.ConditionTrue)}, false},
+		"other condition false": {corev1.PodRunning, []corev1.PodCondition{condition("example.com/gate", corev1.ConditionFalse), condition(corev1.PodReady, corev1.ConditionTrue)}, true},
+		"pending":               {corev1.PodPending, []corev1.PodCondition{condition(corev1.PodReady, corev1.ConditionTrue)}, false},
+	}
+	for name, expected := range cases {
+		pod := corev1.Pod{Status: corev1.PodStatus{Phase: expected.phase, Conditions: expected.conditions}}
+		if ready := isPodReady(pod); ready != expected.ready {
+			test.Errorf("Expected the %s pod to be ready: %v, got %v", name, expected.ready, ready)
+		}
+	}
+}
+
+func TestSwitchbackSkewsZones(test *testing.T) {
+	zones := map[string]string{
+		"redis-node-0":   "zone-a",
+		"redis-node-0-1": "zone-b",
+		"redis-node-1":   "zone-a",
+		"redis-node-1-1": "zone-c",
+		"redis-node-2":   "zone-b",
+		"redis-node-2-1": "zone-c",
+	}
+	objects := []runtime.Object{}
+	for _, zone := range []string{"zone-a", "zone-b", "zone-c"} {
+		objects = append(objects, &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "k8s-" + zone, Labels: map[string]string{zoneLabel: zone}}})
+	}
+	v := &view.RedisClusterView{Nodes: map[string]*view.NodeView{}}
+	for name, zone := range zones {
+		v.Nodes[name] = &view.NodeView{Name: name, Pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.PodSpec{NodeName: "k8s-" + zone}}}
+	}
+	// redis-node-3 is not scheduled yet
+	v.Nodes["redis-node-3"] = &view.NodeView{Name: "redis-node-3", Pod: corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "redis-node-3"}}}
+	r := newTestReconciler(test, objects...)
+
+	cases := map[string]struct {
+		leaders          []string
+		designatedLeader string
+		currentLeader    string
+		skews            bool
+	}{
+		// The zone balancing moved the leader of the shard from zone-a to zone-c
+		"undoes a zone balancing failover": {[]string{"redis-node-0", "redis-node-1-1", "redis-node-2"}, "redis-node-1", "redis-node-1-1", true},
+		"evens out the zones":              {[]string{"redis-node-0", "redis-node-1-1", "redis-node-2-1"}, "redis-node-2", "redis-node-2-1", false},
+		"keeps the zones apart":            {[]string{"redis-node-0", "redis-node-1", "redis-node-2-1"}, "redis-node-2", "redis-node-2-1", false},
+		"unknown zone":                     {[]string{"redis-node-0", "redis-node-1-1", "redis-node-2"}, "redis-node-3", "redis-node-2", false},
+	}
+	for name, expected := range cases {
+		isLeader := map[string]bool{}
+		for _, leader := range expected.leaders {
+			isLeader[leader] = true
+		}
+		if skews := r.switchbackSkewsZones(v, isLeader, expected.designatedLeader, expected.currentLeader); skews != expected.skews {
+			test.Errorf("Expected the switchback that %s to skew the zones: %v, got %v", name, expected.skews, skews)
+		}
+	}
+}
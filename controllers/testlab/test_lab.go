@@ -25,6 +25,10 @@ type TestLab struct {
 	RedisClusterClient *redisclient.RedisClusterClient
 	Log                logr.Logger
 	Report             string
+	// Stops the test suite between its tests when done, the suite runs to its end when not set
+	Context context.Context
+	// Reports the progress of the test suite when set
+	Progress func(done int, total int, message string)
 }
 
 var fetchViewInterval = 10 * time.Second
@@ -61,57 +65,33 @@ func (t *TestLab) RunTest(nodes *map[string]*view.NodeStateView, withData bool)
 	}
 }
 
+const testSuitSize = 6
+
 func (t *TestLab) testSuit(nodes *map[string]*view.NodeStateView) {
-	test_1 := t.runTest(nodes, 1)
-	if !test_1 {
-		return
-	}
-	test_2 := t.runTest(nodes, 2)
-	if !test_2 {
-		return
-	}
-	test_3 := t.runTest(nodes, 3)
-	if !test_3 {
-		return
-	}
-	test_4 := t.runTest(nodes, 4)
-	if !test_4 {
-		return
-	}
-	test_5 := t.runTest(nodes, 5)
-	if !test_5 {
-		return
-	}
-	test_6 := t.runTest(nodes, 6)
-	if !test_6 {
-		return
-	}
+	t.runTestSuit(nodes, t.runTest)
 }
 
 func (t *TestLab) testSuitWithData(nodes *map[string]*view.NodeStateView) {
-	test_1 := t.runTestWithData(nodes, 1)
-	if !test_1 {
-		return
-	}
-	test_2 := t.runTestWithData(nodes, 2)
-	if !test_2 {
-		return
-	}
-	test_3 := t.runTestWithData(nodes, 3)
-	if !test_3 {
-		return
-	}
-	test_4 := t.runTestWithData(nodes, 4)
-	if !test_4 {
-		return
-	}
-	test_5 := t.runTestWithData(nodes, 5)
-	if !test_5 {
-		return
+	t.runTestSuit(nodes, t.runTestWithData)
+}
+
+func (t *TestLab) runTestSuit(nodes *map[string]*view.NodeStateView, run func(*map[string]*view.NodeStateView, int) bool) {
+	for testNum := 1; testNum <= testSuitSize; testNum++ {
+		if t.Context != nil && t.Context.Err() != nil {
+			t.Report += fmt.Sprintf("\n[TEST LAB] Test suite cancelled before test %v\n", testNum)
+			return
+		}
+		t.reportProgress(testNum-1, fmt.Sprintf("Running test %v", testNum))
+		if !run(nodes, testNum) {
+			return
+		}
 	}
-	test_6 := t.runTestWithData(nodes, 6)
-	if !test_6 {
-		return
+	t.reportProgress(testSuitSize, "Test suite completed")
+}
+
+func (t *TestLab) reportProgress(done int, message string) {
+	if t.Progress != nil {
+		t.Progress(done, testSuitSize, message)
 	}
 }
 
@@ -465,7 +445,12 @@ func (t *TestLab) isClusterAligned(expectedNodes *map[string]*view.NodeStateView
 	totalExpectedNodes := t.Cluster.Spec.LeaderCount * (t.Cluster.Spec.LeaderFollowersCount + 1)
 	clusterOK := len(v.Nodes) == totalExpectedNodes && len(*expectedNodes) == totalExpectedNodes
 	if clusterOK {
-		t.RedisClusterClient = redisclient.GetRedisClusterClient(v, t.RedisCLI)
+		clusterClient, err := redisclient.GetRedisClusterClient(v, t.RedisCLI)
+		if err != nil {
+			t.Log.Error(err, "Could not create the cluster client")
+			return false
+		}
+		t.RedisClusterClient = clusterClient
 	}
 	return clusterOK
 }
@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/rediscli"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+	The certificates of the RedisCluster are read from the secret referenced by spec.tls.secretRef, under the
+	'ca.crt', 'tls.crt' and 'tls.key' keys. The secret is mounted into the Redis pods, which serve TLS on the
+	Redis port and use it for the replication and the cluster bus as well; the plain port is closed.
+	The operator writes the certificates to files under the temp dir, named by a hash of their content, which
+	are passed to redis-cli by the '--tls' flags and loaded by the go-redis clients. Renewed certificates are
+	written to new files on the next reconcile loop, since the secret is watched.
+*/
+
+const (
+	redisTLSVolumeName = "redis-tls"
+	redisTLSMountPath  = "/etc/redis/tls"
+	redisTLSCACertKey  = "ca.crt"
+	defaultRedisConfig = "/usr/local/etc/redis/redis.conf"
+)
+
+var redisTLSKeys = []string{redisTLSCACertKey, corev1.TLSCertKey, corev1.TLSPrivateKeyKey}
+
+func redisTLSDir(key types.NamespacedName) string {
+	return filepath.Join(os.TempDir(), "redis-operator-tls", key.Namespace, key.Name)
+}
+
+// Loads the certificates of the RedisCluster and writes them to files for the Redis clients of the operator
+func loadRedisTLS(c client.Client, redisCluster *dbv1.RedisCluster) (*rediscli.RedisTLS, error) {
+	if redisCluster.Spec.TLS == nil {
+		return nil, nil
+	}
+	key := types.NamespacedName{Namespace: redisCluster.Namespace, Name: redisCluster.Spec.TLS.SecretRef.Name}
+	var secret corev1.Secret
+	if err := c.Get(context.Background(), key, &secret); err != nil {
+		return nil, errors.Wrapf(err, "Failed to get the TLS secret [%s]", key)
+	}
+	hash := sha256.New()
+	for _, k := range redisTLSKeys {
+		content, exists := secret.Data[k]
+		if !exists {
+			return nil, errors.Errorf("The TLS secret [%s] has no '%s' key", key, k)
+		}
+		hash.Write(content)
+	}
+	clusterDir := redisTLSDir(types.NamespacedName{Namespace: redisCluster.Namespace, Name: redisCluster.Name})
+	dir := filepath.Join(clusterDir, hex.EncodeToString(hash.Sum(nil))[:16])
+	files := &rediscli.RedisTLS{
+		CACertFile: filepath.Join(dir, redisTLSCACertKey),
+		CertFile:   filepath.Join(dir, corev1.TLSCertKey),
+		KeyFile:    filepath.Join(dir, corev1.TLSPrivateKeyKey),
+	}
+	if _, err := os.Stat(dir); err == nil {
+		return files, nil
+	}
+	if err := os.RemoveAll(clusterDir); err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	for _, k := range redisTLSKeys {
+		if err := ioutil.WriteFile(filepath.Join(dir, k), secret.Data[k], 0600); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// Loads the certificates of the cluster, the RedisCLI of the reconciler is replaced when they change
+func (r *RedisClusterReconciler) reloadRedisTLS(redisCluster *dbv1.RedisCluster) error {
+	tls, err := loadRedisTLS(r.Client, redisCluster)
+	if err != nil {
+		r.recordEvent(corev1.EventTypeWarning, eventTLSLoadFailed, "Failed to load the TLS certificates: %v", err)
+		return err
+	}
+	if reflect.DeepEqual(tls, r.RedisCLI.TLS) {
+		return nil
+	}
+	r.RedisCLI = r.RedisCLI.WithTLS(tls)
+	if tls != nil {
+		r.Log.Info(fmt.Sprintf("Loaded the TLS certificates from secret [%s]", redisCluster.Spec.TLS.SecretRef.Name))
+		r.recordEvent(corev1.EventTypeNormal, eventTLSLoaded, "Loaded the TLS certificates from secret [%s]", redisCluster.Spec.TLS.SecretRef.Name)
+	}
+	return nil
+}
+
+// Mounts the certificates into the redis container and starts redis with TLS on the Redis port
+func (r *RedisClusterReconciler) addRedisTLS(redisCluster *dbv1.RedisCluster, spec *corev1.PodSpec) {
+	if redisCluster.Spec.TLS == nil {
+		return
+	}
+	spec.Volumes = append(spec.Volumes, corev1.Volume{
+		Name: redisTLSVolumeName,
+		VolumeSource: corev1.VolumeSource{
+			Secret: &corev1.SecretVolumeSource{SecretName: redisCluster.Spec.TLS.SecretRef.Name},
+		},
+	})
+	for i := range spec.Containers {
+		container := &spec.Containers[i]
+		if container.Name != dbv1.RedisContainerName {
+			continue
+		}
+		container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+			Name:      redisTLSVolumeName,
+			MountPath: redisTLSMountPath,
+			ReadOnly:  true,
+		})
+		// The options given to redis-server after the config file override the ones found in it
+		if len(container.Command) == 0 && len(container.Args) == 0 {
+			container.Args = []string{"redis-server", redisConfigPath(container)}
+		}
+		container.Args = append(container.Args,
+			"--port", "0",
+			"--tls-port", r.RedisCLI.Port,
+			"--tls-cluster", "yes",
+			"--tls-replication", "yes",
+			"--tls-ca-cert-file", filepath.Join(redisTLSMountPath, redisTLSCACertKey),
+			"--tls-cert-file", filepath.Join(redisTLSMountPath, corev1.TLSCertKey),
+			"--tls-key-file", filepath.Join(redisTLSMountPath, corev1.TLSPrivateKeyKey),
+		)
+	}
+}
+
+func redisConfigPath(container *corev1.Container) string {
+	for _, env := range container.Env {
+		if env.Name == "CONF_PATH" && env.Value != "" {
+			return env.Value
+		}
+	}
+	return defaultRedisConfig
+}
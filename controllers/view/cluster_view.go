@@ -46,9 +46,10 @@ type RedisClusterStateView struct {
 }
 
 type NodeView struct {
-	Name       string
-	Id         string
-	Namespace  string
+	Name      string
+	Id        string
+	Namespace string
+	// The address the node is reached at, its pod IP or its host name when the cluster announces host names
 	Ip         string
 	LeaderName string
 	IsLeader   bool
@@ -142,7 +143,7 @@ func (v *RedisClusterView) CreateView(pods []corev1.Pod, redisCli *rediscli.Redi
 			Name:       pod.Name,
 			Id:         "",
 			Namespace:  pod.Namespace,
-			Ip:         pod.Status.PodIP,
+			Ip:         NodeAddress(pod),
 			LeaderName: getLeaderName(pod),
 			IsLeader:   pod.Labels["redis-node-role"] == "leader",
 			Pod:        pod,
@@ -155,6 +156,15 @@ func (v *RedisClusterView) CreateView(pods []corev1.Pod, redisCli *rediscli.Redi
 	return nil
 }
 
+// Returns the stable host name of the pod when it is set by the headless service of the cluster, otherwise its IP.
+// The host name is the one that the node announces to the cluster.
+func NodeAddress(pod corev1.Pod) string {
+	if pod.Spec.Hostname != "" && pod.Spec.Subdomain != "" {
+		return fmt.Sprintf("%s.%s.%s.svc", pod.Spec.Hostname, pod.Spec.Subdomain, pod.Namespace)
+	}
+	return pod.Status.PodIP
+}
+
 func getLeaderName(pod corev1.Pod) string {
 	leaderName := pod.Labels["leader-name"]
 	if len(leaderName) > 0 {
@@ -0,0 +1,313 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+	"github.com/PayU/redis-operator/controllers/view"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+	The zone of every node is read from the topology.kubernetes.io/zone label of the kubernetes node that runs
+	its pod, and reported on the node status together with the number of leaders and followers of each zone.
+	The default affinity of the pods prefers to place the leaders, and the nodes of each shard, in distinct zones,
+	which is not kept after failovers: the leaders of the cluster gather in the zones that outlived the failures.
+	With the BalanceZones setter, the reconciler of a healthy cluster runs one of the following per reconcile loop:
+	a graceful CLUSTER FAILOVER on a synced follower of a zone that holds at least two leaders less than the most
+	loaded zone, whose leader is in the most loaded zone, or else the recreation of a follower that shares the zone
+	of its leader while another zone holds no node of its shard, so it is scheduled there by the default affinity.
+	Both are disruptive flows and run within the maintenance windows only.
+*/
+
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+
+const (
+	zoneLabel       = "topology.kubernetes.io/zone"
+	legacyZoneLabel = "failure-domain.beta.kubernetes.io/zone"
+
+	// A follower that was recreated may be scheduled to the zone of its leader again, when the other zones have no room for it
+	zoneRelocationCooldown = 30 * time.Minute
+)
+
+// Returns the zones of the kubernetes nodes that run the pods by pod name, pods that are not scheduled
+// or whose kubernetes node has no zone label are left out
+func (r *RedisClusterReconciler) podZones(pods []corev1.Pod) map[string]string {
+	zones := map[string]string{}
+	nodeZones := map[string]string{}
+	for _, pod := range pods {
+		if pod.Spec.NodeName == "" {
+			continue
+		}
+		zone, known := nodeZones[pod.Spec.NodeName]
+		if !known {
+			var node corev1.Node
+			if err := r.Get(context.Background(), client.ObjectKey{Name: pod.Spec.NodeName}, &node); err != nil {
+				r.Log.Info(fmt.Sprintf("[Warn] Could not get the zone of kubernetes node [%s]: %v", pod.Spec.NodeName, err))
+			} else if zone = node.Labels[zoneLabel]; zone == "" {
+				zone = node.Labels[legacyZoneLabel]
+			}
+			nodeZones[pod.Spec.NodeName] = zone
+		}
+		if zone != "" {
+			zones[pod.Name] = zone
+		}
+	}
+	return zones
+}
+
+func clusterViewPods(v *view.RedisClusterView) []corev1.Pod {
+	pods := []corev1.Pod{}
+	for _, n := range v.Nodes {
+		if n != nil {
+			pods = append(pods, n.Pod)
+		}
+	}
+	return pods
+}
+
+// Counts the leaders of each zone that holds a node of the cluster, including the zones without leaders
+func countLeadersPerZone(zones map[string]string, isLeader map[string]bool) map[string]int {
+	leadersPerZone := map[string]int{}
+	for _, zone := range zones {
+		leadersPerZone[zone] = 0
+	}
+	for name := range isLeader {
+		if zone, known := zones[name]; known {
+			leadersPerZone[zone]++
+		}
+	}
+	return leadersPerZone
+}
+
+// Returns the least and the most number of leaders held by a zone
+func leadersSpread(leadersPerZone map[string]int) (int, int) {
+	minLeaders, maxLeaders := -1, 0
+	for _, count := range leadersPerZone {
+		if minLeaders < 0 || count < minLeaders {
+			minLeaders = count
+		}
+		if count > maxLeaders {
+			maxLeaders = count
+		}
+	}
+	if minLeaders < 0 {
+		minLeaders = 0
+	}
+	return minLeaders, maxLeaders
+}
+
+// Counts the leaders and the followers of each zone by the roles the nodes reported, and returns the followers
+// that share the zone of the leader of their shard
+func zoneSpread(nodes []dbv1.RedisNodeStatus, zones map[string]string) ([]dbv1.ZoneSpread, []string) {
+	counts := map[string]*dbv1.ZoneSpread{}
+	shardLeaderZones := map[string]string{}
+	for _, n := range nodes {
+		zone, scheduled := zones[n.Name]
+		if !scheduled {
+			continue
+		}
+		if _, exists := counts[zone]; !exists {
+			counts[zone] = &dbv1.ZoneSpread{Zone: zone}
+		}
+		switch n.Role {
+		case "leader":
+			counts[zone].Leaders++
+			shardLeaderZones[n.LeaderName] = zone
+		case "follower":
+			counts[zone].Followers++
+		}
+	}
+	colocated := []string{}
+	for _, n := range nodes {
+		if zone, scheduled := zones[n.Name]; scheduled && n.Role == "follower" && shardLeaderZones[n.LeaderName] == zone {
+			colocated = append(colocated, n.Name)
+		}
+	}
+	spread := make([]dbv1.ZoneSpread, 0, len(counts))
+	for _, s := range counts {
+		spread = append(spread, *s)
+	}
+	sort.Slice(spread, func(i, j int) bool {
+		return spread[i].Zone < spread[j].Zone
+	})
+	return spread, colocated
+}
+
+// Reports the zone spread of the nodes on the status and on the ZonesBalanced condition
+func (r *RedisClusterReconciler) updateZoneSpread(redisCluster *dbv1.RedisCluster, zones map[string]string) {
+	spread, colocated := zoneSpread(redisCluster.Status.Nodes, zones)
+	redisCluster.Status.Zones = spread
+	if len(spread) == 0 {
+		return
+	}
+	leadersPerZone := map[string]int{}
+	for _, s := range spread {
+		leadersPerZone[s.Zone] = s.Leaders
+	}
+	minLeaders, maxLeaders := leadersSpread(leadersPerZone)
+	switch {
+	case maxLeaders-minLeaders > 1:
+		setCondition(redisCluster, dbv1.ConditionZonesBalanced, false, reasonLeadersSkewed,
+			fmt.Sprintf("The zones hold between %d and %d leaders", minLeaders, maxLeaders))
+	case len(colocated) > 0:
+		setCondition(redisCluster, dbv1.ConditionZonesBalanced, false, reasonFollowersColocated,
+			fmt.Sprintf("Followers %v are in the zone of their leader", colocated))
+	default:
+		setCondition(redisCluster, dbv1.ConditionZonesBalanced, true, reasonZonesBalanced,
+			fmt.Sprintf("The leaders are spread evenly over %d zones", len(spread)))
+	}
+}
+
+// Returns the nodes that are leaders, and the leader that each follower replicates, by node name,
+// as they are reported by the nodes table of one of the nodes
+func (r *RedisClusterReconciler) currentRoles(v *view.RedisClusterView) (map[string]bool, map[string]string, error) {
+	names := map[string]string{}
+	var ip string
+	for _, n := range v.Nodes {
+		if n != nil {
+			names[n.Id] = n.Name
+			ip = n.Ip
+		}
+	}
+	if ip == "" {
+		return nil, nil, errors.New("No reachable node to read the nodes table from")
+	}
+	clusterNodes, _, err := r.RedisCLI.ClusterNodes(ip)
+	if err != nil || clusterNodes == nil {
+		return nil, nil, errors.Errorf("Could not read the nodes table from [%s]: %v", ip, err)
+	}
+	isLeader := map[string]bool{}
+	leaderOf := map[string]string{}
+	for _, clusterNode := range *clusterNodes {
+		name, known := names[clusterNode.ID]
+		if !known || strings.Contains(clusterNode.Flags, "fail") {
+			continue
+		}
+		if strings.Contains(clusterNode.Flags, "master") {
+			isLeader[name] = true
+		} else if leaderName, known := names[clusterNode.Leader]; known {
+			leaderOf[name] = leaderName
+		}
+	}
+	return isLeader, leaderOf, nil
+}
+
+// Runs a single step of the zone balancing of a healthy cluster, it is enabled by the BalanceZones setter
+func (r *RedisClusterReconciler) balanceZones(redisCluster *dbv1.RedisCluster, v *view.RedisClusterView) error {
+	if !r.Config.Setters.BalanceZones || r.RedisClusterStateView.ClusterState != view.ClusterOK {
+		return nil
+	}
+	pods := clusterViewPods(v)
+	zones := r.podZones(pods)
+	if len(zones) < len(pods) {
+		r.Log.Info("[Warn] Zone balancing is skipped, the zone of some of the nodes is unknown")
+		return nil
+	}
+	isLeader, leaderOf, err := r.currentRoles(v)
+	if err != nil {
+		return err
+	}
+	leadersPerZone := countLeadersPerZone(zones, isLeader)
+	if len(leadersPerZone) < 2 {
+		return nil
+	}
+	followers := make([]string, 0, len(leaderOf))
+	for name := range leaderOf {
+		followers = append(followers, name)
+	}
+	sort.Strings(followers)
+	if promoted, found := r.findZoneBalancingFollower(v, zones, leadersPerZone, followers, leaderOf); found {
+		if r.deferDisruption(redisCluster, "Zone balancing") {
+			return nil
+		}
+		leaderName := leaderOf[promoted.Name]
+		r.planReason("Follower [%s] in zone [%s] takes over leader [%s] in zone [%s] to balance the leaders over the zones", promoted.Name, zones[promoted.Name], leaderName, zones[leaderName])
+		if err := r.attemptToFailOver(promoted.Ip); err != nil {
+			r.recordEvent(corev1.EventTypeWarning, eventFailoverFailed, "Follower [%s] could not take over leader [%s] to balance the zones: %v", promoted.Name, leaderName, err)
+			return err
+		}
+		r.recordEvent(corev1.EventTypeNormal, eventZonesBalanced, "Follower [%s] in zone [%s] took over leader [%s] in zone [%s] to balance the leaders over the zones",
+			promoted.Name, zones[promoted.Name], leaderName, zones[leaderName])
+		return nil
+	}
+	for _, name := range followers {
+		if zones[name] != zones[leaderOf[name]] || !hasFreeZoneForShard(v.Nodes[name].LeaderName, v, zones, leadersPerZone) {
+			continue
+		}
+		if time.Since(r.lastZoneRelocation) < zoneRelocationCooldown {
+			r.Log.Info(fmt.Sprintf("Follower [%s] is in the zone of its leader, the relocation of followers is on cooldown", name))
+			return nil
+		}
+		if r.deferDisruption(redisCluster, "Zone balancing") {
+			return nil
+		}
+		r.planReason("Follower [%s] shares the zone of its leader and is recreated", name)
+		return r.relocateFollower(redisCluster, v.Nodes[name], v)
+	}
+	return nil
+}
+
+// Returns a synced follower in one of the least loaded zones whose leader is in one of the most loaded zones,
+// when the zones differ by more than one leader
+func (r *RedisClusterReconciler) findZoneBalancingFollower(v *view.RedisClusterView, zones map[string]string, leadersPerZone map[string]int, followers []string, leaderOf map[string]string) (*view.NodeView, bool) {
+	_, maxLeaders := leadersSpread(leadersPerZone)
+	candidates := []string{}
+	for _, name := range followers {
+		if leadersPerZone[zones[leaderOf[name]]] == maxLeaders && leadersPerZone[zones[name]] <= maxLeaders-2 {
+			candidates = append(candidates, name)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return leadersPerZone[zones[candidates[i]]] < leadersPerZone[zones[candidates[j]]]
+	})
+	for _, name := range candidates {
+		follower := v.Nodes[name]
+		info, _, err := r.RedisCLI.Info(follower.Ip)
+		if err != nil || info == nil || info.Replication["master_link_status"] != "up" {
+			r.Log.Info(fmt.Sprintf("Follower [%s] is not synced with its leader, it is not promoted to balance the zones", name))
+			continue
+		}
+		return follower, true
+	}
+	return nil, false
+}
+
+// Checks if one of the zones of the cluster holds no node of the shard
+func hasFreeZoneForShard(leaderName string, v *view.RedisClusterView, zones map[string]string, clusterZones map[string]int) bool {
+	shardZones := map[string]bool{}
+	for _, n := range v.Nodes {
+		if n != nil && n.LeaderName == leaderName {
+			shardZones[zones[n.Name]] = true
+		}
+	}
+	return len(shardZones) < len(clusterZones)
+}
+
+// Removes the follower from the cluster and deletes its pod, the recovery recreates it in the zone the scheduler prefers
+func (r *RedisClusterReconciler) relocateFollower(redisCluster *dbv1.RedisCluster, n *view.NodeView, v *view.RedisClusterView) error {
+	hl, found := r.findHealthyLeader(v)
+	if !found {
+		return errors.Errorf("Could not find a healthy leader to remove follower [%s] from the cluster", n.Name)
+	}
+	r.Log.Info(fmt.Sprintf("Relocating follower [%s] out of the zone of its leader", n.Name))
+	if err := r.removeNode(v.Nodes[hl].Ip, n); err != nil {
+		return err
+	}
+	if err := r.deletePod(n.Pod); err != nil {
+		return err
+	}
+	r.RedisClusterStateView.SetNodeState(n.Name, n.LeaderName, view.DeleteNodeKeepInMap)
+	r.waitForPodDelete(n.Pod)
+	r.lastZoneRelocation = time.Now()
+	r.RedisClusterStateView.NumOfHealthyReconcileLoopsInRow = 0
+	redisCluster.Status.ClusterState = string(Recovering)
+	r.recordEvent(corev1.EventTypeNormal, eventFollowerRelocated, "Follower [%s] shared the zone of its leader and was recreated", n.Name)
+	return nil
+}
@@ -0,0 +1,60 @@
+package controllers
+
+import (
+	"reflect"
+	"testing"
+
+	dbv1 "github.com/PayU/redis-operator/api/v1"
+)
+
+func TestZoneSpread(test *testing.T) {
+	nodes := []dbv1.RedisNodeStatus{
+		{Name: "redis-node-0", LeaderName: "redis-node-0", Role: "leader"},
+		{Name: "redis-node-0-1", LeaderName: "redis-node-0", Role: "follower"},
+		{Name: "redis-node-1", LeaderName: "redis-node-1", Role: "follower"},
+		{Name: "redis-node-1-1", LeaderName: "redis-node-1", Role: "leader"},
+		{Name: "redis-node-2", LeaderName: "redis-node-2", Role: "leader"},
+		{Name: "redis-node-2-1", LeaderName: "redis-node-2", Role: "follower"},
+	}
+	// redis-node-2-1 is not scheduled yet
+	zones := map[string]string{
+		"redis-node-0":   "zone-a",
+		"redis-node-0-1": "zone-a",
+		"redis-node-1":   "zone-b",
+		"redis-node-1-1": "zone-a",
+		"redis-node-2":   "zone-b",
+	}
+	spread, colocated := zoneSpread(nodes, zones)
+	expected := []dbv1.ZoneSpread{
+		{Zone: "zone-a", Leaders: 2, Followers: 1},
+		{Zone: "zone-b", Leaders: 1, Followers: 1},
+	}
+	if !reflect.DeepEqual(spread, expected) {
+		test.Errorf("Expected the zone spread %+v, got %+v", expected, spread)
+	}
+	// redis-node-1 follows the promoted redis-node-1-1 from another zone
+	if !reflect.DeepEqual(colocated, []string{"redis-node-0-1"}) {
+		test.Errorf("Expected only the follower in the zone of its leader to be colocated, got %v", colocated)
+	}
+
+	if spread, colocated := zoneSpread(nodes, map[string]string{}); len(spread) != 0 || len(colocated) != 0 {
+		test.Errorf("Expected no spread of nodes without zones, got %+v %v", spread, colocated)
+	}
+}
+
+func TestLeadersSpread(test *testing.T) {
+	cases := map[string]struct {
+		leadersPerZone map[string]int
+		spread         [2]int
+	}{
+		"no zones":            {map[string]int{}, [2]int{0, 0}},
+		"single zone":         {map[string]int{"zone-a": 3}, [2]int{3, 3}},
+		"skewed zones":        {map[string]int{"zone-a": 3, "zone-b": 1}, [2]int{1, 3}},
+		"zone with no leader": {map[string]int{"zone-a": 2, "zone-b": 1, "zone-c": 0}, [2]int{0, 2}},
+	}
+	for name, expected := range cases {
+		if minLeaders, maxLeaders := leadersSpread(expected.leadersPerZone); [2]int{minLeaders, maxLeaders} != expected.spread {
+			test.Errorf("Expected the leaders of %s to spread over %v, got %v", name, expected.spread, [2]int{minLeaders, maxLeaders})
+		}
+	}
+}
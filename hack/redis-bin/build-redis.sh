@@ -5,5 +5,5 @@ REDIS_VERSION=6.2.6
 cd /redis
 curl -LJs https://github.com/redis/redis/archive/refs/tags/$REDIS_VERSION.tar.gz | tar xz
 
-make -C redis-$REDIS_VERSION
+make -C redis-$REDIS_VERSION BUILD_TLS=yes
 cp redis-$REDIS_VERSION/src/redis-cli .
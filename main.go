@@ -15,7 +15,6 @@ import (
 	dbv1 "github.com/PayU/redis-operator/api/v1"
 	"github.com/PayU/redis-operator/controllers"
 	"github.com/PayU/redis-operator/controllers/rediscli"
-	"github.com/PayU/redis-operator/controllers/view"
 	"github.com/PayU/redis-operator/server"
 	"github.com/go-logr/logr"
 	// +kubebuilder:scaffold:imports
@@ -29,25 +28,20 @@ func init() {
 	// +kubebuilder:scaffold:scheme
 }
 
-func getRedisCLI(log *logr.Logger) *rediscli.RedisCLI {
+func getRedisCLI(log *logr.Logger, handler rediscli.CommandHandler) *rediscli.RedisCLI {
 	cli := rediscli.NewRedisCLI(log)
-	user := os.Getenv("REDIS_USERNAME")
-	if user != "" {
-		cli.Auth = &rediscli.RedisAuth{
-			User: user,
-		}
-	}
+	cli.Handler = handler
+	cli.Auth = rediscli.NewRedisAuthFromEnv()
 	return cli
 }
 
 func main() {
-	go server.StartServer()
-
 	startManager()
 }
 
 func startManager() {
-	var metricsAddr, namespace, enableLeaderElection, devmode string
+	var metricsAddr, namespace, enableLeaderElection, enableWebhooks, devmode, apiAuth string
+	var apiOptions server.Options
 
 	flag.StringVar(&metricsAddr, "metrics-addr", "0.0.0.0:9808", "The address the metric endpoint binds to.")
 	flag.StringVar(&namespace, "namespace", "default", "The namespace the operator will manage.")
@@ -55,7 +49,18 @@ func startManager() {
 	flag.StringVar(&enableLeaderElection, "enable-leader-election", "true",
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&enableWebhooks, "enable-webhooks", "false",
+		"Enable the RedisCluster defaulting and validating webhooks. "+
+			"Requires the webhook serving certificates to be mounted in the manager pod.")
+	flag.StringVar(&apiAuth, "api-auth", "false",
+		"Require every call of the HTTP API to present a bearer token or a client certificate, "+
+			"and to be authorized by a SubjectAccessReview.")
+	flag.StringVar(&apiOptions.CertDir, "api-cert-dir", "",
+		"Directory of the tls.crt and tls.key files the HTTP API is served with over HTTPS. Plain HTTP is served when not set.")
+	flag.StringVar(&apiOptions.ClientCAFile, "api-client-ca", "",
+		"CA file of the client certificates accepted by the HTTP API, requires api-cert-dir.")
 	flag.Parse()
+	apiOptions.Authentication = apiAuth == "true"
 
 	setupLogger := zap.New(zap.UseDevMode(devmode == "true")).WithName("setup")
 
@@ -89,21 +94,31 @@ func startManager() {
 		setupLogger.Info(fmt.Sprintf("Loaded config: %+v", operatorConfig.Config))
 	}
 
+	var redisCommandHandler rediscli.CommandHandler = &rediscli.RunTimeCommandHandler{}
+	if operatorConfig.Config.Setters.UseNativeRedisClient {
+		setupLogger.Info("Redis commands will be executed by the native redis client")
+		redisCommandHandler = rediscli.NewNativeCommandHandler()
+	}
+	if operatorConfig.Config.Setters.ObserveOnly {
+		setupLogger.Info("Observe-only mode is on, the operator will not change the clusters")
+	}
+
 	k8sManager := controllers.K8sManager{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
 		Log:    configLogger,
 	}
 
-	if err = (&controllers.RedisClusterReconciler{
-		Client:                mgr.GetClient(),
-		Log:                   rdcLogger,
-		Scheme:                mgr.GetScheme(),
-		RedisCLI:              getRedisCLI(&rdcLogger),
-		Config:                &operatorConfig.Config,
-		State:                 controllers.NotExists,
-		RedisClusterStateView: &view.RedisClusterStateView{Name: controllers.RedisClusterStateMapName},
-	}).SetupWithManager(mgr); err != nil {
+	rdcReconciler := &controllers.RedisClusterReconciler{
+		Client:   mgr.GetClient(),
+		Log:      rdcLogger,
+		Scheme:   mgr.GetScheme(),
+		RedisCLI: getRedisCLI(&rdcLogger, redisCommandHandler).WithObserveOnly(operatorConfig.Config.Setters.ObserveOnly),
+		Config:   &operatorConfig.Config,
+		Recorder: mgr.GetEventRecorderFor("redis-operator"),
+		State:    controllers.NotExists,
+	}
+	if err = rdcReconciler.SetupWithManager(mgr); err != nil {
 		setupLogger.Error(err, "unable to create controller", "controller", "RedisCluster")
 		os.Exit(1)
 	}
@@ -114,14 +129,24 @@ func startManager() {
 		K8sManager: &k8sManager,
 		Scheme:     mgr.GetScheme(),
 		Config:     &operatorConfig.Config,
-		RedisCLI:   getRedisCLI(&configLogger),
+		RedisCLI:   getRedisCLI(&configLogger, redisCommandHandler),
+		Recorder:   mgr.GetEventRecorderFor("redis-operator"),
 	}).SetupWithManager(mgr); err != nil {
 		setupLogger.Error(err, "unable to create controller", "controller", "RedisConfig")
 		os.Exit(1)
 	}
 
+	if enableWebhooks == "true" {
+		if err = (&dbv1.RedisCluster{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLogger.Error(err, "unable to create webhook", "webhook", "RedisCluster")
+			os.Exit(1)
+		}
+	}
+
 	operatorConfig.Log = configLogger
 
+	go server.StartServer(rdcReconciler, apiOptions)
+
 	// +kubebuilder:scaffold:builder
 
 	setupLogger.Info("starting manager")
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/PayU/redis-operator/controllers"
+	"github.com/labstack/echo/v4"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+/*
+	Every call of the HTTP API is audited: the caller, the endpoint, the addressed cluster and the outcome are
+	logged and kept in a ring buffer of the last calls, which is served by GET /audit and can be filtered by the
+	'namespace' and 'cluster' query params. The calls that act on a cluster (any method but GET) are recorded
+	as events on the RedisCluster as well.
+*/
+
+const auditLogSize = 1000
+
+// Outcomes of the audited calls
+const (
+	outcomeAllowed         = "allowed"
+	outcomeUnauthenticated = "unauthenticated"
+	outcomeForbidden       = "forbidden"
+	outcomeError           = "error"
+)
+
+type AuditEntry struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user"`
+	Method    string    `json:"method"`
+	Endpoint  string    `json:"endpoint"`
+	Namespace string    `json:"namespace,omitempty"`
+	Cluster   string    `json:"cluster,omitempty"`
+	Status    int       `json:"status"`
+	Outcome   string    `json:"outcome"`
+	Reason    string    `json:"reason,omitempty"`
+}
+
+type auditLog struct {
+	mutex   sync.Mutex
+	entries []AuditEntry
+	next    int
+}
+
+func newAuditLog(size int) *auditLog {
+	return &auditLog{entries: make([]AuditEntry, 0, size)}
+}
+
+func (l *auditLog) add(entry AuditEntry) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.entries) < cap(l.entries) {
+		l.entries = append(l.entries, entry)
+		return
+	}
+	l.entries[l.next] = entry
+	l.next = (l.next + 1) % len(l.entries)
+}
+
+// Returns the last entries of the cluster from the oldest to the newest, an empty namespace or cluster matches all
+func (l *auditLog) query(namespace string, cluster string, limit int) []AuditEntry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	matching := []AuditEntry{}
+	for i := 0; i < len(l.entries); i++ {
+		entry := l.entries[(l.next+i)%len(l.entries)]
+		if (namespace == "" || entry.Namespace == namespace) && (cluster == "" || entry.Cluster == cluster) {
+			matching = append(matching, entry)
+		}
+	}
+	if limit > 0 && len(matching) > limit {
+		matching = matching[len(matching)-limit:]
+	}
+	return matching
+}
+
+// Authenticates and authorizes the calls when an authenticator is set, and audits all of them
+func auditMiddleware(r *controllers.RedisClusterReconciler, auth *authenticator, log *auditLog) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			namespace, name := requestCluster(c)
+			entry := AuditEntry{
+				Time:      time.Now(),
+				User:      anonymousUser,
+				Method:    c.Request().Method,
+				Endpoint:  endpointName(c),
+				Namespace: namespace,
+				Cluster:   name,
+			}
+			err := authenticateAndServe(c, next, auth, &entry)
+			entry.Status = c.Response().Status
+			if httpErr, ok := err.(*echo.HTTPError); ok {
+				entry.Status = httpErr.Code
+			}
+			if entry.Outcome == outcomeAllowed && entry.Status >= http.StatusInternalServerError {
+				entry.Outcome = outcomeError
+			}
+			log.add(entry)
+			r.Log.Info(fmt.Sprintf("[Audit] %s %s %s/%s by [%s]: %s (%d) %s", entry.Method, entry.Endpoint, entry.Namespace, entry.Cluster, entry.User, entry.Outcome, entry.Status, entry.Reason))
+			if entry.Method != http.MethodGet && entry.Cluster != "" {
+				r.RecordEntryPointCall(types.NamespacedName{Namespace: entry.Namespace, Name: entry.Cluster},
+					entry.Outcome == outcomeAllowed, "%s %s by [%s]: %s (%d)", entry.Method, entry.Endpoint, entry.User, entry.Outcome, entry.Status)
+			}
+			return err
+		}
+	}
+}
+
+func authenticateAndServe(c echo.Context, next echo.HandlerFunc, auth *authenticator, entry *AuditEntry) error {
+	if auth != nil {
+		who, err := auth.authenticate(c)
+		if err != nil {
+			entry.Outcome, entry.Reason = outcomeUnauthenticated, err.Error()
+			return c.JSON(http.StatusUnauthorized, controllers.ErrorResponse{Error: "Unauthorized"})
+		}
+		entry.User = who.user
+		allowed, reason, err := auth.authorize(c, who)
+		if err != nil {
+			entry.Outcome, entry.Reason = outcomeError, err.Error()
+			return c.JSON(http.StatusInternalServerError, controllers.ErrorResponse{Error: "Could not authorize the request"})
+		}
+		if !allowed {
+			entry.Outcome, entry.Reason = outcomeForbidden, reason
+			return c.JSON(http.StatusForbidden, controllers.ErrorResponse{Error: "Forbidden: " + reason})
+		}
+	}
+	entry.Outcome = outcomeAllowed
+	return next(c)
+}
+
+/**
+Gets the last audited calls of the HTTP API, filtered by the 'namespace', 'cluster' and 'limit' query params
+**/
+func (l *auditLog) serveAudit(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	return c.JSON(http.StatusOK, l.query(c.QueryParam("namespace"), c.QueryParam("cluster"), limit))
+}
@@ -0,0 +1,26 @@
+package server
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestAuditLog(test *testing.T) {
+	log := newAuditLog(3)
+	for i := 0; i < 5; i++ {
+		log.add(AuditEntry{Namespace: "default", Cluster: fmt.Sprintf("rdc-%d", i%2), Endpoint: fmt.Sprintf("call-%d", i)})
+	}
+	entries := log.query("", "", 0)
+	if len(entries) != 3 || entries[0].Endpoint != "call-2" || entries[2].Endpoint != "call-4" {
+		test.Errorf("Expected the last 3 calls from the oldest to the newest, got %v", entries)
+	}
+	if entries = log.query("default", "rdc-0", 0); len(entries) != 2 || entries[1].Endpoint != "call-4" {
+		test.Errorf("Expected the 2 last calls of rdc-0, got %v", entries)
+	}
+	if entries = log.query("", "", 1); len(entries) != 1 || entries[0].Endpoint != "call-4" {
+		test.Errorf("Expected only the last call, got %v", entries)
+	}
+	if entries = log.query("other", "", 0); len(entries) != 0 {
+		test.Errorf("Expected no calls in namespace other, got %v", entries)
+	}
+}
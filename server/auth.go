@@ -0,0 +1,140 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+/*
+	When the authentication of the HTTP API is enabled, every call should present a bearer token, or a client
+	certificate signed by the client CA when the API is served with mTLS. Tokens are verified by a TokenReview,
+	certificates identify the user by their common name and the groups by their organizations.
+	The caller is then authorized by a SubjectAccessReview on the 'redisclusters/<endpoint>' subresource of the
+	addressed cluster, with the 'get' verb for GET calls, the 'delete' verb for DELETE calls and the 'create' verb
+	for the others. For example, the
+	rebalance of the clusters of a namespace is allowed by the rule:
+
+		- apiGroups: ["db.payu.com"]
+		  resources: ["redisclusters/rebalance"]
+		  verbs: ["create"]
+*/
+
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+const (
+	redisClusterGroup    = "db.payu.com"
+	redisClusterResource = "redisclusters"
+	anonymousUser        = "system:anonymous"
+)
+
+type caller struct {
+	user   string
+	uid    string
+	groups []string
+	extra  map[string]authorizationv1.ExtraValue
+}
+
+type authenticator struct {
+	client client.Client
+}
+
+// Identifies the caller by its client certificate, or by its bearer token
+func (a *authenticator) authenticate(c echo.Context) (*caller, error) {
+	if tlsState := c.Request().TLS; tlsState != nil && len(tlsState.VerifiedChains) > 0 {
+		cert := tlsState.VerifiedChains[0][0]
+		return &caller{user: cert.Subject.CommonName, groups: cert.Subject.Organization}, nil
+	}
+	header := c.Request().Header.Get(echo.HeaderAuthorization)
+	if !strings.HasPrefix(header, "Bearer ") {
+		return nil, errors.New("No bearer token or client certificate was presented")
+	}
+	review := &authenticationv1.TokenReview{
+		Spec: authenticationv1.TokenReviewSpec{Token: strings.TrimSpace(strings.TrimPrefix(header, "Bearer "))},
+	}
+	if err := a.client.Create(context.Background(), review); err != nil {
+		return nil, errors.Wrap(err, "Failed to review the bearer token")
+	}
+	if !review.Status.Authenticated {
+		return nil, errors.Errorf("The bearer token was not authenticated: %s", review.Status.Error)
+	}
+	extra := make(map[string]authorizationv1.ExtraValue)
+	for k, v := range review.Status.User.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	return &caller{
+		user:   review.Status.User.Username,
+		uid:    review.Status.User.UID,
+		groups: review.Status.User.Groups,
+		extra:  extra,
+	}, nil
+}
+
+// Checks that the caller is allowed to call the endpoint on the addressed cluster
+func (a *authenticator) authorize(c echo.Context, who *caller) (bool, string, error) {
+	namespace, name := requestCluster(c)
+	verb := "create"
+	switch c.Request().Method {
+	case http.MethodGet:
+		verb = "get"
+	case http.MethodDelete:
+		verb = "delete"
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   who.user,
+			UID:    who.uid,
+			Groups: who.groups,
+			Extra:  who.extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace:   namespace,
+				Verb:        verb,
+				Group:       redisClusterGroup,
+				Resource:    redisClusterResource,
+				Subresource: endpointName(c),
+				Name:        name,
+			},
+		},
+	}
+	if err := a.client.Create(context.Background(), review); err != nil {
+		return false, "", errors.Wrap(err, "Failed to review the access of the caller")
+	}
+	reason := review.Status.Reason
+	if !review.Status.Allowed && reason == "" {
+		reason = fmt.Sprintf("%s is not allowed to %s %s/%s", who.user, verb, redisClusterResource, endpointName(c))
+	}
+	return review.Status.Allowed, reason, nil
+}
+
+// The cluster is addressed by the path params of the request, or by its query params for the endpoints that are not
+// registered per cluster
+func requestCluster(c echo.Context) (string, string) {
+	if namespace := c.Param("namespace"); namespace != "" {
+		return namespace, c.Param("name")
+	}
+	return c.QueryParam("namespace"), c.QueryParam("cluster")
+}
+
+// The last segment of the route of the request that is not a path param, such as 'jobs' for
+// '/cluster/:namespace/:name/jobs/:id', or the last segment of its path when no route was matched
+func endpointName(c echo.Context) string {
+	if c.Path() == "" {
+		return path.Base(c.Request().URL.Path)
+	}
+	segments := strings.Split(c.Path(), "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		if segments[i] != "" && !strings.HasPrefix(segments[i], ":") {
+			return segments[i]
+		}
+	}
+	return ""
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/PayU/redis-operator/controllers"
+	"github.com/labstack/echo/v4"
+)
+
+/*
+	The OpenAPI document of the HTTP API is generated from the routes and served by GET /openapi.json.
+	The schemas of the responses are derived from the JSON tags of the response types, failures of every
+	operation are described by the ErrorResponse schema.
+*/
+
+const openAPIVersion = "3.0.3"
+
+// Query params of the routes
+var routeQueryParams = map[string][]string{
+	"/audit":              {"namespace", "cluster", "limit"},
+	clusterPath + "/plan": {"leaderCount", "leaderFollowersCount"},
+}
+
+type openAPIGenerator struct {
+	schemas map[string]interface{}
+}
+
+func newOpenAPIDocument(routes []route) map[string]interface{} {
+	g := &openAPIGenerator{schemas: map[string]interface{}{}}
+	errorSchema := g.schema(reflect.TypeOf(controllers.ErrorResponse{}))
+	paths := map[string]interface{}{}
+	for _, rt := range routes {
+		path, params := openAPIPath(rt.path)
+		for _, name := range routeQueryParams[rt.path] {
+			params = append(params, map[string]interface{}{
+				"name":   name,
+				"in":     "query",
+				"schema": map[string]interface{}{"type": "string"},
+			})
+		}
+		operation := map[string]interface{}{
+			"summary":     rt.summary,
+			"operationId": endpointOperationID(rt),
+		}
+		operation["responses"] = map[string]interface{}{
+			strconv.Itoa(rt.status): map[string]interface{}{
+				"description": http.StatusText(rt.status),
+				"content": map[string]interface{}{
+					echo.MIMEApplicationJSON: map[string]interface{}{"schema": g.schema(reflect.TypeOf(rt.response))},
+				},
+			},
+			"default": map[string]interface{}{
+				"description": "Error",
+				"content": map[string]interface{}{
+					echo.MIMEApplicationJSON: map[string]interface{}{"schema": errorSchema},
+				},
+			},
+		}
+		if len(params) > 0 {
+			operation["parameters"] = params
+		}
+		operations, exists := paths[path].(map[string]interface{})
+		if !exists {
+			operations = map[string]interface{}{}
+			paths[path] = operations
+		}
+		operations[strings.ToLower(rt.method)] = operation
+	}
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":   "Redis Operator API",
+			"version": "v1",
+		},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": g.schemas},
+	}
+}
+
+// Converts the ':param' segments of an echo path to '{param}' segments and their path params
+func openAPIPath(path string) (string, []interface{}) {
+	var params []interface{}
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		if !strings.HasPrefix(segment, ":") {
+			continue
+		}
+		name := strings.TrimPrefix(segment, ":")
+		segments[i] = "{" + name + "}"
+		params = append(params, map[string]interface{}{
+			"name":     name,
+			"in":       "path",
+			"required": true,
+			"schema":   map[string]interface{}{"type": "string"},
+		})
+	}
+	return strings.Join(segments, "/"), params
+}
+
+func endpointOperationID(rt route) string {
+	name := rt.path[strings.LastIndex(rt.path, "/")+1:]
+	if strings.HasPrefix(name, ":") {
+		trimmed := strings.TrimSuffix(rt.path, "/"+name)
+		name = trimmed[strings.LastIndex(trimmed, "/")+1:]
+	}
+	return strings.ToLower(rt.method) + strings.Title(name)
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// Returns the schema of the type, structs are added to the components and referenced
+func (g *openAPIGenerator) schema(t reflect.Type) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		if _, exists := g.schemas[t.Name()]; !exists {
+			g.schemas[t.Name()] = nil
+			g.schemas[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.schema(t.Elem())}
+	case t.Kind() == reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.schema(t.Elem())}
+	case t.Kind() == reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	}
+	return map[string]interface{}{}
+}
+
+func (g *openAPIGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if field.PkgPath != "" || tag == "-" {
+			continue
+		}
+		name, options := tag, ""
+		if comma := strings.Index(tag, ","); comma >= 0 {
+			name, options = tag[:comma], tag[comma:]
+		}
+		if name == "" {
+			name = field.Name
+		}
+		properties[name] = g.schema(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			required = append(required, name)
+		}
+	}
+	schema := map[string]interface{}{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+/**
+Gets the OpenAPI document of the HTTP API
+**/
+func serveOpenAPI(document map[string]interface{}) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		return c.JSON(http.StatusOK, document)
+	}
+}
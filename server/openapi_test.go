@@ -0,0 +1,44 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/PayU/redis-operator/controllers"
+)
+
+func TestOpenAPIDocument(test *testing.T) {
+	routes := []route{
+		{http.MethodGet, "/cluster/:namespace/:name/jobs/:id", nil, "Gets a job", http.StatusOK, controllers.Job{}},
+		{http.MethodGet, "/audit", nil, "Gets the audit", http.StatusOK, []AuditEntry{}},
+	}
+	document := newOpenAPIDocument(routes)
+	paths := document["paths"].(map[string]interface{})
+	job, exists := paths["/cluster/{namespace}/{name}/jobs/{id}"].(map[string]interface{})
+	if !exists {
+		test.Fatalf("Expected the path params to be converted, got %v", paths)
+	}
+	operation := job["get"].(map[string]interface{})
+	if params := operation["parameters"].([]interface{}); len(params) != 3 {
+		test.Errorf("Expected 3 path params, got %v", params)
+	}
+	if id := operation["operationId"]; id != "getJobs" {
+		test.Errorf("Expected operation id getJobs, got %v", id)
+	}
+	if params := paths["/audit"].(map[string]interface{})["get"].(map[string]interface{})["parameters"].([]interface{}); len(params) != 3 {
+		test.Errorf("Expected 3 query params for /audit, got %v", params)
+	}
+	schemas := document["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	for _, name := range []string{"Job", "JobProgress", "AuditEntry", "ErrorResponse"} {
+		if _, exists := schemas[name]; !exists {
+			test.Errorf("Expected schema %s, got %v", name, schemas)
+		}
+	}
+	properties := schemas["Job"].(map[string]interface{})["properties"].(map[string]interface{})
+	if createdAt := properties["createdAt"].(map[string]interface{}); createdAt["format"] != "date-time" {
+		test.Errorf("Expected createdAt to be a date-time, got %v", createdAt)
+	}
+	if _, exists := properties["ctx"]; exists {
+		test.Errorf("Expected the unexported fields to be skipped, got %v", properties)
+	}
+}
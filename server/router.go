@@ -1,21 +1,47 @@
 package server
 
 import (
+	"net/http"
+
 	"github.com/PayU/redis-operator/controllers"
 	"github.com/labstack/echo/v4"
 )
 
-func register(e *echo.Echo) {
-	e.GET("/state", controllers.ClusterState)
-	e.GET("/info", controllers.ClusterInfo)
-	e.POST("/rebalance", controllers.ClusterRebalance)
-	e.POST("/fix", controllers.ClusterFix)
-	e.POST("/forgetLostNodes", controllers.ForgetLostNodes)
-	e.POST("/forceReconcile", controllers.ForceReconcile)
-	e.POST("/upgrade", controllers.UpgradeCluster)
-	e.POST("/test", controllers.ClusterTest)
-	e.POST("/reset", controllers.DoResetCluster)
-	e.POST("/testData", controllers.ClusterTestWithData)
-	e.POST("/populateMockData", controllers.PopulateClusterWithMockData)
-	e.POST("/flushAllData", controllers.FlushClusterData)
+const clusterPath = "/cluster/:namespace/:name"
+
+// A route of the HTTP API, the summary, status and response of the route describe it in the OpenAPI document
+type route struct {
+	method   string
+	path     string
+	handler  echo.HandlerFunc
+	summary  string
+	status   int
+	response interface{}
+}
+
+func routes(r *controllers.RedisClusterReconciler) []route {
+	return []route{
+		{http.MethodGet, clusterPath + "/state", r.ClusterState, "Gets the operator state and the cluster state", http.StatusOK, controllers.ClusterStateResponse{}},
+		{http.MethodGet, clusterPath + "/info", r.ClusterInfo, "Gets the nodes of the cluster", http.StatusOK, controllers.ClusterInfoResponse{}},
+		{http.MethodGet, clusterPath + "/plan", r.ClusterPlan, "Gets the actions the reconciler would take on the cluster, without carrying them out", http.StatusOK, controllers.ClusterPlan{}},
+		{http.MethodPost, clusterPath + "/rebalance", r.ClusterRebalance, "Starts a job that rebalances the slots of the cluster", http.StatusAccepted, controllers.Job{}},
+		{http.MethodPost, clusterPath + "/memoryRebalance", r.ClusterMemoryRebalance, "Starts a job that plans the moves of slots that even out the memory of the leaders, and carries them out with apply=true", http.StatusAccepted, controllers.Job{}},
+		{http.MethodPost, clusterPath + "/fix", r.ClusterFix, "Starts a job that fixes the cluster", http.StatusAccepted, controllers.Job{}},
+		{http.MethodPost, clusterPath + "/forgetLostNodes", r.ForgetLostNodes, "Forgets the lost nodes of the cluster", http.StatusOK, controllers.MessageResponse{}},
+		{http.MethodPost, clusterPath + "/forceReconcile", r.ForceReconcile, "Runs a reconcile loop of the cluster", http.StatusOK, controllers.MessageResponse{}},
+		{http.MethodPost, clusterPath + "/upgrade", r.UpgradeCluster, "Upgrades the nodes of the cluster", http.StatusOK, controllers.MessageResponse{}},
+		{http.MethodPost, clusterPath + "/test", r.ClusterTest, "Starts a job that runs the test lab on the cluster", http.StatusAccepted, controllers.Job{}},
+		{http.MethodPost, clusterPath + "/reset", r.DoResetCluster, "Sets the cluster state to reset mode", http.StatusOK, controllers.MessageResponse{}},
+		{http.MethodPost, clusterPath + "/testData", r.ClusterTestWithData, "Starts a job that runs the test lab with data on the cluster", http.StatusAccepted, controllers.Job{}},
+		{http.MethodPost, clusterPath + "/populateMockData", r.PopulateClusterWithMockData, "Starts a job that writes mock data to the cluster", http.StatusAccepted, controllers.Job{}},
+		{http.MethodPost, clusterPath + "/flushAllData", r.FlushClusterData, "Flushes the data of the cluster", http.StatusOK, controllers.MessageResponse{}},
+		{http.MethodGet, clusterPath + "/jobs/:id", r.GetJob, "Gets a job of the cluster", http.StatusOK, controllers.Job{}},
+		{http.MethodDelete, clusterPath + "/jobs/:id", r.CancelJob, "Cancels a job of the cluster", http.StatusOK, controllers.Job{}},
+	}
+}
+
+func register(e *echo.Echo, routes []route) {
+	for _, rt := range routes {
+		e.Add(rt.method, rt.path, rt.handler)
+	}
 }
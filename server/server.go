@@ -1,15 +1,77 @@
 package server
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"io/ioutil"
+	"net/http"
+	"path/filepath"
+
+	"github.com/PayU/redis-operator/controllers"
 	"github.com/labstack/echo/v4"
+	"github.com/pkg/errors"
+	corev1 "k8s.io/api/core/v1"
 )
 
-func StartServer() {
+const serverAddress = "0.0.0.0:8080"
+
+type Options struct {
+	// Requires every call to be authenticated and authorized by the api server
+	Authentication bool
+	// Directory of the 'tls.crt' and 'tls.key' files, the API is served over HTTPS when set
+	CertDir string
+	// CA of the client certificates, client certificates are verified when set and the API is served over HTTPS
+	ClientCAFile string
+}
+
+func StartServer(r *controllers.RedisClusterReconciler, opts Options) {
 	echo := echo.New()
 
+	var auth *authenticator
+	if opts.Authentication {
+		auth = &authenticator{client: r.Client}
+	}
+	audit := newAuditLog(auditLogSize)
+	echo.Use(auditMiddleware(r, auth, audit))
+
 	// Routes
-	register(echo)
+	apiRoutes := append(routes(r), route{http.MethodGet, "/audit", audit.serveAudit, "Gets the last audited calls of the API", http.StatusOK, []AuditEntry{}})
+	apiRoutes = append(apiRoutes, route{http.MethodGet, "/openapi.json", nil, "Gets the OpenAPI document of the API", http.StatusOK, map[string]interface{}{}})
+	apiRoutes[len(apiRoutes)-1].handler = serveOpenAPI(newOpenAPIDocument(apiRoutes))
+	register(echo, apiRoutes)
 
 	// Start server
-	go echo.Logger.Fatal(echo.Start("0.0.0.0:8080"))
+	if opts.CertDir == "" {
+		echo.Logger.Fatal(echo.Start(serverAddress))
+		return
+	}
+	tlsConfig, err := serverTLSConfig(opts)
+	if err != nil {
+		echo.Logger.Fatal(err)
+	}
+	echo.Logger.Fatal(echo.StartServer(&http.Server{Addr: serverAddress, TLSConfig: tlsConfig}))
+}
+
+// Client certificates are optional so callers can present a bearer token instead
+func serverTLSConfig(opts Options) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(filepath.Join(opts.CertDir, corev1.TLSCertKey), filepath.Join(opts.CertDir, corev1.TLSPrivateKeyKey))
+	if err != nil {
+		return nil, errors.Wrap(err, "Failed to load the serving certificate of the API")
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:   tls.VersionTLS12,
+		Certificates: []tls.Certificate{cert},
+	}
+	if opts.ClientCAFile != "" {
+		clientCA, err := ioutil.ReadFile(opts.ClientCAFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "Failed to read the client CA of the API")
+		}
+		tlsConfig.ClientCAs = x509.NewCertPool()
+		if !tlsConfig.ClientCAs.AppendCertsFromPEM(clientCA) {
+			return nil, errors.Errorf("No certificate was found in %s", opts.ClientCAFile)
+		}
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return tlsConfig, nil
 }